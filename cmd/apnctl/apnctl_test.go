@@ -1,14 +1,22 @@
 package main
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
 )
 
 const apnctlFixtureXML = `<apns version="8">
@@ -106,6 +114,31 @@ func TestAPNCtlCapabilities(t *testing.T) {
 			},
 			wantOut: []string{`"profileID": 42`, `"IsEnabled": false`},
 		},
+		{
+			name: "patch applies commented yaml patch file",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				patchFile := filepath.Join(fixture.dir, "overlay.yaml")
+				patch := "# curated overlay: keep profile ids in sync with the modem config\n" +
+					"- carrierName: Carrier A\n" +
+					"  carrierID: 10\n" +
+					"  mcc: 250\n" +
+					"  mnc: 01 # leading zero is dropped like in json\n" +
+					"  groupMap:\n" +
+					"    default:\n" +
+					"      base: {apn: internet, type: [default], profileID: 7}\n"
+				if err := os.WriteFile(patchFile, []byte(patch), 0o600); err != nil {
+					t.Fatalf("write yaml patch: %v", err)
+				}
+				return []string{
+					"patch",
+					"--in", fixture.inputXML,
+					"--patch-file", patchFile,
+					"--output-format", "yaml",
+					"--out", fixture.out(t),
+				}
+			},
+			wantOut: []string{"- carrierName: Carrier A\n", "        apn: internet\n        type: [default]\n        profileID: 7\n"},
+		},
 		{
 			name: "build creates one normalized record",
 			args: func(t *testing.T, fixture apnctlFixture) []string {
@@ -172,6 +205,515 @@ func TestAPNCtlCapabilities(t *testing.T) {
 			wantErr: "invalid APN records: 1",
 			wantOut: []string{"invalid: 1"},
 		},
+		{
+			name: "fmt check rejects non-canonical files",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{"fmt", "--check", fixture.inputXML}
+			},
+			wantErr: "1 file(s) are not canonical",
+		},
+		{
+			name: "fmt writes canonical attribute layout",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{
+					"fmt",
+					"--in", fixture.inputXML,
+					"--layout", "attribute",
+					"--out", fixture.out(t),
+				}
+			},
+			wantOut: []string{"\t<apn\n\t\tcarrier=\"Carrier A\"\n\t\tcarrier_id=\"10\"\n\t\tmcc=\"250\"", "\t\tapn=\"internet\"\n\t\ttype=\"default\"\n\t\tprotocol=\"IPV4V6\"", "\n\t/>\n"},
+		},
+		{
+			name: "patch preserve keeps comments and order",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{
+					"patch",
+					"--in", fixture.inputXML,
+					"--preserve",
+					"--plmn", "25102",
+					"--set", "base.apn=ims2",
+					"--output-format", "xml",
+					"--out", fixture.out(t),
+				}
+			},
+			wantOut: []string{
+				"\t<apn carrier=\"Carrier B\" carrier_id=\"20\" mcc=\"251\" mnc=\"02\" apn=\"ims2\" type=\"ims\" protocol=\"IPV6\" />\n\t<apn carrier=\"Broken\" mcc=\"999\"",
+				"bearer_bitmask=\"lte|nr\"",
+			},
+		},
+		{
+			name: "convert applies xml export profile",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{
+					"convert",
+					"--in", fixture.inputXML,
+					"--profile", "android-10",
+					"--layout", "element",
+					"--output-format", "xml",
+					"--out", fixture.out(t),
+				}
+			},
+			wantOut: []string{`<apns version="8">`, "\t<apn carrier=\"Carrier B\" carrier_id=\"20\" mcc=\"251\" mnc=\"2\" apn=\"ims\" type=\"ims\" protocol=\"IPV6\" />\n"},
+		},
+		{
+			name: "validate strict rejects unknown enum tokens in lenient mode",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				path := filepath.Join(fixture.dir, "unknown.xml")
+				data := `<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="internet" type="default,wap" /></apns>`
+				if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+					t.Fatalf("write unknown fixture: %v", err)
+				}
+				return []string{"validate", "--in", path, "--lenient", "--strict", "--out", fixture.out(t)}
+			},
+			wantErr: "decode warnings: 1",
+			wantOut: []string{"records: 1", "invalid: 0"},
+		},
+		{
+			name: "migrate rewrites legacy bearer attributes",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				path := filepath.Join(fixture.dir, "legacy.xml")
+				data := `<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="internet" type="default" bearer="14" /></apns>`
+				if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+					t.Fatalf("write legacy fixture: %v", err)
+				}
+				return []string{"migrate", "--in", path, "--out", fixture.out(t)}
+			},
+			wantOut: []string{`apn="internet" type="default" network_type_bitmask="13" />`},
+			validateOut: func(t *testing.T, out string) {
+				if strings.Contains(out, "bearer=") {
+					t.Fatalf("legacy bearer attribute was kept:\n%s", out)
+				}
+			},
+		},
+		{
+			name: "convert redacts credentials",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				path := filepath.Join(fixture.dir, "auth.xml")
+				data := `<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="internet" type="default" user="operator" password="${env:APNCTL_TEST_PASS}" /></apns>`
+				if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+					t.Fatalf("write auth fixture: %v", err)
+				}
+				return []string{"convert", "--in", path, "--redact", "--output-format", "json", "--out", fixture.out(t)}
+			},
+			wantOut: []string{`"username": "***"`, `"password": "***"`},
+		},
+		{
+			name: "convert resolves secret references on export",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				t.Setenv("APNCTL_TEST_PASS", "s3cret")
+				path := filepath.Join(fixture.dir, "auth.xml")
+				data := `<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="internet" type="default" user="operator" password="${env:APNCTL_TEST_PASS}" /></apns>`
+				if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+					t.Fatalf("write auth fixture: %v", err)
+				}
+				return []string{"convert", "--in", path, "--resolve-secrets", "--output-format", "xml", "--out", fixture.out(t)}
+			},
+			wantOut: []string{`user="operator" password="s3cret"`},
+		},
+		{
+			name: "anonymize pseudonymizes carrier names",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{"anonymize", "--in", fixture.inputXML, "--key", "report", "--out", fixture.out(t)}
+			},
+			wantOut: []string{`mcc="250" mnc="1" apn="internet"`, `carrier="carrier-`},
+			validateOut: func(t *testing.T, out string) {
+				if strings.Contains(out, "Carrier A") || strings.Contains(out, "mms.example") {
+					t.Fatalf("anonymized output leaks source values:\n%s", out)
+				}
+			},
+		},
+		{
+			name: "anonymize requires key",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{"anonymize", "--in", fixture.inputXML}
+			},
+			wantErr: "anonymize requires --key or --key-file",
+		},
+		{
+			name: "convert dispatches registered output format",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				if _, err := apnxml.ParseFormat("plmn-test"); err != nil {
+					_, err = apnxml.RegisterFormat("plmn-test", nil, nil, func(data apnxml.Array, _ ...apnxml.EncodeOption) ([]byte, error) {
+						var builder strings.Builder
+						for _, group := range data {
+							builder.WriteString("plmn=" + group.GetPLMN() + "\n")
+						}
+						return []byte(builder.String()), nil
+					})
+					if err != nil {
+						t.Fatalf("RegisterFormat returned error: %v", err)
+					}
+				}
+				return []string{"convert", "--in", fixture.inputXML, "--output-format", "plmn-test", "--out", fixture.out(t)}
+			},
+			wantOut: []string{"plmn=25001\n", "plmn=25102\n"},
+		},
+		{
+			name: "find detects input format without known extension",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				data, err := os.ReadFile(fixture.inputXML)
+				if err != nil {
+					t.Fatalf("read fixture: %v", err)
+				}
+				path := filepath.Join(fixture.dir, "apns.download")
+				if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(data)), 0o600); err != nil {
+					t.Fatalf("write base64 fixture: %v", err)
+				}
+				return []string{"find", "--in", path, "--type", "ims", "--output-format", "table", "--out", fixture.out(t)}
+			},
+			wantOut: []string{"25102\tCarrier B\t20\tims\tims"},
+		},
+		{
+			name: "find reads archive member selector",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				data, err := os.ReadFile(fixture.inputXML)
+				if err != nil {
+					t.Fatalf("read fixture: %v", err)
+				}
+				var buffer bytes.Buffer
+				zipWriter := zip.NewWriter(&buffer)
+				writer, _ := zipWriter.Create("system/etc/apns-conf.xml")
+				_, _ = writer.Write(data)
+				if err := zipWriter.Close(); err != nil {
+					t.Fatalf("write zip: %v", err)
+				}
+				path := filepath.Join(fixture.dir, "ota.zip")
+				if err := os.WriteFile(path, buffer.Bytes(), 0o600); err != nil {
+					t.Fatalf("write ota fixture: %v", err)
+				}
+				return []string{"find", "--in", "zip://" + path + "!/system/etc/apns-conf.xml", "--type", "ims", "--output-format", "table", "--out", fixture.out(t)}
+			},
+			wantOut: []string{"25102\tCarrier B\t20\tims\tims"},
+		},
+		{
+			name: "scan builds device matrix with conflicts",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				root := filepath.Join(fixture.dir, "tree")
+				files := map[string]string{
+					"device/a/one/apns-conf.xml":   `<apns version="8"><apn carrier="Op" mcc="250" mnc="01" apn="internet" type="default" protocol="IPV4V6" /><apn carrier="Op" mcc="250" mnc="01" apn="mms" type="mms" /></apns>`,
+					"device/b/two/apns-conf.xml":   `<apns version="8"><apn carrier="Op" mcc="250" mnc="01" apn="internet" type="default" protocol="IP" /></apns>`,
+					"device/b/two/apns-broken.xml": `<apns><apn`,
+					"device/b/two/other.xml":       `<resources />`,
+				}
+				for name, content := range files {
+					path := filepath.Join(root, filepath.FromSlash(name))
+					if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+						t.Fatalf("create scan fixture: %v", err)
+					}
+					if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+						t.Fatalf("write scan fixture: %v", err)
+					}
+				}
+				return []string{"scan", "--root", root, "--output-format", "csv", "--out", fixture.out(t)}
+			},
+			wantOut: []string{
+				"kind,plmn,carrier_id,type,mvno,apn,fields,device/a/one/apns-conf.xml,device/b/two/apns-conf.xml",
+				"row,25001,,default,,internet,,true,true",
+				"row,25001,,mms,,mms,,true,false",
+				"conflict,25001,,default,,internet,bearer.type,true,false",
+				"conflict,25001,,default,,internet,bearer.type,false,true",
+			},
+		},
+		{
+			name: "scan writes only conflicts as csv",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				root := filepath.Join(fixture.dir, "tree-conflicts")
+				files := map[string]string{
+					"device/a/one/apns-conf.xml":   `<apns version="8"><apn carrier="Op" mcc="250" mnc="01" apn="internet" type="default" protocol="IPV4V6" /><apn carrier="Op" mcc="250" mnc="01" apn="mms" type="mms" /></apns>`,
+					"device/b/two/apns-conf.xml":   `<apns version="8"><apn carrier="Op" mcc="250" mnc="01" apn="internet" type="default" protocol="IP" /></apns>`,
+					"device/b/two/apns-broken.xml": `<apns><apn`,
+					"device/b/two/other.xml":       `<resources />`,
+				}
+				for name, content := range files {
+					path := filepath.Join(root, filepath.FromSlash(name))
+					if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+						t.Fatalf("create scan fixture: %v", err)
+					}
+					if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+						t.Fatalf("write scan fixture: %v", err)
+					}
+				}
+				return []string{"scan", "--root", root, "--conflicts-only", "--output-format", "csv", "--out", fixture.out(t)}
+			},
+			wantOut: []string{
+				"kind,plmn,carrier_id,type,mvno,apn,fields,device/a/one/apns-conf.xml,device/b/two/apns-conf.xml",
+				"conflict,25001,,default,,internet,bearer.type,true,false",
+				"conflict,25001,,default,,internet,bearer.type,false,true",
+			},
+		},
+		{
+			name: "diff compares shipped records with device dump",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				device := filepath.Join(fixture.dir, "device.txt")
+				dump := "Row: 0 _id=1, name=Carrier A, numeric=25001, carrier_id=10, apn=internet, type=default, protocol=IPV4V6, roaming_protocol=IPV4V6, carrier_enabled=1\n" +
+					"Row: 1 _id=2, name=Carrier A, numeric=25001, apn=wap, type=mms, mmsc=http://mms.example\n"
+				if err := os.WriteFile(device, []byte(dump), 0o600); err != nil {
+					t.Fatalf("write device dump: %v", err)
+				}
+				return []string{"diff", "--in", fixture.inputXML, "--against", device, "--plmn", "25001", "--out", fixture.out(t)}
+			},
+			wantOut: []string{
+				"25001\tmms\tmms\t",
+				"25001\tmms\twap\t",
+				"conflict: plmn=25001 carrier_id=10 type=default fields=other.IsEditable,other.IsVisible\n",
+			},
+			validateOut: func(t *testing.T, out string) {
+				if strings.Contains(out, "\tinternet\t") {
+					t.Fatalf("records present on both sides must not be listed:\n%s", out)
+				}
+			},
+		},
+		{
+			name: "convert keeps the base file version under overlays",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				base := filepath.Join(fixture.dir, "base-v7.xml")
+				overlay := filepath.Join(fixture.dir, "overlay-v8.xml")
+				files := map[string]string{
+					base:    `<apns version="7"><apn carrier="A" mcc="250" mnc="01" apn="internet" type="default" /></apns>`,
+					overlay: `<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="internet" type="default" protocol="IPV6" /></apns>`,
+				}
+				for path, content := range files {
+					if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+						t.Fatalf("write version fixture: %v", err)
+					}
+				}
+				return []string{"convert", "--in", base, "--overlay", overlay, "--output-format", "xml", "--profile", "aosp", "--out", fixture.out(t)}
+			},
+			wantOut: []string{`<apns version="7">`, `protocol="IPV6"`},
+		},
+		{
+			name: "find layers inputs and overlays in order",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				dir := filepath.Join(fixture.dir, "overlays")
+				if err := os.MkdirAll(dir, 0o700); err != nil {
+					t.Fatalf("create overlay dir: %v", err)
+				}
+				files := map[string]string{
+					filepath.Join(fixture.dir, "extra.xml"):  `<apns version="8"><apn carrier="Carrier C" mcc="252" mnc="03" apn="web" type="default" /></apns>`,
+					filepath.Join(dir, "10-region.xml"):      `<apns version="8"><apn carrier="Carrier A" carrier_id="10" mcc="250" mnc="01" apn="internet" type="default" protocol="IP" /></apns>`,
+					filepath.Join(dir, "20-oem.json"):        `[{"carrierName":"Carrier A","carrierID":10,"mcc":250,"mnc":1,"groupMap":{"default":{"base":{"apn":"internet","type":["default"],"profileID":7}}}}]`,
+					filepath.Join(dir, "notes.txt"):          `ignored`,
+					filepath.Join(fixture.dir, "device.xml"): `<apns version="8"><apn carrier="Carrier A" carrier_id="10" mcc="250" mnc="01" apn="internet" type="default" protocol="IPV6" profile_id="9" /></apns>`,
+				}
+				for path, content := range files {
+					if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+						t.Fatalf("write overlay fixture: %v", err)
+					}
+				}
+				return []string{
+					"find",
+					"--in", fixture.inputXML,
+					"--in", filepath.Join(fixture.dir, "extra.xml"),
+					"--overlay-dir", dir,
+					"--overlay", "merge:" + filepath.Join(fixture.dir, "device.xml"),
+					"--type", "default",
+					"--valid-only",
+					"--output-format", "table",
+					"--out", fixture.out(t),
+				}
+			},
+			wantOut: []string{
+				"25001\tCarrier A\t10\tdefault\tinternet\tip\t",
+				"\t7\ttrue\ttrue\tfalse",
+				"25203\tCarrier C\t\tdefault\tweb",
+			},
+		},
+		{
+			name: "inspect effective shows platform defaults",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{"inspect", "--in", fixture.inputXML, "--plmn", "25001", "--effective", "--out", fixture.out(t)}
+			},
+			wantOut: []string{
+				"type=mms apn=mms profile_id=0 protocol=ip roaming_protocol=ip network= enabled=true visible=true editable=true",
+				"type=default apn=internet profile_id=0 protocol=ipv4v6 roaming_protocol=ipv4v6 network= enabled=true visible=true editable=false",
+			},
+		},
+		{
+			name: "simulate picks APN per capability and roaming state",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{
+					"simulate",
+					"--in", fixture.inputXML,
+					"--plmn", "25001",
+					"--capability", "default",
+					"--capability", "ims",
+					"--network-type", "lte",
+					"--out", fixture.out(t),
+				}
+			},
+			wantOut: []string{
+				"default\tlte\tfalse\tinternet\tdefault\tipv4v6\n",
+				"  rejected apn=mms type=mms: type mms does not include default\n",
+				"ims\tlte\ttrue\t-\t-\t-\n",
+			},
+		},
+		{
+			name: "simulate follows file order and matches mvno by SIM",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				path := filepath.Join(fixture.dir, "simulate.xml")
+				data := `<apns version="8">
+	<apn carrier="A" mcc="250" mnc="01" apn="internet" type="default,supl" />
+	<apn carrier="A" mcc="250" mnc="01" apn="plain" type="default" />
+	<apn carrier="A" mcc="250" mnc="01" apn="virtual" type="default" mvno_type="spn" mvno_match_data="Virtual" />
+</apns>`
+				if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+					t.Fatalf("write simulate fixture: %v", err)
+				}
+				return []string{"simulate", "--in", path, "--capability", "default", "--network-type", "lte", "--roaming", "false", "--spn", "Virtual", "--out", fixture.out(t)}
+			},
+			wantOut: []string{
+				"default\tlte\tfalse\tvirtual\tdefault\tip\n",
+				"  rejected apn=internet type=default|supl: an mvno record matches the SIM\n",
+				"  rejected apn=plain type=default: an mvno record matches the SIM\n",
+			},
+		},
+		{
+			name: "simulate requires one PLMN",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{"simulate", "--in", fixture.inputXML, "--out", fixture.out(t)}
+			},
+			wantErr: "simulate needs the records of one PLMN, got 2",
+		},
+		{
+			name: "export generates adb delete script",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{
+					"export",
+					"--in", fixture.inputXML,
+					"--apn", "internet",
+					"--target", "adb",
+					"--action", "delete",
+					"--out", fixture.out(t),
+				}
+			},
+			wantOut: []string{
+				"#!/bin/sh\nset -e\n",
+				`adb shell "content delete --uri content://telephony/carriers --where \"numeric='25001' AND apn='internet'`,
+			},
+		},
+		{
+			name: "export writes cosa document for one operator",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{
+					"export",
+					"--in", fixture.inputXML,
+					"--plmn", "25001",
+					"--target", "cosa",
+					"--out", fixture.out(t),
+				}
+			},
+			wantOut: []string{
+				`<CellularProvisioning xmlns="http://www.microsoft.com/networking/CellularProvisioning/v1">`,
+				"<AccessString>internet</AccessString>",
+				"<Purpose>Mms</Purpose>",
+			},
+		},
+		{
+			name: "export writes cosa document per operator into directory",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				dir := filepath.Join(fixture.dir, "cosa")
+				if err := os.MkdirAll(dir, 0o700); err != nil {
+					t.Fatalf("create cosa dir: %v", err)
+				}
+				return []string{"export", "--in", fixture.inputXML, "--target", "cosa", "--out", dir}
+			},
+			wantOut: []string{
+				"25001-carrier-a.cosa.xml\n",
+				"25102-carrier-b.cosa.xml\n",
+				`<Condition Name="Mnc" Value="02"></Condition>`,
+			},
+		},
+		{
+			name: "export cosa directory resolves secrets and keeps same-type mvno rows",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				t.Setenv("APNCTL_TEST_PASS", "s3cret")
+				path := filepath.Join(fixture.dir, "sheet.csv")
+				sheet := "plmn,carrier,apn,type,authtype,user,password,mvno_type,mvno_match_data\n" +
+					"25001,Op,internet,default,pap,operator,${env:APNCTL_TEST_PASS},,\n" +
+					"25001,Virt,virt.internet,default,,,,spn,Virt\n"
+				if err := os.WriteFile(path, []byte(sheet), 0o600); err != nil {
+					t.Fatalf("write csv fixture: %v", err)
+				}
+				dir := filepath.Join(fixture.dir, "cosa")
+				if err := os.MkdirAll(dir, 0o700); err != nil {
+					t.Fatalf("create cosa dir: %v", err)
+				}
+				return []string{"export", "--in", path, "--target", "cosa", "--resolve-secrets", "--out", dir}
+			},
+			wantOut: []string{
+				"25001-op.cosa.xml\n",
+				"<Password>s3cret</Password>",
+				`<Condition Name="Spn" Value="Virt"></Condition>`,
+				"<AccessString>virt.internet</AccessString>",
+			},
+		},
+		{
+			name: "export writes openwrt interface for default apn",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{
+					"export",
+					"--in", fixture.inputXML,
+					"--plmn", "25001",
+					"--target", "openwrt",
+					"--out", fixture.out(t),
+				}
+			},
+			wantOut: []string{
+				"config interface 'wwan'\n\toption proto 'qmi'\n\toption device '/dev/cdc-wdm0'\n\toption apn 'internet'\n\toption auth 'none'\n\toption pdptype 'ipv4v6'\n",
+			},
+		},
+		{
+			name: "export openwrt requires one plmn",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{"export", "--in", fixture.inputXML, "--target", "openwrt", "--out", fixture.out(t)}
+			},
+			wantErr: "openwrt needs the records of one PLMN, got 2",
+		},
+		{
+			name: "find writes selected csv columns",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{
+					"find",
+					"--in", fixture.inputXML,
+					"--plmn", "25001",
+					"--output-format", "csv",
+					"--columns", "plmn,apn,type,mmsc",
+					"--out", fixture.out(t),
+				}
+			},
+			wantOut: []string{"plmn,apn,type,mmsc\n", "25001,internet,default,\n", "25001,mms,mms,http://mms.example\n"},
+		},
+		{
+			name: "convert imports csv sheet",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				path := filepath.Join(fixture.dir, "sheet.csv")
+				sheet := "plmn,carrier,apn,type,authtype,user\n25203,Carrier C,web,default|supl,pap,guest\n"
+				if err := os.WriteFile(path, []byte(sheet), 0o600); err != nil {
+					t.Fatalf("write csv fixture: %v", err)
+				}
+				return []string{"convert", "--in", path, "--output-format", "xml", "--out", fixture.out(t)}
+			},
+			wantOut: []string{`carrier="Carrier C" mcc="252" mnc="3" apn="web" user="guest"`, `authtype="1" type="default,supl"`},
+		},
+		{
+			name: "convert reports csv cell errors",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				path := filepath.Join(fixture.dir, "broken.tsv")
+				if err := os.WriteFile(path, []byte("plmn\tapn\tmtu\n25203\tweb\tlarge\n"), 0o600); err != nil {
+					t.Fatalf("write tsv fixture: %v", err)
+				}
+				return []string{"convert", "--in", path, "--output-format", "xml", "--out", fixture.out(t)}
+			},
+			wantErr: "row 2, column mtu",
+		},
+		{
+			name: "export requires target",
+			args: func(t *testing.T, fixture apnctlFixture) []string {
+				return []string{"export", "--in", fixture.inputXML, "--out", fixture.out(t)}
+			},
+			wantErr: "export requires --target",
+		},
 		{
 			name: "unknown command returns error",
 			args: func(t *testing.T, fixture apnctlFixture) []string {
@@ -252,6 +794,18 @@ func readAPNCtlOutput(t *testing.T, args []string) string {
 	t.Helper()
 	for index, arg := range args {
 		if arg == "--out" && index+1 < len(args) {
+			if entryArray, err := os.ReadDir(args[index+1]); err == nil {
+				var out strings.Builder
+				for _, entry := range entryArray {
+					data, err := os.ReadFile(filepath.Join(args[index+1], entry.Name()))
+					if err != nil {
+						t.Fatalf("read output %q: %v", entry.Name(), err)
+					}
+					out.WriteString(entry.Name() + "\n")
+					out.Write(data)
+				}
+				return out.String()
+			}
 			data, err := os.ReadFile(args[index+1])
 			if err != nil {
 				if os.IsNotExist(err) {
@@ -264,3 +818,198 @@ func readAPNCtlOutput(t *testing.T, args []string) string {
 	}
 	return ""
 }
+
+func TestAPNCtlWritesCompressedOutputs(t *testing.T) {
+	fixture := newAPNCtlFixture(t)
+
+	tests := []struct {
+		name   string
+		format string
+		read   func(t *testing.T, data []byte) []byte
+	}{
+		{
+			name:   "apns.json.gz",
+			format: "json",
+			read: func(t *testing.T, data []byte) []byte {
+				reader, err := gzip.NewReader(bytes.NewReader(data))
+				if err != nil {
+					t.Fatalf("gzip.NewReader returned error: %v", err)
+				}
+				content, err := io.ReadAll(reader)
+				if err != nil {
+					t.Fatalf("read gzip payload: %v", err)
+				}
+				return content
+			},
+		},
+		{
+			name:   "apns.xml.zip",
+			format: "xml",
+			read: func(t *testing.T, data []byte) []byte {
+				reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+				if err != nil {
+					t.Fatalf("zip.NewReader returned error: %v", err)
+				}
+				if len(reader.File) != 1 || reader.File[0].Name != "apns.xml" {
+					t.Fatalf("zip must hold one apns.xml member: %v", reader.File)
+				}
+				member, err := reader.File[0].Open()
+				if err != nil {
+					t.Fatalf("open zip member: %v", err)
+				}
+				defer member.Close()
+				content, err := io.ReadAll(member)
+				if err != nil {
+					t.Fatalf("read zip member: %v", err)
+				}
+				return content
+			},
+		},
+		{
+			name:   "apns.xml.tar",
+			format: "xml",
+			read: func(t *testing.T, data []byte) []byte {
+				reader := tar.NewReader(bytes.NewReader(data))
+				header, err := reader.Next()
+				if err != nil || header.Name != "apns.xml" {
+					t.Fatalf("tar must hold an apns.xml member: %v, %v", header, err)
+				}
+				content, err := io.ReadAll(reader)
+				if err != nil {
+					t.Fatalf("read tar member: %v", err)
+				}
+				return content
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(fixture.dir, test.name)
+			if err := run([]string{"convert", "--in", fixture.inputXML, "--output-format", test.format, "--out", path}); err != nil {
+				t.Fatalf("run returned error: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read output: %v", err)
+			}
+			if content := test.read(t, data); !bytes.Contains(content, []byte("internet")) {
+				t.Fatalf("decompressed output misses records:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestAPNCtlServe(t *testing.T) {
+	fixture := newAPNCtlFixture(t)
+	server, err := newAPNServer(fixture.inputXML, "", false)
+	if err != nil {
+		t.Fatalf("newAPNServer returned error: %v", err)
+	}
+	server.reloadInterval = 0
+	httpServer := httptest.NewServer(server.handler())
+	defer httpServer.Close()
+
+	get := func(t *testing.T, path string, headers map[string]string) *http.Response {
+		t.Helper()
+		request, err := http.NewRequest(http.MethodGet, httpServer.URL+path, nil)
+		if err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		response, err := httpServer.Client().Do(request)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		t.Cleanup(func() { _ = response.Body.Close() })
+		return response
+	}
+	readBody := func(t *testing.T, response *http.Response) string {
+		t.Helper()
+		data, err := io.ReadAll(response.Body)
+		if err != nil {
+			t.Fatalf("read body: %v", err)
+		}
+		return string(data)
+	}
+
+	t.Run("plmns", func(t *testing.T) {
+		body := readBody(t, get(t, "/plmns", nil))
+		if strings.TrimSpace(body) != `["25001","25102"]` {
+			t.Fatalf("unexpected PLMNs: %s", body)
+		}
+	})
+
+	t.Run("apns filtered as csv", func(t *testing.T) {
+		response := get(t, "/apns?plmn=25001&type=mms", map[string]string{"Accept": "text/csv"})
+		body := readBody(t, response)
+		if response.Header.Get("Content-Type") != "text/csv" || !strings.Contains(body, "25001,Carrier A,10,mms,mms") || strings.Contains(body, "internet") {
+			t.Fatalf("unexpected CSV response %q:\n%s", response.Header.Get("Content-Type"), body)
+		}
+	})
+
+	t.Run("carrier as xml", func(t *testing.T) {
+		body := readBody(t, get(t, "/carriers/20", map[string]string{"Accept": "application/xml"}))
+		if !strings.Contains(body, `apn="ims"`) {
+			t.Fatalf("unexpected carrier XML:\n%s", body)
+		}
+		if response := get(t, "/carriers/99", nil); response.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404 for missing carrier, got %d", response.StatusCode)
+		}
+	})
+
+	t.Run("accept honours quality values", func(t *testing.T) {
+		for accept, want := range map[string]string{
+			"application/xml;q=0.1, application/json": "application/json",
+			"application/json;q=0.5, text/csv":        "text/csv",
+			"*/*, application/xml":                    "application/xml",
+			"text/csv;q=0, application/xml;q=0.2":     "application/xml",
+		} {
+			if got := get(t, "/plmns", map[string]string{"Accept": accept}).Header.Get("Content-Type"); got != want {
+				t.Fatalf("Accept %q: expected %s, got %s", accept, want, got)
+			}
+		}
+		if response := get(t, "/plmns", map[string]string{"Accept": "text/csv;q=0"}); response.StatusCode != http.StatusNotAcceptable {
+			t.Fatalf("expected 406 for q=0, got %d", response.StatusCode)
+		}
+	})
+
+	t.Run("stats with etag", func(t *testing.T) {
+		response := get(t, "/stats", nil)
+		etag := response.Header.Get("ETag")
+		if etag == "" || !strings.Contains(readBody(t, response), `"Records":3`) {
+			t.Fatal("expected stats body with ETag")
+		}
+		if cached := get(t, "/stats", map[string]string{"If-None-Match": etag}); cached.StatusCode != http.StatusNotModified {
+			t.Fatalf("expected 304 for matching ETag, got %d", cached.StatusCode)
+		}
+	})
+
+	t.Run("resolve SIM profile", func(t *testing.T) {
+		response, err := httpServer.Client().Post(httpServer.URL+"/resolve", "application/json", strings.NewReader(`{"mcc":251,"mnc":2}`))
+		if err != nil {
+			t.Fatalf("POST /resolve: %v", err)
+		}
+		defer response.Body.Close()
+		if body := readBody(t, response); !strings.Contains(body, `"apn": "ims"`) {
+			t.Fatalf("unexpected resolve response:\n%s", body)
+		}
+	})
+
+	t.Run("hot reload", func(t *testing.T) {
+		updated := strings.Replace(apnctlFixtureXML, `mcc="251"`, `mcc="252"`, 1)
+		if err := os.WriteFile(fixture.inputXML, []byte(updated), 0o600); err != nil {
+			t.Fatalf("rewrite fixture: %v", err)
+		}
+		future := time.Now().Add(time.Hour)
+		if err := os.Chtimes(fixture.inputXML, future, future); err != nil {
+			t.Fatalf("touch fixture: %v", err)
+		}
+		body := readBody(t, get(t, "/plmns", nil))
+		if strings.TrimSpace(body) != `["25001","25202"]` {
+			t.Fatalf("expected reloaded PLMNs, got %s", body)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+)
+
+func runAnonymize(args []string) error {
+	flags, fs := newCommonFlagSet("anonymize")
+	flags.outputFormat = "xml"
+	var key, keyFile string
+	fs.StringVar(&key, "key", "", "secret key for the keyed hash")
+	fs.StringVar(&keyFile, "key-file", "", "file with the secret key for the keyed hash")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if key != "" && keyFile != "" {
+		return fmt.Errorf("--key and --key-file are mutually exclusive")
+	}
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return err
+		}
+		key = strings.TrimRight(string(data), "\r\n")
+	}
+	anonymizer, err := apntool.NewAnonymizer([]byte(key))
+	if err != nil {
+		return fmt.Errorf("anonymize requires --key or --key-file: %w", err)
+	}
+
+	data, err := loadAPNs(flags)
+	if err != nil {
+		return err
+	}
+	tool, err := process(apntool.From(data).Anonymize(anonymizer), flags)
+	if err != nil {
+		return err
+	}
+	return writeAPNs(flags, tool)
+}
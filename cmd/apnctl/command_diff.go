@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+)
+
+func runDiff(args []string) error {
+	common, filters, fs := newQueryFlagSet("diff")
+	common.outputFormat = "table"
+	var (
+		against       stringList
+		againstFormat string
+	)
+	fs.Var(&against, "against", "file compared with the input, such as a device dump; repeatable")
+	fs.StringVar(&againstFormat, "against-format", "", "format of --against files: "+formatNames("auto"))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(against) == 0 {
+		return fmt.Errorf("diff requires --against")
+	}
+
+	data, err := loadAPNs(common)
+	if err != nil {
+		return err
+	}
+	predicate, err := buildPredicate(filters)
+	if err != nil {
+		return err
+	}
+
+	sources := []apntool.Source{{Name: diffInputName(common), Data: apntool.From(data).Filter(predicate)}}
+	for _, path := range against {
+		againstData, err := loadFile(path, againstFormat, decodeOptions(common)...)
+		if err == nil {
+			againstData, err = effectiveAPNs(againstData, common)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		sources = append(sources, apntool.Source{Name: path, Data: apntool.From(againstData).Filter(predicate)})
+	}
+
+	matrix := apntool.BuildMatrix(sources)
+	rows := []apntool.MatrixRow{}
+	for _, row := range matrix.Rows {
+		if len(row.Sources) != len(sources) {
+			rows = append(rows, row)
+		}
+	}
+	matrix.Rows = rows
+	return writeMatrix("diff", common, matrix)
+}
+
+func diffInputName(flags *commonFlags) string {
+	switch {
+	case flags.url != "":
+		return flags.url
+	case flags.stdin:
+		return "stdin"
+	default:
+		return strings.Join(flags.in, "+")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnexport"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+func runExport(args []string) error {
+	common, filters, fs := newQueryFlagSet("export")
+	var target, actionValue, protoValue string
+	var openWrtOptions apnexport.OpenWrtOptions
+	fs.StringVar(&target, "target", "", "export target: adb or "+formatNames())
+	fs.StringVar(&actionValue, "action", "insert", "adb action: insert, update, delete")
+	fs.StringVar(&protoValue, "proto", "qmi", "openwrt proto: qmi, modemmanager")
+	fs.StringVar(&openWrtOptions.Interface, "interface", "wwan", "openwrt interface name")
+	fs.StringVar(&openWrtOptions.Device, "device", "", "openwrt modem device, /dev/cdc-wdm0 for qmi")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if target == "" {
+		return fmt.Errorf("export requires --target")
+	}
+	action, err := apnexport.ParseADBAction(actionValue)
+	if err != nil {
+		return err
+	}
+	openWrtOptions.Proto, err = apnexport.ParseOpenWrtProto(protoValue)
+	if err != nil {
+		return err
+	}
+
+	data, err := loadAPNs(common)
+	if err != nil {
+		return err
+	}
+	predicate, err := buildPredicate(filters)
+	if err != nil {
+		return err
+	}
+	tool, err := process(apntool.From(data).Filter(predicate), common)
+	if err != nil {
+		return err
+	}
+
+	isOpenWrt := strings.EqualFold(target, string(apnexport.FormatOpenWrt))
+	isCOSADirectory := strings.EqualFold(target, string(apnexport.FormatCOSA)) && isDirectory(common.out)
+	if !isCOSADirectory && !isOpenWrt && !strings.EqualFold(target, string(apnexport.FormatADB)) {
+		common.outputFormat = target
+		return writeAPNs(common, tool)
+	}
+	tool, err = outputAPNs(common, tool)
+	if err != nil {
+		return err
+	}
+	data = tool.Data()
+	if common.resolveSecret {
+		data, err = data.ResolveSecrets(apnxml.LookupSecret)
+		if err != nil {
+			return err
+		}
+	}
+	if isCOSADirectory {
+		return writeCOSADirectory(common.out, data)
+	}
+	if isOpenWrt {
+		network, err := apnexport.OpenWrtNetwork(data, openWrtOptions)
+		if err != nil {
+			return err
+		}
+		return writeData(common.out, func(writer io.Writer) error {
+			_, err := writer.Write(network)
+			return err
+		})
+	}
+	return writeData(common.out, func(writer io.Writer) error {
+		_, err := writer.Write(apnexport.ADBScript(data, action))
+		return err
+	})
+}
+
+func writeCOSADirectory(dir string, data apnxml.Array) error {
+	documentArray := apnexport.COSADocuments(data)
+	if len(documentArray) == 0 {
+		return fmt.Errorf("no operators to export")
+	}
+	for _, document := range documentArray {
+		data, err := document.MarshalIndent()
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, document.FileName()), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isDirectory(path string) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+func runFmt(args []string) error {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	var in, out, layoutValue string
+	var stdin, write, check bool
+	fs.StringVar(&in, "in", "", "input XML file")
+	fs.BoolVar(&stdin, "stdin", false, "read input from stdin")
+	fs.StringVar(&out, "out", "", "output file")
+	fs.StringVar(&layoutValue, "layout", "element", "layout: element (one element per line) or attribute (one attribute per line)")
+	fs.BoolVar(&write, "write", false, "rewrite input files in place")
+	fs.BoolVar(&check, "check", false, "return an error when input is not canonical")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	layout, err := apnxml.ParseCanonicalLayout(layoutValue)
+	if err != nil {
+		return err
+	}
+
+	paths := fs.Args()
+	if in != "" {
+		paths = append([]string{in}, paths...)
+	}
+	if stdin {
+		if len(paths) > 0 {
+			return fmt.Errorf("--stdin cannot be combined with input files")
+		}
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		return formatData("stdin", data, layout, check, func(formatted []byte) error {
+			return writeData(out, func(writer io.Writer) error {
+				_, err := writer.Write(formatted)
+				return err
+			})
+		})
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("input is required: use --in, --stdin, or file arguments")
+	}
+	if out != "" && (len(paths) > 1 || write) {
+		return fmt.Errorf("--out requires exactly one input and cannot be combined with --write")
+	}
+
+	var unformatted []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		err = formatData(path, data, layout, check, func(formatted []byte) error {
+			if write {
+				if bytes.Equal(data, formatted) {
+					return nil
+				}
+				return os.WriteFile(path, formatted, 0644)
+			}
+			return writeData(out, func(writer io.Writer) error {
+				_, err := writer.Write(formatted)
+				return err
+			})
+		})
+		if err != nil {
+			if !check {
+				return err
+			}
+			unformatted = append(unformatted, path)
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if len(unformatted) > 0 {
+		return fmt.Errorf("%d file(s) are not canonical", len(unformatted))
+	}
+	return nil
+}
+
+func formatData(name string, data []byte, layout apnxml.CanonicalLayout, check bool, write func([]byte) error) error {
+	formatted, err := apnxml.Canonicalize(data, layout)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if check {
+		if !bytes.Equal(data, formatted) {
+			return fmt.Errorf("%s: not canonical", name)
+		}
+		return nil
+	}
+	return write(formatted)
+}
@@ -1,6 +1,10 @@
 package main
 
-import "github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+import (
+	"io"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+)
 
 func runInspect(args []string) error {
 	common, filters, fs := newQueryFlagSet("inspect")
@@ -20,11 +24,12 @@ func runInspect(args []string) error {
 	if err != nil {
 		return err
 	}
-
-	writer, closeOutput, err := outputWriter(common.out)
+	tool, err = outputAPNs(common, tool)
 	if err != nil {
 		return err
 	}
-	defer closeOutput()
-	return writeInspect(writer, tool)
+
+	return writeData(common.out, func(writer io.Writer) error {
+		return writeInspect(writer, tool)
+	})
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+)
+
+func runMigrate(args []string) error {
+	flags, fs := newCommonFlagSet("migrate")
+	flags.outputFormat = "xml"
+	flags.preserve = true
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := loadAPNs(flags)
+	if err != nil {
+		return err
+	}
+	migrated := data.MigrateLegacyBearer()
+	fmt.Fprintf(os.Stderr, "migrated=%d\n", migrated)
+
+	tool, err := process(apntool.From(data), flags)
+	if err != nil {
+		return err
+	}
+	return writeAPNs(flags, tool)
+}
@@ -17,8 +17,8 @@ func runPatch(args []string) error {
 	var strict bool
 	fs.Var(&setList, "set", "set APN field as section.field=value")
 	fs.StringVar(&modeValue, "mode", "patch", "update mode: merge, patch, apply")
-	fs.StringVar(&patchFile, "patch-file", "", "XML or JSON APN file to merge, patch, or apply")
-	fs.StringVar(&patchFormat, "patch-format", "", "patch file format: xml or json")
+	fs.StringVar(&patchFile, "patch-file", "", "XML, JSON or YAML APN file to merge, patch, or apply")
+	fs.StringVar(&patchFormat, "patch-format", "", "patch file format: xml, json or yaml")
 	fs.BoolVar(&strict, "strict", false, "return an error when --set matches no records")
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -39,14 +39,7 @@ func runPatch(args []string) error {
 		if err != nil {
 			return err
 		}
-		switch mode {
-		case apnxml.ObjectUpdateMerge:
-			tool = tool.Merge(patchData)
-		case apnxml.ObjectUpdatePatch:
-			tool = tool.Patch(patchData)
-		case apnxml.ObjectUpdateApply:
-			tool = tool.ApplyUpdate(patchData)
-		}
+		tool = updateAPNs(tool, patchData, mode)
 	}
 
 	if len(setList) > 0 {
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+func runScan(args []string) error {
+	flags, flagSet := newCommonFlagSet("scan")
+	flags.outputFormat = "table"
+	var (
+		root          string
+		patterns      stringList
+		conflictsOnly bool
+	)
+	flagSet.StringVar(&root, "root", ".", "directory tree to scan")
+	flagSet.Var(&patterns, "pattern", "file name glob; repeatable, default apns*")
+	flagSet.BoolVar(&conflictsOnly, "conflicts-only", false, "print inconsistencies only")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+	if len(patterns) == 0 {
+		patterns = stringList{"apns*"}
+	}
+
+	sources, failed, err := scanSources(root, patterns, flags)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("no apn files found under %s", root)
+	}
+	fmt.Fprintf(os.Stderr, "scanned=%d failed=%d\n", len(sources), failed)
+
+	matrix := apntool.BuildMatrix(sources)
+	if conflictsOnly {
+		matrix.Rows = []apntool.MatrixRow{}
+	}
+	return writeMatrix("scan", flags, matrix)
+}
+
+func scanSources(root string, patterns []string, flags *commonFlags) ([]apntool.Source, int, error) {
+	optionList := decodeOptions(flags)
+	var (
+		sources []apntool.Source
+		failed  int
+	)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
+			failed++
+			return nil
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !scanMatches(entry.Name(), patterns) {
+			return nil
+		}
+		if _, err := apnxml.FormatFromFilename(path); err != nil {
+			return nil
+		}
+
+		data, err := apnxml.ImportFromFile(path, optionList...)
+		if err == nil {
+			data, err = effectiveAPNs(data, flags)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
+			failed++
+			return nil
+		}
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			name = path
+		}
+		sources = append(sources, apntool.Source{Name: filepath.ToSlash(name), Data: apntool.From(data)})
+		return nil
+	})
+	return sources, failed, err
+}
+
+func scanMatches(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func writeMatrix(name string, flags *commonFlags, matrix apntool.Matrix) error {
+	switch strings.ToLower(flags.outputFormat) {
+	case "json":
+		return writeJSON(flags.out, matrix)
+	case "csv":
+		return writeData(flags.out, func(writer io.Writer) error {
+			return writeMatrixCSV(writer, matrix)
+		})
+	case "table", "text":
+		return writeData(flags.out, func(writer io.Writer) error {
+			writeMatrixTable(writer, matrix)
+			return nil
+		})
+	default:
+		return fmt.Errorf("unsupported output format for %s: %s", name, flags.outputFormat)
+	}
+}
+
+func writeMatrixTable(writer io.Writer, matrix apntool.Matrix) {
+	if len(matrix.Rows) > 0 {
+		fmt.Fprintln(writer, "PLMN\tType\tAPN\tSources")
+		for _, row := range matrix.Rows {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", row.PLMN, row.Type, row.APN, strings.Join(row.Sources, ","))
+		}
+	}
+	for _, conflict := range matrix.Conflicts {
+		fmt.Fprintf(writer, "conflict: plmn=%s", conflict.PLMN)
+		if conflict.CarrierID != "" {
+			fmt.Fprintf(writer, " carrier_id=%s", conflict.CarrierID)
+		}
+		fmt.Fprintf(writer, " type=%s", conflict.Type)
+		if conflict.MVNO != "" {
+			fmt.Fprintf(writer, " mvno=%s", conflict.MVNO)
+		}
+		fmt.Fprintf(writer, " fields=%s\n", strings.Join(conflict.Fields, ","))
+		for _, variant := range conflict.Variants {
+			fmt.Fprintf(writer, "  apn=%s sources=%s\n", variant.APN, strings.Join(variant.Sources, ","))
+		}
+	}
+}
+
+func writeMatrixCSV(writer io.Writer, matrix apntool.Matrix) error {
+	csvWriter := csv.NewWriter(writer)
+	header := append([]string{"kind", "plmn", "carrier_id", "type", "mvno", "apn", "fields"}, matrix.Sources...)
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+	for _, row := range matrix.Rows {
+		line := append([]string{"row", row.PLMN, "", row.Type, "", row.APN, ""}, matrixShipped(matrix.Sources, row.Sources)...)
+		if err := csvWriter.Write(line); err != nil {
+			return err
+		}
+	}
+	for _, conflict := range matrix.Conflicts {
+		fields := strings.Join(conflict.Fields, ";")
+		for _, variant := range conflict.Variants {
+			line := append([]string{"conflict", conflict.PLMN, conflict.CarrierID, conflict.Type, conflict.MVNO, variant.APN, fields}, matrixShipped(matrix.Sources, variant.Sources)...)
+			if err := csvWriter.Write(line); err != nil {
+				return err
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func matrixShipped(sources []string, shippedSources []string) []string {
+	shipped := map[string]bool{}
+	for _, source := range shippedSources {
+		shipped[source] = true
+	}
+
+	result := make([]string, 0, len(sources))
+	for _, source := range sources {
+		result = append(result, strconv.FormatBool(shipped[source]))
+	}
+	return result
+}
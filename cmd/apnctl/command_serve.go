@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+func runServe(args []string) error {
+	flags, fs := newCommonFlagSet("serve")
+	var listen string
+	fs.StringVar(&listen, "listen", ":8080", "HTTP listen address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(flags.in) != 1 || len(flags.overlays) > 0 {
+		return fmt.Errorf("serve requires exactly one --in and no overlays")
+	}
+
+	server, err := newAPNServer(flags.in[0], flags.inputFormat, flags.redact)
+	if err != nil {
+		return err
+	}
+
+	httpServer := &http.Server{
+		Addr:              listen,
+		Handler:           server.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Fprintf(os.Stderr, "apnctl: serving %s on %s\n", flags.in[0], listen)
+	return httpServer.ListenAndServe()
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+func runSimulate(args []string) error {
+	common, filters, fs := newQueryFlagSet("simulate")
+	common.outputFormat = "table"
+	common.preserve = true
+	var capabilities, networkTypes stringList
+	var roaming string
+	var sim apntool.SIMProfile
+	fs.Var(&capabilities, "capability", "requested APN type; repeatable, default default, ims and mms")
+	fs.Var(&networkTypes, "network-type", "serving network type; repeatable, default nr, lte and iwlan")
+	fs.StringVar(&roaming, "roaming", "", "roaming state: true or false; both when empty")
+	fs.StringVar(&sim.SPN, "spn", "", "SIM service provider name for MVNO matching")
+	fs.StringVar(&sim.IMSI, "imsi", "", "SIM IMSI for MVNO matching")
+	fs.StringVar(&sim.GID1, "gid1", "", "SIM group identifier level 1 for MVNO matching")
+	fs.StringVar(&sim.ICCID, "iccid", "", "SIM ICCID for MVNO matching")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(capabilities) == 0 {
+		capabilities = stringList{"default", "ims", "mms"}
+	}
+	if len(networkTypes) == 0 {
+		networkTypes = stringList{"nr", "lte", "iwlan"}
+	}
+	roamingStates := []bool{false, true}
+	if roaming != "" {
+		value, err := strconv.ParseBool(roaming)
+		if err != nil {
+			return fmt.Errorf("invalid --roaming value: %w", err)
+		}
+		roamingStates = []bool{value}
+	}
+
+	data, err := loadAPNs(common)
+	if err != nil {
+		return err
+	}
+	predicate, err := buildPredicate(filters)
+	if err != nil {
+		return err
+	}
+	tool := apntool.From(data).Filter(predicate)
+	if plmns := tool.Stats().ByPLMN; len(plmns) != 1 {
+		return fmt.Errorf("simulate needs the records of one PLMN, got %d: use --plmn", len(plmns))
+	}
+	var simProfile *apntool.SIMProfile
+	if sim.SPN != "" || sim.IMSI != "" || sim.GID1 != "" || sim.ICCID != "" {
+		simProfile = &sim
+	}
+
+	var results []apntool.SimulationResult
+	for _, capabilityValue := range capabilities {
+		capability, err := apnxml.ParseObjectBaseType(capabilityValue)
+		if err != nil {
+			return err
+		}
+		for _, networkValue := range networkTypes {
+			networkType, err := apnxml.ParseObjectNetworkType(networkValue)
+			if err != nil {
+				return err
+			}
+			for _, roamingState := range roamingStates {
+				results = append(results, tool.Simulate(apntool.SimulationRequest{
+					NetworkType: networkType,
+					Roaming:     roamingState,
+					Capability:  capability,
+					SIM:         simProfile,
+				}))
+			}
+		}
+	}
+	return writeSimulation(common, results)
+}
+
+func writeSimulation(flags *commonFlags, results []apntool.SimulationResult) error {
+	switch strings.ToLower(flags.outputFormat) {
+	case "json":
+		for index := range results {
+			results[index] = redactSimulation(flags, results[index])
+		}
+		return writeJSON(flags.out, results)
+	case "table", "text":
+		return writeData(flags.out, func(writer io.Writer) error {
+			writeSimulationTable(writer, results)
+			return nil
+		})
+	default:
+		return fmt.Errorf("unsupported output format for simulate: %s", flags.outputFormat)
+	}
+}
+
+func redactSimulation(flags *commonFlags, result apntool.SimulationResult) apntool.SimulationResult {
+	if !flags.redact {
+		return result
+	}
+	if result.Selected != nil {
+		selected := *result.Selected
+		selected.Record = *selected.Record.Redacted()
+		result.Selected = &selected
+	}
+	for index := range result.Rejected {
+		result.Rejected[index].Record = *result.Rejected[index].Record.Redacted()
+	}
+	return result
+}
+
+func writeSimulationTable(writer io.Writer, results []apntool.SimulationResult) {
+	fmt.Fprintln(writer, "Capability\tNetwork\tRoaming\tAPN\tType\tProtocol")
+	for _, result := range results {
+		apn, apnType, protocol := "-", "-", "-"
+		if result.Selected != nil {
+			apn = apnString(result.Selected.Record.Base)
+			apnType = baseTypeString(result.Selected.Record.Base)
+			protocol = result.Selected.Protocol.String()
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%t\t%s\t%s\t%s\n", result.Request.Capability, result.Request.NetworkType, result.Request.Roaming, apn, apnType, protocol)
+		for _, candidate := range result.Rejected {
+			fmt.Fprintf(writer, "  rejected apn=%s type=%s: %s\n", apnString(candidate.Record.Base), baseTypeString(candidate.Record.Base), strings.Join(candidate.Reasons, "; "))
+		}
+	}
+}
@@ -10,7 +10,7 @@ func runValidate(args []string) error {
 	common, filters, fs := newQueryFlagSet("validate")
 	var strict bool
 	common.outputFormat = "summary"
-	fs.BoolVar(&strict, "strict", false, "return an error when invalid records exist")
+	fs.BoolVar(&strict, "strict", false, "return an error when invalid records or decode warnings exist")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -34,5 +34,8 @@ func runValidate(args []string) error {
 	if strict && stats.Invalid > 0 {
 		return fmt.Errorf("invalid APN records: %d", stats.Invalid)
 	}
+	if strict && len(common.inputWarnings) > 0 {
+		return fmt.Errorf("decode warnings: %d", len(common.inputWarnings))
+	}
 	return nil
 }
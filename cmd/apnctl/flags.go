@@ -9,19 +9,31 @@ import (
 func newCommonFlagSet(name string) (*commonFlags, *flag.FlagSet) {
 	flags := &commonFlags{outputFormat: string(apnxml.FormatJSON)}
 	fs := flag.NewFlagSet(name, flag.ContinueOnError)
-	fs.StringVar(&flags.in, "in", "", "input file")
+	fs.Var(&flags.in, "in", "input file; repeatable, later files only add records and fill empty fields")
+	fs.Var(overlayFlag{layers: &flags.overlays}, "overlay", "APN file layered over the input as [merge:|patch:|apply:]path; repeatable")
+	fs.Var(overlayFlag{layers: &flags.overlays, dir: true}, "overlay-dir", "directory whose *.xml, *.json and *.yaml files are layered in lexical order; repeatable")
+	fs.StringVar(&flags.overlayMode, "overlay-mode", "patch", "default overlay update mode: merge, patch, apply")
 	fs.BoolVar(&flags.stdin, "stdin", false, "read input from stdin")
 	fs.StringVar(&flags.url, "url", "", "input URL")
 	fs.BoolVar(&flags.base64, "base64", false, "decode base64 input body")
-	fs.StringVar(&flags.inputFormat, "input-format", "", "input format: xml or json")
+	fs.StringVar(&flags.inputFormat, "input-format", "", "input format: "+formatNames("auto"))
 	fs.StringVar(&flags.out, "out", "", "output file")
-	fs.StringVar(&flags.outputFormat, "output-format", flags.outputFormat, "output format: xml, json, table, csv, text, summary")
+	fs.StringVar(&flags.outputFormat, "output-format", flags.outputFormat, "output format: "+formatNames("table", "text", "summary"))
+	fs.StringVar(&flags.columns, "columns", "", "comma-separated csv and tsv columns; defaults to every column")
 	fs.BoolVar(&flags.flat, "flat", false, "flatten grouped records")
 	fs.StringVar(&flags.groupBy, "group-by", "", "group flat records by plmn or identity")
 	fs.BoolVar(&flags.normalize, "normalize", false, "normalize records before output")
 	fs.StringVar(&flags.dedupeBy, "dedupe-by", "", "dedupe/group by plmn or identity")
 	fs.IntVar(&flags.offset, "offset", 0, "skip N materialized records before output")
 	fs.IntVar(&flags.limit, "limit", 0, "limit materialized records after filtering")
+	fs.BoolVar(&flags.preserve, "preserve", false, "keep XML comments and original record order")
+	fs.BoolVar(&flags.lenient, "lenient", false, "keep unknown XML and JSON enum tokens and report them as warnings")
+	fs.StringVar(&flags.layout, "layout", "", "canonical XML output layout: element or attribute")
+	fs.StringVar(&flags.profile, "profile", "", "XML export profile: aosp, android-9, android-10, android-14, lineage")
+	fs.StringVar(&flags.xmlVersion, "xml-version", "", "XML root version; defaults to the profile or input version")
+	fs.BoolVar(&flags.effective, "effective", false, "fill unset fields with the Android defaults of --profile (aosp by default)")
+	fs.BoolVar(&flags.redact, "redact", false, "replace APN usernames and passwords in the output")
+	fs.BoolVar(&flags.resolveSecret, "resolve-secrets", false, "resolve ${env:NAME} and ${file:/path} references in XML and JSON output")
 	return flags, fs
 }
 
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
 	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
 )
 
@@ -15,23 +18,154 @@ func loadAPNs(flags *commonFlags) (apnxml.Array, error) {
 	if err != nil {
 		return nil, err
 	}
-	if flags.url != "" {
+	var report apnxml.DecodeReport
+	optionList := append(decodeOptions(flags), apnxml.WithDecodeReport(&report))
+	defer func() {
+		flags.inputVersion = report.Version
+		flags.inputWarnings = report.Warnings
+		for _, warning := range report.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+		}
+	}()
+	var data apnxml.Array
+	switch {
+	case flags.url != "":
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
-		return apnxml.ImportFromURL(ctx, http.DefaultClient, flags.url, format, flags.base64)
+		data, err = apnxml.ImportFromURL(ctx, http.DefaultClient, flags.url, format, flags.base64, optionList...)
+	case flags.stdin:
+		data, err = apnxml.ImportFromReader(os.Stdin, format, optionList...)
+	case len(flags.in) > 0:
+		data, err = loadInputs(flags.in, flags.inputFormat, optionList...)
+	default:
+		return nil, fmt.Errorf("input is required: use --in, --stdin, or --url")
 	}
-	if flags.stdin {
-		return apnxml.ImportFromReader(os.Stdin, format)
+	if err != nil {
+		return nil, err
+	}
+	data, err = applyOverlays(data, flags, optionList...)
+	if err != nil {
+		return nil, err
+	}
+	return effectiveAPNs(data, flags)
+}
+
+func effectiveAPNs(data apnxml.Array, flags *commonFlags) (apnxml.Array, error) {
+	if !flags.effective {
+		return data, nil
+	}
+	if flags.profile == "" {
+		return data.Effective(), nil
+	}
+	profile, err := apnxml.ParseExportProfile(flags.profile)
+	if err != nil {
+		return nil, err
+	}
+	return data.EffectiveFor(profile), nil
+}
+
+func loadInputs(paths []string, formatValue string, optionList ...apnxml.DecodeOption) (apnxml.Array, error) {
+	var tool apntool.Array
+	for index, path := range paths {
+		data, err := loadFile(path, formatValue, optionList...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if index == 0 {
+			tool = apntool.From(data, apntool.WithTrustedInput())
+			continue
+		}
+		tool = tool.Merge(data)
 	}
-	if flags.in != "" {
-		return loadFile(flags.in, flags.inputFormat)
+	return tool.Data(), nil
+}
+
+func applyOverlays(data apnxml.Array, flags *commonFlags, optionList ...apnxml.DecodeOption) (apnxml.Array, error) {
+	if len(flags.overlays) == 0 {
+		return data, nil
+	}
+	defaultMode, err := apnxml.ParseObjectUpdateMode(flags.overlayMode)
+	if err != nil {
+		return nil, err
+	}
+
+	tool := apntool.From(data, apntool.WithTrustedInput())
+	for _, layer := range flags.overlays {
+		mode, path := splitOverlayMode(layer.path, defaultMode)
+		paths := []string{path}
+		if layer.dir {
+			paths, err = overlayDirFiles(path)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, path := range paths {
+			layerData, err := loadFile(path, "", optionList...)
+			if err != nil {
+				return nil, fmt.Errorf("overlay %s: %w", path, err)
+			}
+			tool = updateAPNs(tool, layerData, mode)
+		}
 	}
-	return nil, fmt.Errorf("input is required: use --in, --stdin, or --url")
+	return tool.Data(), nil
 }
 
-func loadFile(path string, formatValue string) (apnxml.Array, error) {
+func splitOverlayMode(value string, defaultMode apnxml.ObjectUpdateMode) (apnxml.ObjectUpdateMode, string) {
+	if prefix, path, ok := strings.Cut(value, ":"); ok && prefix != "" {
+		if mode, err := apnxml.ParseObjectUpdateMode(prefix); err == nil {
+			return mode, path
+		}
+	}
+	return defaultMode, value
+}
+
+func overlayDirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		extension := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (extension != ".xml" && extension != ".json" && extension != ".yaml" && extension != ".yml") {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
+	return paths, nil
+}
+
+func updateAPNs(tool apntool.Array, data apnxml.Array, mode apnxml.ObjectUpdateMode) apntool.Array {
+	switch mode {
+	case apnxml.ObjectUpdateMerge:
+		return tool.Merge(data)
+	case apnxml.ObjectUpdateApply:
+		return tool.ApplyUpdate(data)
+	default:
+		return tool.Patch(data)
+	}
+}
+
+func decodeOptions(flags *commonFlags) []apnxml.DecodeOption {
+	var optionList []apnxml.DecodeOption
+	if flags.preserve {
+		optionList = append(optionList, apnxml.WithPreserveDocument())
+	}
+	if flags.lenient {
+		optionList = append(optionList, apnxml.WithLenientDecode())
+	}
+	return optionList
+}
+
+func loadFile(path string, formatValue string, optionList ...apnxml.DecodeOption) (apnxml.Array, error) {
+	if archivePath, member, ok := parseArchiveSelector(path); ok {
+		if formatValue != "" {
+			return nil, fmt.Errorf("--input-format cannot be combined with an archive selector")
+		}
+		return apnxml.ImportFromArchive(archivePath, member, optionList...)
+	}
 	if formatValue == "" {
-		return apnxml.ImportFromFile(path)
+		return apnxml.ImportFromFile(path, optionList...)
 	}
 	format, err := apnxml.ParseFormat(formatValue)
 	if err != nil {
@@ -42,18 +176,27 @@ func loadFile(path string, formatValue string) (apnxml.Array, error) {
 		return nil, err
 	}
 	defer file.Close()
-	return apnxml.ImportFromReader(file, format)
+	return apnxml.ImportFromReader(file, format, optionList...)
+}
+
+func parseArchiveSelector(value string) (string, string, bool) {
+	for _, scheme := range []string{"zip://", "tar://"} {
+		if strings.HasPrefix(value, scheme) {
+			archivePath, member, _ := strings.Cut(strings.TrimPrefix(value, scheme), "!")
+			return archivePath, member, true
+		}
+	}
+	return "", "", false
 }
 
 func inputFormat(flags *commonFlags) (apnxml.Format, error) {
 	if flags.inputFormat != "" {
 		return apnxml.ParseFormat(flags.inputFormat)
 	}
-	if flags.in != "" {
-		return apnxml.FormatFromFilename(flags.in)
-	}
-	if flags.url != "" {
-		return apnxml.FormatXML, nil
+	if len(flags.in) == 1 {
+		if format, err := apnxml.FormatFromFilename(flags.in[0]); err == nil {
+			return format, nil
+		}
 	}
-	return "", fmt.Errorf("--input-format is required for stdin")
+	return apnxml.FormatAuto, nil
 }
@@ -40,6 +40,22 @@ func run(args []string) error {
 		return runInspect(args[1:])
 	case "build":
 		return runBuild(args[1:])
+	case "fmt":
+		return runFmt(args[1:])
+	case "migrate":
+		return runMigrate(args[1:])
+	case "anonymize":
+		return runAnonymize(args[1:])
+	case "export":
+		return runExport(args[1:])
+	case "simulate":
+		return runSimulate(args[1:])
+	case "diff":
+		return runDiff(args[1:])
+	case "scan":
+		return runScan(args[1:])
+	case "serve":
+		return runServe(args[1:])
 	case "help", "-h", "--help":
 		usage(os.Stdout)
 		return nil
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -10,35 +11,104 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnexport"
 	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
 	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
 )
 
 func writeAPNs(flags *commonFlags, tool apntool.Array) error {
+	tool, err := outputAPNs(flags, tool)
+	if err != nil {
+		return err
+	}
 	switch strings.ToLower(flags.outputFormat) {
-	case "json":
-		return writeData(flags.out, func(writer io.Writer) error {
-			return apnxml.ExportToWriter(tool.Data(), writer, apnxml.FormatJSON)
-		})
-	case "xml":
-		return writeData(flags.out, func(writer io.Writer) error {
-			return apnxml.ExportToWriter(tool.Data(), writer, apnxml.FormatXML)
-		})
 	case "table", "text":
 		return writeData(flags.out, func(writer io.Writer) error {
 			return writeTable(writer, tool)
 		})
-	case "csv":
+	case string(apnexport.FormatCSV), string(apnexport.FormatTSV):
+		data := tool.Data()
+		if flags.resolveSecret {
+			data, err = data.ResolveSecrets(apnxml.LookupSecret)
+			if err != nil {
+				return err
+			}
+		}
+		options := apnexport.CSVOptions{Comma: ','}
+		if strings.EqualFold(flags.outputFormat, string(apnexport.FormatTSV)) {
+			options.Comma = '\t'
+		}
+		if flags.columns != "" {
+			options.Columns = strings.Split(flags.columns, ",")
+		}
 		return writeData(flags.out, func(writer io.Writer) error {
-			return writeCSV(writer, tool)
+			return apnexport.WriteCSV(writer, data, options)
 		})
 	case "summary":
 		return writeStats(flags, tool.Stats())
 	default:
-		return fmt.Errorf("unsupported output format: %s", flags.outputFormat)
+		format, err := apnxml.ParseFormat(flags.outputFormat)
+		if err != nil || !format.CanEncode() {
+			return fmt.Errorf("unsupported output format: %s", flags.outputFormat)
+		}
+		optionList, err := encodeOptions(flags)
+		if err != nil {
+			return err
+		}
+		return writeData(flags.out, func(writer io.Writer) error {
+			return apnxml.ExportToWriter(tool.Data(), writer, format, optionList...)
+		})
 	}
 }
 
+func formatNames(extra ...string) string {
+	names := append([]string(nil), extra...)
+	for _, format := range apnxml.Formats() {
+		names = append(names, string(format))
+	}
+	return strings.Join(names, ", ")
+}
+
+func outputAPNs(flags *commonFlags, tool apntool.Array) (apntool.Array, error) {
+	if flags.redact && flags.resolveSecret {
+		return apntool.Array{}, fmt.Errorf("--redact and --resolve-secrets are mutually exclusive")
+	}
+	if flags.redact {
+		return apntool.From(tool.Data().Redacted(), apntool.WithTrustedInput()), nil
+	}
+	return tool, nil
+}
+
+func encodeOptions(flags *commonFlags) ([]apnxml.EncodeOption, error) {
+	var optionList []apnxml.EncodeOption
+	if flags.layout != "" {
+		layout, err := apnxml.ParseCanonicalLayout(flags.layout)
+		if err != nil {
+			return nil, err
+		}
+		optionList = append(optionList, apnxml.WithCanonicalLayout(layout))
+	}
+	var profile apnxml.ExportProfile
+	if flags.profile != "" {
+		var err error
+		profile, err = apnxml.ParseExportProfile(flags.profile)
+		if err != nil {
+			return nil, err
+		}
+		optionList = append(optionList, apnxml.WithExportProfile(profile))
+	}
+	if flags.resolveSecret {
+		optionList = append(optionList, apnxml.WithSecretResolver(apnxml.LookupSecret))
+	}
+	switch {
+	case flags.xmlVersion != "":
+		optionList = append(optionList, apnxml.WithXMLVersion(flags.xmlVersion))
+	case profile.Version == "" && flags.inputVersion != "":
+		optionList = append(optionList, apnxml.WithXMLVersion(flags.inputVersion))
+	}
+	return optionList, nil
+}
+
 func writeStats(flags *commonFlags, stats apntool.Stats) error {
 	if strings.EqualFold(flags.outputFormat, "json") {
 		return writeJSON(flags.out, stats)
@@ -117,32 +187,7 @@ func writeTable(writer io.Writer, tool apntool.Array) error {
 }
 
 func writeCSV(writer io.Writer, tool apntool.Array) error {
-	csvWriter := csv.NewWriter(writer)
-	header := []string{"plmn", "carrier", "carrier_id", "type", "apn", "protocol", "roaming_protocol", "network", "profile_id", "enabled", "visible", "editable"}
-	if err := csvWriter.Write(header); err != nil {
-		return err
-	}
-	err := tool.ForEach(func(record apnxml.Object) error {
-		return csvWriter.Write([]string{
-			record.GetPLMN(),
-			record.Carrier,
-			intPtrString(record.CarrierID),
-			baseTypeString(record.Base),
-			apnString(record.Base),
-			protocolString(record.Bearer),
-			roamingProtocolString(record.Bearer),
-			networkString(record.Other),
-			profileIDString(record.Base),
-			boolPtrString(otherBool(record.Other, "enabled")),
-			boolPtrString(otherBool(record.Other, "visible")),
-			boolPtrString(otherBool(record.Other, "editable")),
-		})
-	})
-	if err != nil {
-		return err
-	}
-	csvWriter.Flush()
-	return csvWriter.Error()
+	return apnexport.WriteCSV(writer, tool.Data(), apnexport.CSVOptions{})
 }
 
 func writeInspect(writer io.Writer, tool apntool.Array) error {
@@ -179,19 +224,26 @@ func writeData(path string, write func(io.Writer) error) error {
 	if err != nil {
 		return err
 	}
-	defer closeOutput()
-	return write(writer)
+	err = write(writer)
+	if closeErr := closeOutput(); err == nil {
+		err = closeErr
+	}
+	return err
 }
 
-func outputWriter(path string) (io.Writer, func(), error) {
+func outputWriter(path string) (io.Writer, func() error, error) {
 	if path == "" {
-		return os.Stdout, func() {}, nil
+		return os.Stdout, func() error { return nil }, nil
+	}
+	if apnxml.IsArchiveFilename(path) {
+		var buffer bytes.Buffer
+		return &buffer, func() error { return apnxml.WriteArchiveFile(path, buffer.Bytes()) }, nil
 	}
 	file, err := os.Create(path)
 	if err != nil {
 		return nil, nil, err
 	}
-	return file, func() { _ = file.Close() }, nil
+	return file, file.Close, nil
 }
 
 func intPtrString(value *int) string {
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+const serverReloadInterval = time.Second
+
+type apnServer struct {
+	path           string
+	inputFormat    string
+	redact         bool
+	reloadInterval time.Duration
+
+	mutex   sync.Mutex
+	current atomic.Pointer[apnSnapshot]
+	checked atomic.Int64
+}
+
+type apnSnapshot struct {
+	tool    apntool.Array
+	modTime time.Time
+	size    int64
+}
+
+func newAPNServer(path string, inputFormat string, redact bool) (*apnServer, error) {
+	server := &apnServer{path: path, inputFormat: inputFormat, redact: redact, reloadInterval: serverReloadInterval}
+	if err := server.reload(); err != nil {
+		return nil, err
+	}
+	server.checked.Store(time.Now().UnixNano())
+	return server, nil
+}
+
+func (server *apnServer) reload() error {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	statPath := server.path
+	if archivePath, _, ok := parseArchiveSelector(server.path); ok {
+		statPath = archivePath
+	}
+	info, err := os.Stat(statPath)
+	if err != nil {
+		return err
+	}
+
+	current := server.current.Load()
+	if current != nil && info.ModTime().Equal(current.modTime) && info.Size() == current.size {
+		return nil
+	}
+
+	data, err := loadFile(server.path, server.inputFormat)
+	if err != nil {
+		return err
+	}
+	if server.redact {
+		data = data.Redacted()
+	}
+
+	server.current.Store(&apnSnapshot{
+		tool:    apntool.From(data, apntool.WithTrustedInput()),
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	})
+	return nil
+}
+
+func (server *apnServer) isReloadDue() bool {
+	now := time.Now().UnixNano()
+	checked := server.checked.Load()
+	if now-checked < int64(server.reloadInterval) {
+		return false
+	}
+	return server.checked.CompareAndSwap(checked, now)
+}
+
+func (server *apnServer) snapshot() apntool.Array {
+	if server.isReloadDue() {
+		if err := server.reload(); err != nil {
+			fmt.Fprintln(os.Stderr, "apnctl: reload:", err)
+		}
+	}
+	return server.current.Load().tool
+}
+
+func (server *apnServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/plmns", server.handlePLMNs)
+	mux.HandleFunc("/apns", server.handleAPNs)
+	mux.HandleFunc("/carriers/", server.handleCarrier)
+	mux.HandleFunc("/stats", server.handleStats)
+	mux.HandleFunc("/resolve", server.handleResolve)
+	return mux
+}
+
+func (server *apnServer) handlePLMNs(response http.ResponseWriter, request *http.Request) {
+	if !allowMethod(response, request, http.MethodGet) {
+		return
+	}
+	tool, err := server.query(request.URL.Query())
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeResponse(response, request, func(writer io.Writer, format string) error {
+		return encodeList(writer, format, "plmns", "plmn", tool.PLMNs())
+	})
+}
+
+func (server *apnServer) handleAPNs(response http.ResponseWriter, request *http.Request) {
+	if !allowMethod(response, request, http.MethodGet) {
+		return
+	}
+	tool, err := server.query(request.URL.Query())
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeResponse(response, request, func(writer io.Writer, format string) error {
+		return encodeAPNs(writer, format, tool)
+	})
+}
+
+func (server *apnServer) handleCarrier(response http.ResponseWriter, request *http.Request) {
+	if !allowMethod(response, request, http.MethodGet) {
+		return
+	}
+	carrierID, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(request.URL.Path, "/carriers/"), "/"))
+	if err != nil {
+		http.Error(response, "carrier ID must be an integer", http.StatusBadRequest)
+		return
+	}
+	tool := server.snapshot().Filter(apntool.ByCarrierID(carrierID))
+	if tool.Len() == 0 {
+		http.Error(response, fmt.Sprintf("carrier %d not found", carrierID), http.StatusNotFound)
+		return
+	}
+	writeResponse(response, request, func(writer io.Writer, format string) error {
+		return encodeAPNs(writer, format, tool)
+	})
+}
+
+func (server *apnServer) handleStats(response http.ResponseWriter, request *http.Request) {
+	if !allowMethod(response, request, http.MethodGet) {
+		return
+	}
+	tool, err := server.query(request.URL.Query())
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeResponse(response, request, func(writer io.Writer, format string) error {
+		return encodeStats(writer, format, tool.Stats())
+	})
+}
+
+func (server *apnServer) handleResolve(response http.ResponseWriter, request *http.Request) {
+	if !allowMethod(response, request, http.MethodPost) {
+		return
+	}
+	var profile apntool.SIMProfile
+	decoder := json.NewDecoder(io.LimitReader(request.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&profile); err != nil {
+		http.Error(response, fmt.Sprintf("invalid SIM profile: %v", err), http.StatusBadRequest)
+		return
+	}
+	tool := server.snapshot().Resolve(profile)
+	writeResponse(response, request, func(writer io.Writer, format string) error {
+		return encodeAPNs(writer, format, tool)
+	})
+}
+
+func (server *apnServer) query(values url.Values) (apntool.Array, error) {
+	filters, err := queryFilters(values)
+	if err != nil {
+		return apntool.Array{}, err
+	}
+	predicate, err := buildPredicate(filters)
+	if err != nil {
+		return apntool.Array{}, err
+	}
+	return server.snapshot().Filter(predicate), nil
+}
+
+func queryFilters(values url.Values) (*filterFlags, error) {
+	filters := &filterFlags{mcc: -1, mnc: -1, carrierID: -1}
+	for _, value := range values["plmn"] {
+		filters.plmn = append(filters.plmn, strings.Split(value, ",")...)
+	}
+	for name, target := range map[string]*int{"mcc": &filters.mcc, "mnc": &filters.mnc, "carrier-id": &filters.carrierID} {
+		if value := values.Get(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+	filters.carrier = values.Get("carrier")
+	filters.apn = values.Get("apn")
+	filters.apnContains = values.Get("apn-contains")
+	filters.apnType = values.Get("type")
+	filters.protocol = values.Get("protocol")
+	filters.network = values.Get("network")
+	filters.has = values["has"]
+	filters.without = values["without"]
+	for name, target := range map[string]*bool{"valid-only": &filters.validOnly, "invalid-only": &filters.invalidOnly, "not": &filters.invert} {
+		if value := values.Get(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+	return filters, nil
+}
+
+func allowMethod(response http.ResponseWriter, request *http.Request, method string) bool {
+	if request.Method == method || (method == http.MethodGet && request.Method == http.MethodHead) {
+		return true
+	}
+	response.Header().Set("Allow", method)
+	http.Error(response, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func negotiateFormat(accept string) (string, string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return "json", "application/json", true
+	}
+
+	var format, contentType string
+	bestQuality, bestRank := 0.0, 0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		quality := acceptQuality(params)
+		if quality <= 0 {
+			continue
+		}
+
+		var partFormat, partType string
+		rank := 2
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case "application/json":
+			partFormat, partType = "json", "application/json"
+		case "application/*", "*/*":
+			partFormat, partType, rank = "json", "application/json", 1
+		case "application/xml", "text/xml":
+			partFormat, partType = "xml", "application/xml"
+		case "text/csv":
+			partFormat, partType = "csv", "text/csv"
+		default:
+			continue
+		}
+
+		if quality > bestQuality || (quality == bestQuality && rank > bestRank) {
+			format, contentType = partFormat, partType
+			bestQuality, bestRank = quality, rank
+		}
+	}
+	return format, contentType, format != ""
+}
+
+func acceptQuality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(param, "=")
+		if !strings.EqualFold(strings.TrimSpace(name), "q") {
+			continue
+		}
+		quality, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || quality < 0 || quality > 1 {
+			return 0
+		}
+		return quality
+	}
+	return 1
+}
+
+func writeResponse(response http.ResponseWriter, request *http.Request, encode func(io.Writer, string) error) {
+	format, contentType, ok := negotiateFormat(request.Header.Get("Accept"))
+	if !ok {
+		http.Error(response, "supported media types: application/json, application/xml, text/csv", http.StatusNotAcceptable)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := encode(&body, format); err != nil {
+		http.Error(response, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(body.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	response.Header().Set("ETag", etag)
+	response.Header().Set("Vary", "Accept")
+	if matchETag(request.Header.Get("If-None-Match"), etag) {
+		response.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	response.Header().Set("Content-Type", contentType)
+	response.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	if request.Method != http.MethodHead {
+		_, _ = response.Write(body.Bytes())
+	}
+}
+
+func matchETag(header string, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == etag || value == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeAPNs(writer io.Writer, format string, tool apntool.Array) error {
+	switch format {
+	case "json":
+		return apnxml.ExportToWriter(tool.Data(), writer, apnxml.FormatJSON)
+	case "xml":
+		return apnxml.ExportToWriter(tool.Data(), writer, apnxml.FormatXML)
+	case "csv":
+		return writeCSV(writer, tool)
+	default:
+		return fmt.Errorf("unsupported response format: %s", format)
+	}
+}
+
+func encodeList(writer io.Writer, format string, root string, item string, values []string) error {
+	switch format {
+	case "json":
+		return json.NewEncoder(writer).Encode(values)
+	case "xml":
+		encoder := xml.NewEncoder(writer)
+		encoder.Indent("", "\t")
+		start := xml.StartElement{Name: xml.Name{Local: root}}
+		if err := encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, value := range values {
+			if err := encoder.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: item}}); err != nil {
+				return err
+			}
+		}
+		if err := encoder.EncodeToken(start.End()); err != nil {
+			return err
+		}
+		return encoder.Flush()
+	case "csv":
+		csvWriter := csv.NewWriter(writer)
+		if err := csvWriter.Write([]string{item}); err != nil {
+			return err
+		}
+		for _, value := range values {
+			if err := csvWriter.Write([]string{value}); err != nil {
+				return err
+			}
+		}
+		csvWriter.Flush()
+		return csvWriter.Error()
+	default:
+		return fmt.Errorf("unsupported response format: %s", format)
+	}
+}
+
+type statsEntry struct {
+	Kind  string `xml:"kind,attr"`
+	Key   string `xml:"key,attr"`
+	Count int    `xml:"count,attr"`
+}
+
+func statsEntries(stats apntool.Stats) []statsEntry {
+	entries := []statsEntry{
+		{Kind: "total", Key: "groups", Count: stats.Groups},
+		{Kind: "total", Key: "records", Count: stats.Records},
+		{Kind: "total", Key: "invalid", Count: stats.Invalid},
+	}
+	var byPLMN, byType []statsEntry
+	for plmn, count := range stats.ByPLMN {
+		byPLMN = append(byPLMN, statsEntry{Kind: "plmn", Key: plmn, Count: count})
+	}
+	for apnType, count := range stats.ByType {
+		byType = append(byType, statsEntry{Kind: "type", Key: apnType.String(), Count: count})
+	}
+	for _, group := range [][]statsEntry{byPLMN, byType} {
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].Key < group[j].Key
+		})
+		entries = append(entries, group...)
+	}
+	return entries
+}
+
+func encodeStats(writer io.Writer, format string, stats apntool.Stats) error {
+	switch format {
+	case "json":
+		return json.NewEncoder(writer).Encode(stats)
+	case "xml":
+		encoder := xml.NewEncoder(writer)
+		encoder.Indent("", "\t")
+		if err := encoder.Encode(struct {
+			XMLName xml.Name     `xml:"stats"`
+			Entries []statsEntry `xml:"entry"`
+		}{Entries: statsEntries(stats)}); err != nil {
+			return err
+		}
+		return encoder.Flush()
+	case "csv":
+		csvWriter := csv.NewWriter(writer)
+		if err := csvWriter.Write([]string{"kind", "key", "count"}); err != nil {
+			return err
+		}
+		for _, entry := range statsEntries(stats) {
+			if err := csvWriter.Write([]string{entry.Kind, entry.Key, strconv.Itoa(entry.Count)}); err != nil {
+				return err
+			}
+		}
+		csvWriter.Flush()
+		return csvWriter.Error()
+	default:
+		return fmt.Errorf("unsupported response format: %s", format)
+	}
+}
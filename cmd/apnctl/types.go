@@ -1,6 +1,10 @@
 package main
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
 
 type stringList []string
 
@@ -13,20 +17,61 @@ func (list *stringList) Set(value string) error {
 	return nil
 }
 
+type overlayLayer struct {
+	path string
+	dir  bool
+}
+
+type overlayFlag struct {
+	layers *[]overlayLayer
+	dir    bool
+}
+
+func (value overlayFlag) String() string {
+	if value.layers == nil {
+		return ""
+	}
+	var paths []string
+	for _, layer := range *value.layers {
+		if layer.dir == value.dir {
+			paths = append(paths, layer.path)
+		}
+	}
+	return strings.Join(paths, ",")
+}
+
+func (value overlayFlag) Set(path string) error {
+	*value.layers = append(*value.layers, overlayLayer{path: path, dir: value.dir})
+	return nil
+}
+
 type commonFlags struct {
-	in           string
-	out          string
-	url          string
-	stdin        bool
-	base64       bool
-	inputFormat  string
-	outputFormat string
-	flat         bool
-	groupBy      string
-	normalize    bool
-	dedupeBy     string
-	offset       int
-	limit        int
+	in            stringList
+	overlays      []overlayLayer
+	overlayMode   string
+	out           string
+	url           string
+	stdin         bool
+	base64        bool
+	inputFormat   string
+	outputFormat  string
+	columns       string
+	flat          bool
+	groupBy       string
+	normalize     bool
+	dedupeBy      string
+	offset        int
+	limit         int
+	preserve      bool
+	lenient       bool
+	layout        string
+	profile       string
+	xmlVersion    string
+	redact        bool
+	effective     bool
+	resolveSecret bool
+	inputVersion  string
+	inputWarnings []apnxml.DecodeWarning
 }
 
 type filterFlags struct {
@@ -16,8 +16,16 @@ func usage(writer io.Writer) {
   apnctl validate --in apns-full-conf.xml --strict
   apnctl inspect  --in apns-full-conf.xml --plmn 25001
   apnctl build    --carrier "Operator" --mcc 999 --mnc 99 --apn iot.example
+  apnctl fmt      --in apns-full-conf.xml --write --layout attribute
+  apnctl migrate  --in legacy-apns.xml --out apns-conf.xml
+  apnctl anonymize --in apns-conf.xml --key-file report.key --out report.xml
+  apnctl export   --in apns-full-conf.xml --plmn 25001 --target adb --action insert --out push-apns.sh
+  apnctl simulate --in apns-full-conf.xml --plmn 25001 --network-type nr --roaming true
+  apnctl diff     --in apns-full-conf.xml --against bugreport.txt --plmn 25001
+  apnctl scan     --root ./aosp --output-format csv
+  apnctl serve    --in apns-full-conf.xml --listen :8080
 
-Input flags: --in, --stdin, --url, --base64, --input-format xml|json
-Output flags: --out, --output-format xml|json|table|csv|text|summary, --flat, --group-by, --dedupe-by, --normalize, --offset, --limit
+Input flags: --in file|zip://a.zip!/member|tar://a.tar!/member (repeatable), --overlay [merge:|patch:|apply:]file, --overlay-dir, --overlay-mode, --stdin, --url, --base64, --input-format auto|xml|json|yaml|<registered format>
+Output flags: --out, --output-format xml|json|yaml|table|csv|text|summary|<registered format>, --flat, --group-by, --dedupe-by, --normalize, --effective, --offset, --limit, --redact, --resolve-secrets
 Filter flags: --plmn, --mcc, --mnc, --carrier-id, --carrier, --apn, --apn-contains, --type, --protocol, --network, --has, --without, --valid-only, --invalid-only, --not`)
 }
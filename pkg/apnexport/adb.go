@@ -0,0 +1,219 @@
+package apnexport
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+const FormatADB apnxml.Format = "adb"
+
+const adbCarriersURI = "content://telephony/carriers"
+
+type ADBAction int
+
+const (
+	ADBInsert ADBAction = iota
+	ADBUpdate
+	ADBDelete
+)
+
+type ADBBinding struct {
+	Column string
+	Type   string
+	Value  string
+}
+
+func init() {
+	if _, err := apnxml.RegisterFormat(string(FormatADB), []string{".adb.sh"}, nil, encodeADB); err != nil {
+		panic(err)
+	}
+}
+
+func ParseADBAction(value string) (ADBAction, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "insert":
+		return ADBInsert, nil
+	case "update":
+		return ADBUpdate, nil
+	case "delete":
+		return ADBDelete, nil
+	default:
+		return 0, fmt.Errorf("unsupported adb action: %s", value)
+	}
+}
+
+func ADBBindings(record apnxml.Object) []ADBBinding {
+	var bindingArray []ADBBinding
+	bindString := func(column string, value *string) {
+		if value != nil {
+			bindingArray = append(bindingArray, ADBBinding{Column: column, Type: "s", Value: *value})
+		}
+	}
+	bindInt := func(column string, value *int) {
+		if value != nil {
+			bindingArray = append(bindingArray, ADBBinding{Column: column, Type: "i", Value: strconv.Itoa(*value)})
+		}
+	}
+	bindPort := func(column string, value *int) {
+		if value != nil {
+			bindingArray = append(bindingArray, ADBBinding{Column: column, Type: "s", Value: strconv.Itoa(*value)})
+		}
+	}
+	bindBool := func(column string, value *bool) {
+		if value != nil {
+			bindingArray = append(bindingArray, ADBBinding{Column: column, Type: "b", Value: strconv.FormatBool(*value)})
+		}
+	}
+	bindText := func(column string, value xml.MarshalerAttr) {
+		if xmlAttr, err := value.MarshalXMLAttr(xml.Name{Local: column}); err == nil && xmlAttr.Value != "" {
+			bindingArray = append(bindingArray, ADBBinding{Column: column, Type: "s", Value: xmlAttr.Value})
+		}
+	}
+
+	if record.ObjectRoot != nil {
+		bindingArray = append(bindingArray, ADBBinding{Column: "name", Type: "s", Value: record.Carrier})
+		if record.Mcc != nil && record.Mnc != nil {
+			bindingArray = append(bindingArray,
+				ADBBinding{Column: "numeric", Type: "s", Value: record.GetPLMN()},
+				ADBBinding{Column: "mcc", Type: "s", Value: fmt.Sprintf("%03d", *record.Mcc)},
+				ADBBinding{Column: "mnc", Type: "s", Value: fmt.Sprintf("%02d", *record.Mnc)},
+			)
+		}
+		bindInt("carrier_id", record.CarrierID)
+	}
+	if record.Base != nil {
+		bindString("apn", record.Base.Apn)
+		if record.Base.Type != nil {
+			bindText("type", *record.Base.Type)
+		}
+		bindInt("profile_id", record.Base.ProfileID)
+	}
+	if record.Auth != nil {
+		if record.Auth.Type != nil {
+			bindingArray = append(bindingArray, ADBBinding{Column: "authtype", Type: "i", Value: strconv.Itoa(int(*record.Auth.Type))})
+		}
+		bindString("user", record.Auth.Username)
+		bindString("password", record.Auth.Password)
+	}
+	if record.Bearer != nil {
+		if record.Bearer.Type != nil {
+			bindText("protocol", *record.Bearer.Type)
+		}
+		if record.Bearer.TypeRoaming != nil {
+			bindText("roaming_protocol", *record.Bearer.TypeRoaming)
+		}
+		bindInt("mtu", record.Bearer.Mtu)
+		bindString("server", record.Bearer.Server)
+	}
+	if record.Proxy != nil {
+		bindString("proxy", record.Proxy.Server)
+		bindPort("port", record.Proxy.Port)
+	}
+	if record.Mms != nil {
+		bindString("mmsc", record.Mms.Center)
+		bindString("mmsproxy", record.Mms.Server)
+		bindPort("mmsport", record.Mms.Port)
+	}
+	if record.Mvno != nil {
+		bindString("mvno_type", record.Mvno.Type)
+		bindString("mvno_match_data", record.Mvno.Data)
+	}
+	if record.Limit != nil {
+		bindInt("max_conns", record.Limit.MaxConn)
+		bindInt("max_conns_time", record.Limit.MaxConnTime)
+	}
+	if record.Other != nil {
+		if record.Other.NetworkTypeBitmask != nil {
+			bindingArray = append(bindingArray, ADBBinding{Column: "network_type_bitmask", Type: "i", Value: strconv.Itoa(int(*record.Other.NetworkTypeBitmask))})
+		}
+		bindBool("modem_cognitive", record.Other.ModemCognitive)
+		bindBool("carrier_enabled", record.Other.CarrierEnabled)
+		bindBool("user_visible", record.Other.UserVisible)
+		bindBool("user_editable", record.Other.UserEditable)
+	}
+
+	return bindingArray
+}
+
+func adbWhere(bindingArray []ADBBinding) string {
+	var conditionArray []string
+	for _, binding := range bindingArray {
+		switch binding.Column {
+		case "numeric", "apn", "type", "mvno_type", "mvno_match_data":
+			conditionArray = append(conditionArray, fmt.Sprintf("%s='%s'", binding.Column, strings.ReplaceAll(binding.Value, "'", "''")))
+		}
+	}
+
+	return strings.Join(conditionArray, " AND ")
+}
+
+func ADBCommand(record apnxml.Object, action ADBAction) string {
+	bindingArray := ADBBindings(record)
+
+	var argumentArray []string
+	switch action {
+	case ADBUpdate:
+		argumentArray = append(argumentArray, "content", "update", "--uri", adbCarriersURI)
+	case ADBDelete:
+		argumentArray = append(argumentArray, "content", "delete", "--uri", adbCarriersURI)
+	default:
+		argumentArray = append(argumentArray, "content", "insert", "--uri", adbCarriersURI)
+	}
+
+	if action != ADBDelete {
+		for _, binding := range bindingArray {
+			argumentArray = append(argumentArray, "--bind", adbQuote(binding.Column+":"+binding.Type+":"+binding.Value))
+		}
+	}
+	if action != ADBInsert {
+		argumentArray = append(argumentArray, "--where", adbQuote(adbWhere(bindingArray)))
+	}
+
+	return "adb shell " + shellDoubleQuote(strings.Join(argumentArray, " "))
+}
+
+func ADBScript(apnArray apnxml.Array, action ADBAction) []byte {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("#!/bin/sh\nset -e\n")
+	_ = apntool.From(apnArray, apntool.WithTrustedInput()).ForEach(func(record apnxml.Object) error {
+		if record.ObjectRoot == nil || !record.ObjectRoot.Validate() {
+			return nil
+		}
+
+		var apn, apnType string
+		if record.Base != nil && record.Base.Apn != nil {
+			apn = *record.Base.Apn
+		}
+		if record.Base != nil && record.Base.Type != nil {
+			apnType = record.Base.Type.String()
+		}
+		fmt.Fprintf(&buffer, "\n# %s %s %s\n%s\n", record.GetPLMN(), apn, apnType, ADBCommand(record, action))
+		return nil
+	})
+
+	return buffer.Bytes()
+}
+
+func adbQuote(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._:/@%+=,-") == "" {
+		return value
+	}
+
+	return shellDoubleQuote(value)
+}
+
+func shellDoubleQuote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`")
+	return `"` + replacer.Replace(value) + `"`
+}
+
+func encodeADB(apnArray apnxml.Array, _ ...apnxml.EncodeOption) ([]byte, error) {
+	return ADBScript(apnArray, ADBInsert), nil
+}
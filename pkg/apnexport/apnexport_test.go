@@ -0,0 +1,468 @@
+package apnexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+const testXML = `<apns version="8">
+	<apn carrier="Carrier A" mcc="250" mnc="01" apn="internet" type="default,supl" protocol="IPV4V6" user="user" password="secret" network_type_bitmask="13|20" mtu="1400" mtu_v6="1280" modem_cognitive="true" />
+	<apn carrier="Carrier A" mcc="250" mnc="01" apn="mms" type="mms" carrier_enabled="false" />
+	<apn carrier="Broken" mcc="999" apn="broken" type="default" />
+</apns>`
+
+func testData(t *testing.T) apnxml.Array {
+	t.Helper()
+	apnArray, err := apnxml.ImportFromXMLByte([]byte(testXML))
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+	return apnArray
+}
+
+func TestDataProfilesUseEffectiveValues(t *testing.T) {
+	profileArray := DataProfiles(testData(t))
+	if len(profileArray) != 2 {
+		t.Fatalf("expected two valid data profiles, got %d", len(profileArray))
+	}
+
+	internet := profileArray[1]
+	want := DataProfile{
+		PLMN:                    "25001",
+		APN:                     "internet",
+		Protocol:                "IPV4V6",
+		RoamingProtocol:         "IP",
+		AuthType:                int(apnxml.ObjectAuthTypePAP | apnxml.ObjectAuthTypeCHAP),
+		User:                    "user",
+		Password:                "secret",
+		SupportedApnTypesBitmap: int(apnxml.ObjectBaseTypeDefault | apnxml.ObjectBaseTypeSUPL),
+		SupportedApnTypes:       "default|supl",
+		BearerBitmap:            1<<13 | 1<<19,
+		BearerTypes:             "lte|nr",
+		NetworkTypeBitmask:      int(apnxml.ObjectNetworkTypeLTE | apnxml.ObjectNetworkTypeNR),
+		MtuV4:                   1400,
+		MtuV6:                   1280,
+		Persistent:              true,
+		Enabled:                 true,
+	}
+	if internet != want {
+		t.Fatalf("unexpected data profile:\n got %+v\nwant %+v", internet, want)
+	}
+	if profileArray[0].APN != "mms" || profileArray[0].Enabled || profileArray[0].AuthType != 0 {
+		t.Fatalf("unexpected mms data profile: %+v", profileArray[0])
+	}
+}
+
+func TestDataProfileFormatsAreRegistered(t *testing.T) {
+	if format, err := apnxml.FormatFromFilename("modem.dataprofile.csv"); err != nil || format != FormatDataProfileCSV {
+		t.Fatalf("FormatFromFilename = %q, %v", format, err)
+	}
+	if FormatDataProfile.CanDecode() || !FormatDataProfile.CanEncode() {
+		t.Fatal("dataprofile must be an export-only format")
+	}
+
+	var jsonBuffer bytes.Buffer
+	if err := apnxml.ExportToWriter(testData(t), &jsonBuffer, FormatDataProfile); err != nil {
+		t.Fatalf("ExportToWriter returned error: %v", err)
+	}
+	var profileArray []map[string]any
+	if err := json.Unmarshal(jsonBuffer.Bytes(), &profileArray); err != nil || len(profileArray) != 2 || profileArray[1]["bearerBitmap"].(float64) != 532480 || profileArray[1]["networkTypeBitmask"].(float64) != 528384 || profileArray[1]["preferred"] != nil {
+		t.Fatalf("unexpected dataprofile JSON (%v):\n%s", err, jsonBuffer.String())
+	}
+
+	var csvBuffer bytes.Buffer
+	if err := apnxml.ExportToWriter(testData(t), &csvBuffer, FormatDataProfileCSV); err != nil {
+		t.Fatalf("ExportToWriter returned error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(csvBuffer.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "plmn,profile_id,apn,protocol") || lines[2] != "25001,0,internet,IPV4V6,IP,3,user,secret,5,default|supl,532480,lte|nr,528384,1400,1280,true,true,0,0" {
+		t.Fatalf("unexpected dataprofile CSV:\n%s", csvBuffer.String())
+	}
+}
+
+func TestADBCommandsBindCarrierColumns(t *testing.T) {
+	apnArray, err := apnxml.ImportFromXMLByte([]byte(`<apns version="8">
+	<apn carrier="Carrier 'A'" carrier_id="10" mcc="250" mnc="01" apn="internet" type="default,supl" protocol="IPV4V6" authtype="1" user="user" port="8080" mtu="1400" network_type_bitmask="13" carrier_enabled="false" mvno_type="gid" mvno_match_data="A1" />
+</apns>`))
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+	var record apnxml.Object
+	_ = apntool.From(apnArray, apntool.WithTrustedInput()).ForEach(func(apnObject apnxml.Object) error {
+		record = apnObject
+		return nil
+	})
+
+	var bindingArray []string
+	for _, binding := range ADBBindings(record) {
+		bindingArray = append(bindingArray, binding.Column+":"+binding.Type+":"+binding.Value)
+	}
+	want := "name:s:Carrier 'A',numeric:s:25001,mcc:s:250,mnc:s:01,carrier_id:i:10,apn:s:internet,type:s:default,supl,authtype:i:1,user:s:user,password:s:,protocol:s:IPV4V6,mtu:i:1400,port:s:8080,mvno_type:s:gid,mvno_match_data:s:A1,network_type_bitmask:i:4096,carrier_enabled:b:false"
+	if got := strings.Join(bindingArray, ","); got != want {
+		t.Fatalf("unexpected bindings:\n got %s\nwant %s", got, want)
+	}
+
+	insert := ADBCommand(record, ADBInsert)
+	if !strings.HasPrefix(insert, `adb shell "content insert --uri content://telephony/carriers --bind \"name:s:Carrier 'A'\" --bind numeric:s:25001`) || strings.Contains(insert, "--where") {
+		t.Fatalf("unexpected insert command: %s", insert)
+	}
+	wantDelete := `adb shell "content delete --uri content://telephony/carriers --where \"numeric='25001' AND apn='internet' AND type='default,supl' AND mvno_type='gid' AND mvno_match_data='A1'\""`
+	if got := ADBCommand(record, ADBDelete); got != wantDelete {
+		t.Fatalf("unexpected delete command:\n got %s\nwant %s", got, wantDelete)
+	}
+	if update := ADBCommand(record, ADBUpdate); !strings.Contains(update, "--bind carrier_enabled:b:false --where") {
+		t.Fatalf("update must bind values and select the row: %s", update)
+	}
+
+	if _, err := ParseADBAction("upsert"); err == nil {
+		t.Fatal("unknown adb action must return error")
+	}
+	if script := string(ADBScript(testData(t), ADBDelete)); strings.Count(script, "adb shell") != 2 || !strings.HasPrefix(script, "#!/bin/sh\n") {
+		t.Fatalf("unexpected adb script:\n%s", script)
+	}
+}
+
+func TestShillRoundTripsOperatorDB(t *testing.T) {
+	const textproto = `# serviceproviders excerpt
+mno {
+  data {
+    uuid: "1"
+    localized_name { name: "Operator One" language: "en" }
+    mccmnc: "25001"
+    mobile_apn {
+      apn: "tether"
+      type: DUN
+      authentication: CHAP
+      username: "user"
+      password: 'p\'a"ss'
+    }
+    mobile_apn {
+      apn: "internet"
+      type: DEFAULT
+      type: IA
+      ip_type: IPV4V6
+    }
+  }
+  mvno {
+    mvno_filter { type: GID1 regex: "A1.*" }
+    data { uuid: "2" mobile_apn { apn: "virtual" } }
+  }
+}
+mvno {
+  mvno_filter: { type: MCCMNC regex: "25002" }
+  mvno_filter: { type: IMSI regex: "25002[0-9]5.*" }
+  data { uuid: "3" localized_name < name: "Imsi MVNO" > mobile_apn { apn: "imsi.apn" ip_type: IPV6 } }
+}
+`
+	apnArray, err := apnxml.ImportFromReader(strings.NewReader(textproto), FormatShill)
+	if err != nil {
+		t.Fatalf("ImportFromReader returned error: %v", err)
+	}
+
+	recordMap := map[string]apnxml.Object{}
+	_ = apntool.From(apnArray).ForEach(func(record apnxml.Object) error {
+		recordMap[record.GetPLMN()+"/"+*record.Base.Apn] = record
+		return nil
+	})
+	if len(recordMap) != 4 {
+		t.Fatalf("expected four records, got %d", len(recordMap))
+	}
+	if record := recordMap["25001/internet"]; *record.Base.Type != apnxml.ObjectBaseTypeDefault|apnxml.ObjectBaseTypeIA || *record.Bearer.Type != apnxml.ObjectBearerProtocolIPv4v6 {
+		t.Fatalf("unexpected internet record: %+v", record)
+	}
+	if record := recordMap["25001/tether"]; *record.Base.Type != apnxml.ObjectBaseTypeDUN || *record.Auth.Type != apnxml.ObjectAuthTypeCHAP || *record.Auth.Password != `p'a"ss` {
+		t.Fatalf("unexpected tether record: %+v %+v", record.Base, record.Auth)
+	}
+	if record := recordMap["25001/virtual"]; record.Mvno == nil || *record.Mvno.Type != "gid" || *record.Mvno.Data != "A1" || *record.Base.Type != apnxml.ObjectBaseTypeDefault {
+		t.Fatalf("unexpected gid MVNO record: %+v", record.Mvno)
+	}
+	if record := recordMap["25002/imsi.apn"]; record.Mvno == nil || *record.Mvno.Type != "imsi" || *record.Mvno.Data != "25002x5" {
+		t.Fatalf("unexpected imsi MVNO record: %+v", record.Mvno)
+	}
+
+	var buffer bytes.Buffer
+	if err := apnxml.ExportToWriter(apnArray, &buffer, FormatShill); err != nil {
+		t.Fatalf("ExportToWriter returned error: %v", err)
+	}
+	exported := buffer.String()
+	for _, want := range []string{
+		"mno {\n  data {\n    uuid: \"apnxml.25001\"\n",
+		"    mobile_apn {\n      apn: \"internet\"\n      type: DEFAULT\n      type: IA\n      ip_type: IPV4V6\n    }\n    mobile_apn {\n      apn: \"tether\"\n",
+		"      password: \"p'a\\\"ss\"\n",
+		"    mvno_filter {\n      type: GID1\n      regex: \"A1.*\"\n    }\n",
+		"      regex: \"25002[0-9]5.*\"\n",
+	} {
+		if !strings.Contains(exported, want) {
+			t.Fatalf("shill export does not contain %q:\n%s", want, exported)
+		}
+	}
+
+	roundTrip, err := apnxml.ImportFromReader(strings.NewReader(exported), FormatShill)
+	if err != nil {
+		t.Fatalf("re-import returned error: %v", err)
+	}
+	if roundTrip.CountRecords() != apnArray.CountRecords() {
+		t.Fatalf("round trip changed record count: %d != %d", roundTrip.CountRecords(), apnArray.CountRecords())
+	}
+
+	if _, err := apnxml.ImportFromReader(strings.NewReader(`mno { data { uuid: "1" `), FormatShill); err == nil {
+		t.Fatal("truncated textproto must return error")
+	}
+}
+
+func TestShillDecodeKeepsEveryMobileAPN(t *testing.T) {
+	const textproto = `mno {
+  data {
+    uuid: "1"
+    localized_name { name: "Operator One" }
+    mccmnc: "25001"
+    mobile_apn { apn: "internet" type: DEFAULT }
+    mobile_apn { apn: "internet2" type: DEFAULT }
+    mobile_apn { apn: "attach" type: IA }
+  }
+  mvno {
+    mvno_filter { type: OPERATOR_NAME regex: "Virt" }
+    data { uuid: "2" mobile_apn { apn: "virt.internet" type: DEFAULT } }
+  }
+}
+`
+	apnArray, err := apnxml.ImportFromReader(strings.NewReader(textproto), FormatShill)
+	if err != nil {
+		t.Fatalf("ImportFromReader returned error: %v", err)
+	}
+
+	var apnNameArray []string
+	_ = apntool.From(apnArray).ForEach(func(record apnxml.Object) error {
+		apnNameArray = append(apnNameArray, *record.Base.Apn)
+		if *record.Base.Apn == "virt.internet" && (record.Mvno == nil || *record.Mvno.Type != "spn" || *record.Mvno.Data != "Virt") {
+			t.Fatalf("unexpected spn MVNO record: %+v", record.Mvno)
+		}
+		return nil
+	})
+	if strings.Join(apnNameArray, ",") != "internet,internet2,attach,virt.internet" {
+		t.Fatalf("every mobile_apn must be imported in file order, got %v", apnNameArray)
+	}
+
+	var buffer bytes.Buffer
+	if err := apnxml.ExportToWriter(apnArray, &buffer, FormatShill); err != nil {
+		t.Fatalf("ExportToWriter returned error: %v", err)
+	}
+	if strings.Count(buffer.String(), "mobile_apn {") != 4 {
+		t.Fatalf("shill round trip lost APNs:\n%s", buffer.String())
+	}
+}
+func TestCOSADocumentsTargetOperatorsAndMVNOs(t *testing.T) {
+	apnArray, err := apnxml.ImportFromXMLByte([]byte(`<apns version="8">
+	<apn carrier="Operator One" mcc="250" mnc="01" apn="internet" type="default,supl" protocol="IPV4V6" authtype="3" user="u" password="p" />
+	<apn carrier="Operator One" mcc="250" mnc="01" apn="virtual" type="default,ia" mvno_type="imsi" mvno_match_data="25001x5" />
+	<apn carrier="Operator One" mcc="250" mnc="01" apn="spnv" type="dun" mvno_type="spn" mvno_match_data="Virt &amp; Co" />
+	<apn carrier="Operator One" mcc="250" mnc="01" apn="fota" type="fota" />
+	<apn carrier="Operator Two" mcc="250" mnc="02" apn="net2" type="default" />
+</apns>`))
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+
+	flatArray, err := ReadCSV(strings.NewReader("plmn,carrier,apn,type,mvno_type,mvno_match_data\n25001,Virt,virt,default,spn,Virt\n25001,Op,internet,default,,\n"), CSVOptions{})
+	if err != nil {
+		t.Fatalf("ReadCSV returned error: %v", err)
+	}
+	if flatDocumentArray := COSADocuments(flatArray); len(flatDocumentArray) != 1 || len(flatDocumentArray[0].Profiles) != 2 || flatDocumentArray[0].Carrier != "Op" {
+		t.Fatalf("flat MVNO record of the operator type must get its own profile: %+v", flatDocumentArray)
+	}
+
+	documentArray := COSADocuments(apnArray)
+	if len(documentArray) != 2 {
+		t.Fatalf("expected two operator documents, got %d", len(documentArray))
+	}
+	document := documentArray[0]
+	if document.PLMN != "25001" || document.FileName() != "25001-operator-one.cosa.xml" {
+		t.Fatalf("unexpected document identity: %s %s", document.PLMN, document.FileName())
+	}
+	if len(document.Targets) != 3 || len(document.Profiles) != 3 {
+		t.Fatalf("expected three targets and profiles, got %d and %d", len(document.Targets), len(document.Profiles))
+	}
+
+	data, err := document.MarshalIndent()
+	if err != nil {
+		t.Fatalf("MarshalIndent returned error: %v", err)
+	}
+	exported := string(data)
+	for _, want := range []string{
+		`<CellularProvisioning xmlns="http://www.microsoft.com/networking/CellularProvisioning/v1">`,
+		`<Condition Name="Mnc" Value="01"></Condition>`,
+		`<Condition Name="ImsiRange" Start="250010500000000" End="250010599999999"></Condition>`,
+		`<Condition Name="Spn" Value="Virt &amp; Co"></Condition>`,
+		"<AccessString>internet</AccessString>\n\t\t\t<UserName>u</UserName>\n\t\t\t<Password>p</Password>\n\t\t\t<AuthProtocol>Auto</AuthProtocol>\n\t\t\t<Compression>Disable</Compression>\n\t\t\t<IPType>IPv4v6</IPType>",
+		`<ConnectivityProfile TargetRef="25001-imsi-25001x5">`,
+		"<Purpose>Tethering</Purpose>",
+	} {
+		if !strings.Contains(exported, want) {
+			t.Fatalf("cosa export does not contain %q:\n%s", want, exported)
+		}
+	}
+	if strings.Contains(exported, "fota") {
+		t.Fatalf("cosa export must skip records without a COSA purpose:\n%s", exported)
+	}
+
+	if err := apnxml.ExportToWriter(apnArray, &bytes.Buffer{}, FormatCOSA); err == nil {
+		t.Fatal("cosa export of several operators into one document must return error")
+	}
+}
+
+func TestOpenWrtNetworkSelectsDefaultAPN(t *testing.T) {
+	apnArray, err := apnxml.ImportFromXMLByte([]byte(`<apns version="8">
+	<apn carrier="Operator One" mcc="250" mnc="01" apn="mms" type="mms" />
+	<apn carrier="Operator One" mcc="250" mnc="01" apn="virtual" type="default" mvno_type="gid" mvno_match_data="A1" />
+	<apn carrier="Operator One" mcc="250" mnc="01" apn="internet" type="default,supl" protocol="IPV4V6" user="it's" password="secret" />
+</apns>`))
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+
+	network, err := OpenWrtNetwork(apnArray, OpenWrtOptions{})
+	if err != nil {
+		t.Fatalf("OpenWrtNetwork returned error: %v", err)
+	}
+	want := "# 25001 Operator One\n" +
+		"config interface 'wwan'\n" +
+		"\toption proto 'qmi'\n" +
+		"\toption device '/dev/cdc-wdm0'\n" +
+		"\toption apn 'internet'\n" +
+		"\toption auth 'both'\n" +
+		"\toption username 'it'\\''s'\n" +
+		"\toption password 'secret'\n" +
+		"\toption pdptype 'ipv4v6'\n"
+	if string(network) != want {
+		t.Fatalf("unexpected qmi stanza:\n%s", network)
+	}
+
+	network, err = OpenWrtNetwork(apnArray, OpenWrtOptions{Proto: OpenWrtModemManager, Interface: "lte"})
+	if err != nil {
+		t.Fatalf("OpenWrtNetwork returned error: %v", err)
+	}
+	for _, want := range []string{"config interface 'lte'\n", "\tlist allowedauth 'pap'\n\tlist allowedauth 'chap'\n", "\toption iptype 'ipv4v6'\n"} {
+		if !strings.Contains(string(network), want) {
+			t.Fatalf("modemmanager stanza does not contain %q:\n%s", want, network)
+		}
+	}
+	if strings.Contains(string(network), "device") {
+		t.Fatalf("modemmanager stanza must not guess a device:\n%s", network)
+	}
+
+	severalArray, err := apnxml.ImportFromXMLByte([]byte(`<apns version="8">
+	<apn carrier="Operator One" mcc="250" mnc="01" apn="internet" type="default" />
+	<apn carrier="Operator Two" mcc="250" mnc="02" apn="internet" type="default" />
+</apns>`))
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+	if _, err := OpenWrtNetwork(severalArray, OpenWrtOptions{}); err == nil {
+		t.Fatal("several PLMNs must return error")
+	}
+	if _, err := OpenWrtNetwork(apnArray, OpenWrtOptions{Interface: "wan-lte"}); err == nil {
+		t.Fatal("invalid interface name must return error")
+	}
+}
+
+func TestCSVRoundTripsEveryColumn(t *testing.T) {
+	apnArray, err := apnxml.ImportFromXMLByte([]byte(`<apns version="8">
+	<apn carrier="Operator, One" carrier_id="7" mcc="250" mnc="01" apn="internet" type="default,supl" protocol="IPV4V6" roaming_protocol="IP" network_type_bitmask="13|20" profile_id="1" carrier_enabled="true" user_visible="false" user_editable="true" authtype="3" user="user" password="pa&quot;ss" mtu="1400" server="*" proxy="10.0.0.1" port="8080" mmsc="http://mms" mmsproxy="10.0.0.2" mmsport="80" max_conns="8" max_conns_time="300" modem_cognitive="true" />
+	<apn carrier="Operator, One" carrier_id="7" mcc="250" mnc="01" apn="virtual" type="default" mvno_type="gid" mvno_match_data="A1" />
+	<apn carrier="Operator Two" mcc="310" mnc="260" apn="fast" type="default" />
+</apns>`))
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+
+	for _, format := range []apnxml.Format{FormatCSV, FormatTSV} {
+		var buffer bytes.Buffer
+		if err := apnxml.ExportToWriter(apnArray, &buffer, format); err != nil {
+			t.Fatalf("%s export returned error: %v", format, err)
+		}
+		roundTrip, err := apnxml.ImportFromReader(bytes.NewReader(buffer.Bytes()), format)
+		if err != nil {
+			t.Fatalf("%s import returned error: %v\n%s", format, err, buffer.String())
+		}
+
+		var again bytes.Buffer
+		if err := apnxml.ExportToWriter(roundTrip, &again, format); err != nil {
+			t.Fatalf("%s re-export returned error: %v", format, err)
+		}
+		if again.String() != buffer.String() {
+			t.Fatalf("%s round trip changed records:\n%s\nwant:\n%s", format, again.String(), buffer.String())
+		}
+		if !strings.Contains(buffer.String(), "modem_cognitive") || strings.Count(buffer.String(), "\n") != 4 {
+			t.Fatalf("%s export is missing columns or rows:\n%s", format, buffer.String())
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := WriteCSV(&buffer, apnArray, CSVOptions{Columns: []string{"plmn", "base.apn", "mvno_match_data"}}); err != nil {
+		t.Fatalf("WriteCSV returned error: %v", err)
+	}
+	if buffer.String() != "plmn,apn,mvno_match_data\n25001,virtual,A1\n25001,internet,\n310260,fast,\n" {
+		t.Fatalf("unexpected selected columns:\n%s", buffer.String())
+	}
+	if err := WriteCSV(&buffer, apnArray, CSVOptions{Columns: []string{"unknown"}}); err == nil {
+		t.Fatal("unknown column must return error")
+	}
+}
+
+func TestReadCSVReportsRowAndCellErrors(t *testing.T) {
+	apnArray, err := ReadCSV(strings.NewReader("plmn,apn,type,mtu\n2500x,a,default,1\n25001,b,bogus,abc\n25001,c\n,d,default,\n25002,ok,default|supl,\n"), CSVOptions{})
+
+	var errs CSVErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ReadCSV error = %v, want CSVErrors", err)
+	}
+	want := []struct {
+		row    int
+		column string
+	}{{2, "plmn"}, {3, "type"}, {3, "mtu"}, {4, ""}, {5, ""}}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got:\n%v", len(want), errs)
+	}
+	for index, item := range want {
+		if errs[index].Row != item.row || errs[index].Column != item.column {
+			t.Fatalf("error %d = %v, want row %d column %q", index, errs[index], item.row, item.column)
+		}
+	}
+
+	if apnArray.CountRecords() != 1 {
+		t.Fatalf("expected the valid row to be kept, got %d records", apnArray.CountRecords())
+	}
+	partial, err := apnxml.ImportFromReader(strings.NewReader("plmn,apn,type\n2500x,a,default\n25002,ok,default\n"), FormatCSV)
+	if !errors.As(err, &errs) || partial.CountRecords() != 1 {
+		t.Fatalf("csv format must return partial records with CSVErrors, got %d records: %v", partial.CountRecords(), err)
+	}
+	_ = apntool.From(apnArray).ForEach(func(record apnxml.Object) error {
+		if *record.Base.Type != apnxml.ObjectBaseTypeDefault|apnxml.ObjectBaseTypeSUPL {
+			t.Fatalf("pipe separated type was not parsed: %v", *record.Base.Type)
+		}
+		return nil
+	})
+
+	apnArray, err = ReadCSV(strings.NewReader("plmn,carrier,apn,type,mvno_type,mvno_match_data\n25001,Op,internet,default,,\n25001,Virt,virt.internet,default,spn,Virt\n25001,Op,internet2,default,,\n"), CSVOptions{})
+	if err != nil || apnArray.CountRecords() != 3 || len(apnArray) != 3 || !apnArray[0].HasGroup() {
+		t.Fatalf("rows sharing PLMN and type must all be kept in identity groups, got %d records: %v", apnArray.CountRecords(), err)
+	}
+	var apnNameArray []string
+	_ = apntool.From(apnArray).ForEach(func(record apnxml.Object) error {
+		apnNameArray = append(apnNameArray, *record.Base.Apn)
+		return nil
+	})
+	if strings.Join(apnNameArray, ",") != "internet,virt.internet,internet2" {
+		t.Fatalf("rows must keep sheet order, got %v", apnNameArray)
+	}
+
+	if _, err := ReadCSV(strings.NewReader("plmn,colour\n"), CSVOptions{}); err == nil || !strings.Contains(err.Error(), "row 1, column colour") {
+		t.Fatalf("unknown header error = %v", err)
+	}
+}
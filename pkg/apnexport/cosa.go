@@ -0,0 +1,285 @@
+package apnexport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+const FormatCOSA apnxml.Format = "cosa"
+
+const (
+	cosaNamespace      = "http://www.microsoft.com/networking/CellularProvisioning/v1"
+	cosaIMSILength     = 15
+	cosaIMSIRangeLimit = 100
+)
+
+var cosaPurposeArray = []struct {
+	apnType apnxml.ObjectBaseType
+	name    string
+}{
+	{apnxml.ObjectBaseTypeDefault, "Internet"},
+	{apnxml.ObjectBaseTypeIA, "LteAttach"},
+	{apnxml.ObjectBaseTypeMMS, "Mms"},
+	{apnxml.ObjectBaseTypeIMS, "Ims"},
+	{apnxml.ObjectBaseTypeSUPL, "Supl"},
+	{apnxml.ObjectBaseTypeDUN, "Tethering"},
+}
+
+type COSAProvisioning struct {
+	XMLName  xml.Name                  `xml:"CellularProvisioning"`
+	Xmlns    string                    `xml:"xmlns,attr"`
+	Targets  []COSATarget              `xml:"Targets>Target"`
+	Profiles []COSAConnectivityProfile `xml:"ConnectivityProfiles>ConnectivityProfile"`
+
+	PLMN    string `xml:"-"`
+	Carrier string `xml:"-"`
+}
+
+type COSATarget struct {
+	ID         string          `xml:"Id,attr"`
+	Conditions []COSACondition `xml:"Condition"`
+}
+
+type COSACondition struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:"Value,attr,omitempty"`
+	Start string `xml:"Start,attr,omitempty"`
+	End   string `xml:"End,attr,omitempty"`
+}
+
+type COSAConnectivityProfile struct {
+	TargetRef    string   `xml:"TargetRef,attr"`
+	Name         string   `xml:"Name"`
+	AccessString string   `xml:"AccessString"`
+	UserName     string   `xml:"UserName,omitempty"`
+	Password     string   `xml:"Password,omitempty"`
+	AuthProtocol string   `xml:"AuthProtocol"`
+	Compression  string   `xml:"Compression"`
+	IPType       string   `xml:"IPType"`
+	Purposes     []string `xml:"Purposes>Purpose"`
+}
+
+func init() {
+	if _, err := apnxml.RegisterFormat(string(FormatCOSA), []string{".cosa.xml"}, nil, encodeCOSA); err != nil {
+		panic(err)
+	}
+}
+
+func COSADocuments(apnArray apnxml.Array) []COSAProvisioning {
+	var (
+		documentArray []COSAProvisioning
+		documentMap   = map[string]int{}
+		operatorMap   = map[string]bool{}
+	)
+
+	_ = apntool.From(apnArray, apntool.WithTrustedInput()).ForEach(func(record apnxml.Object) error {
+		if record.ObjectRoot == nil || !record.ObjectRoot.Validate() {
+			return nil
+		}
+		profile, ok := newCOSAProfile(record)
+		if !ok {
+			return nil
+		}
+		target, ok := newCOSATarget(record)
+		if !ok {
+			return nil
+		}
+
+		groupID := record.GetID()
+		index, ok := documentMap[groupID]
+		if !ok {
+			index = len(documentArray)
+			documentMap[groupID] = index
+			documentArray = append(documentArray, COSAProvisioning{Xmlns: cosaNamespace, PLMN: record.GetPLMN(), Carrier: record.Carrier})
+		}
+
+		document := &documentArray[index]
+		if target.ID == document.PLMN && !operatorMap[groupID] {
+			operatorMap[groupID] = true
+			document.Carrier = record.Carrier
+		}
+		profile.TargetRef = target.ID
+		if !document.hasTarget(target.ID) {
+			document.Targets = append(document.Targets, target)
+		}
+		document.Profiles = append(document.Profiles, profile)
+		return nil
+	})
+
+	return documentArray
+}
+
+func (document COSAProvisioning) hasTarget(id string) bool {
+	for _, target := range document.Targets {
+		if target.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (document COSAProvisioning) FileName() string {
+	name := strings.Map(func(symbol rune) rune {
+		switch {
+		case symbol >= 'a' && symbol <= 'z', symbol >= '0' && symbol <= '9':
+			return symbol
+		case symbol >= 'A' && symbol <= 'Z':
+			return symbol + 'a' - 'A'
+		default:
+			return '-'
+		}
+	}, document.Carrier)
+	name = strings.Trim(name, "-")
+	if name == "" {
+		return document.PLMN + ".cosa.xml"
+	}
+
+	return document.PLMN + "-" + name + ".cosa.xml"
+}
+
+func (document COSAProvisioning) MarshalIndent() ([]byte, error) {
+	data, err := xml.MarshalIndent(document, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+func newCOSATarget(record apnxml.Object) (COSATarget, bool) {
+	target := COSATarget{
+		ID: record.GetPLMN(),
+		Conditions: []COSACondition{
+			{Name: "Mcc", Value: fmt.Sprintf("%03d", *record.Mcc)},
+			{Name: "Mnc", Value: fmt.Sprintf("%02d", *record.Mnc)},
+		},
+	}
+	if record.Mvno == nil || !record.Mvno.Validate() {
+		return target, true
+	}
+	if record.Mvno.Type == nil || record.Mvno.Data == nil {
+		return COSATarget{}, false
+	}
+
+	mvnoType, mvnoData := strings.ToLower(*record.Mvno.Type), *record.Mvno.Data
+	switch mvnoType {
+	case "spn":
+		target.Conditions = append(target.Conditions, COSACondition{Name: "Spn", Value: mvnoData})
+	case "imsi":
+		rangeArray := cosaIMSIRanges(mvnoData)
+		if len(rangeArray) == 0 {
+			return COSATarget{}, false
+		}
+		target.Conditions = append(target.Conditions, rangeArray...)
+	case "gid":
+		target.Conditions = append(target.Conditions, COSACondition{Name: "Gid1", Value: mvnoData})
+	case "iccid":
+		target.Conditions = append(target.Conditions, COSACondition{Name: "Iccid", Value: mvnoData})
+	default:
+		return COSATarget{}, false
+	}
+	target.ID += "-" + mvnoType + "-" + mvnoData
+
+	return target, true
+}
+
+func cosaIMSIRanges(pattern string) []COSACondition {
+	pattern = strings.ToLower(pattern)
+	if pattern == "" || len(pattern) > cosaIMSILength || strings.Trim(pattern, "0123456789x") != "" {
+		return nil
+	}
+
+	prefix := strings.TrimRight(pattern, "x")
+	prefixArray := []string{""}
+	for _, symbol := range prefix {
+		var nextArray []string
+		for _, value := range prefixArray {
+			if symbol != 'x' {
+				nextArray = append(nextArray, value+string(symbol))
+				continue
+			}
+			for digit := '0'; digit <= '9'; digit++ {
+				nextArray = append(nextArray, value+string(digit))
+			}
+		}
+		if len(nextArray) > cosaIMSIRangeLimit {
+			return nil
+		}
+		prefixArray = nextArray
+	}
+
+	conditionArray := make([]COSACondition, 0, len(prefixArray))
+	for _, value := range prefixArray {
+		conditionArray = append(conditionArray, COSACondition{
+			Name:  "ImsiRange",
+			Start: value + strings.Repeat("0", cosaIMSILength-len(value)),
+			End:   value + strings.Repeat("9", cosaIMSILength-len(value)),
+		})
+	}
+
+	return conditionArray
+}
+
+func newCOSAProfile(record apnxml.Object) (COSAConnectivityProfile, bool) {
+	if record.Base == nil || record.Base.Apn == nil || record.Base.Type == nil {
+		return COSAConnectivityProfile{}, false
+	}
+
+	profile := COSAConnectivityProfile{
+		Name:         strings.TrimSpace(record.Carrier + " " + *record.Base.Apn),
+		AccessString: *record.Base.Apn,
+		AuthProtocol: "None",
+		Compression:  "Disable",
+		IPType:       "Default",
+	}
+	for _, purpose := range cosaPurposeArray {
+		if *record.Base.Type&purpose.apnType != apnxml.ObjectBaseTypeNone {
+			profile.Purposes = append(profile.Purposes, purpose.name)
+		}
+	}
+	if len(profile.Purposes) == 0 {
+		return COSAConnectivityProfile{}, false
+	}
+
+	effective := record.Effective()
+	if effective.Auth.Username != nil {
+		profile.UserName = *effective.Auth.Username
+	}
+	if effective.Auth.Password != nil {
+		profile.Password = *effective.Auth.Password
+	}
+	switch *effective.Auth.Type {
+	case apnxml.ObjectAuthTypePAP:
+		profile.AuthProtocol = "Pap"
+	case apnxml.ObjectAuthTypeCHAP:
+		profile.AuthProtocol = "Chap"
+	case apnxml.ObjectAuthTypePAP | apnxml.ObjectAuthTypeCHAP:
+		profile.AuthProtocol = "Auto"
+	}
+	if record.Bearer != nil && record.Bearer.Type != nil {
+		switch *record.Bearer.Type {
+		case apnxml.ObjectBearerProtocolIP, apnxml.ObjectBearerProtocolIPv4:
+			profile.IPType = "IPv4"
+		case apnxml.ObjectBearerProtocolIPv6:
+			profile.IPType = "IPv6"
+		case apnxml.ObjectBearerProtocolIPv4v6:
+			profile.IPType = "IPv4v6"
+		}
+	}
+
+	return profile, true
+}
+
+func encodeCOSA(apnArray apnxml.Array, _ ...apnxml.EncodeOption) ([]byte, error) {
+	documentArray := COSADocuments(apnArray)
+	if len(documentArray) != 1 {
+		return nil, fmt.Errorf("cosa writes one operator per document, got %d operators", len(documentArray))
+	}
+
+	return documentArray[0].MarshalIndent()
+}
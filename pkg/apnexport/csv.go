@@ -0,0 +1,506 @@
+package apnexport
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+const (
+	FormatCSV apnxml.Format = "csv"
+	FormatTSV apnxml.Format = "tsv"
+)
+
+const csvPLMNColumn = "plmn"
+
+type CSVOptions struct {
+	Columns []string
+	Comma   rune
+}
+
+type CSVError struct {
+	Row    int
+	Column string
+	Value  string
+	Err    error
+}
+
+type CSVErrors []CSVError
+
+type sheetColumn struct {
+	name  string
+	path  string
+	value func(record apnxml.Object) string
+}
+
+var sheetColumnArray = []sheetColumn{
+	{csvPLMNColumn, "", func(record apnxml.Object) string {
+		if record.ObjectRoot == nil || !record.ObjectRoot.Validate() {
+			return ""
+		}
+		return record.GetPLMN()
+	}},
+	{"carrier", "root.carrier", func(record apnxml.Object) string {
+		if record.ObjectRoot == nil {
+			return ""
+		}
+		return record.Carrier
+	}},
+	{"carrier_id", "root.carrierid", func(record apnxml.Object) string {
+		if record.ObjectRoot == nil {
+			return ""
+		}
+		return sheetInt(record.CarrierID)
+	}},
+	{"type", "base.type", func(record apnxml.Object) string {
+		if record.Base == nil || record.Base.Type == nil {
+			return ""
+		}
+		return sheetText(*record.Base.Type)
+	}},
+	{"apn", "base.apn", func(record apnxml.Object) string {
+		if record.Base == nil {
+			return ""
+		}
+		return sheetString(record.Base.Apn)
+	}},
+	{"protocol", "bearer.type", func(record apnxml.Object) string {
+		if record.Bearer == nil || record.Bearer.Type == nil {
+			return ""
+		}
+		return sheetText(*record.Bearer.Type)
+	}},
+	{"roaming_protocol", "bearer.typeroaming", func(record apnxml.Object) string {
+		if record.Bearer == nil || record.Bearer.TypeRoaming == nil {
+			return ""
+		}
+		return sheetText(*record.Bearer.TypeRoaming)
+	}},
+	{"network", "other.networktypebitmask", func(record apnxml.Object) string {
+		if record.Other == nil || record.Other.NetworkTypeBitmask == nil {
+			return ""
+		}
+		return sheetText(*record.Other.NetworkTypeBitmask)
+	}},
+	{"profile_id", "base.profileid", func(record apnxml.Object) string {
+		if record.Base == nil {
+			return ""
+		}
+		return sheetInt(record.Base.ProfileID)
+	}},
+	{"enabled", "other.carrierenabled", func(record apnxml.Object) string {
+		if record.Other == nil {
+			return ""
+		}
+		return sheetBool(record.Other.CarrierEnabled)
+	}},
+	{"visible", "other.uservisible", func(record apnxml.Object) string {
+		if record.Other == nil {
+			return ""
+		}
+		return sheetBool(record.Other.UserVisible)
+	}},
+	{"editable", "other.usereditable", func(record apnxml.Object) string {
+		if record.Other == nil {
+			return ""
+		}
+		return sheetBool(record.Other.UserEditable)
+	}},
+	{"authtype", "auth.type", func(record apnxml.Object) string {
+		if record.Auth == nil || record.Auth.Type == nil {
+			return ""
+		}
+		return sheetText(*record.Auth.Type)
+	}},
+	{"user", "auth.username", func(record apnxml.Object) string {
+		if record.Auth == nil {
+			return ""
+		}
+		return sheetString(record.Auth.Username)
+	}},
+	{"password", "auth.password", func(record apnxml.Object) string {
+		if record.Auth == nil {
+			return ""
+		}
+		return sheetString(record.Auth.Password)
+	}},
+	{"mtu", "bearer.mtu", func(record apnxml.Object) string {
+		if record.Bearer == nil {
+			return ""
+		}
+		return sheetInt(record.Bearer.Mtu)
+	}},
+	{"server", "bearer.server", func(record apnxml.Object) string {
+		if record.Bearer == nil {
+			return ""
+		}
+		return sheetString(record.Bearer.Server)
+	}},
+	{"proxy", "proxy.server", func(record apnxml.Object) string {
+		if record.Proxy == nil {
+			return ""
+		}
+		return sheetString(record.Proxy.Server)
+	}},
+	{"port", "proxy.port", func(record apnxml.Object) string {
+		if record.Proxy == nil {
+			return ""
+		}
+		return sheetInt(record.Proxy.Port)
+	}},
+	{"mmsc", "mms.center", func(record apnxml.Object) string {
+		if record.Mms == nil {
+			return ""
+		}
+		return sheetString(record.Mms.Center)
+	}},
+	{"mmsproxy", "mms.server", func(record apnxml.Object) string {
+		if record.Mms == nil {
+			return ""
+		}
+		return sheetString(record.Mms.Server)
+	}},
+	{"mmsport", "mms.port", func(record apnxml.Object) string {
+		if record.Mms == nil {
+			return ""
+		}
+		return sheetInt(record.Mms.Port)
+	}},
+	{"mvno_type", "mvno.type", func(record apnxml.Object) string {
+		if record.Mvno == nil {
+			return ""
+		}
+		return sheetString(record.Mvno.Type)
+	}},
+	{"mvno_match_data", "mvno.data", func(record apnxml.Object) string {
+		if record.Mvno == nil {
+			return ""
+		}
+		return sheetString(record.Mvno.Data)
+	}},
+	{"max_conns", "limit.maxconn", func(record apnxml.Object) string {
+		if record.Limit == nil {
+			return ""
+		}
+		return sheetInt(record.Limit.MaxConn)
+	}},
+	{"max_conns_time", "limit.maxconntime", func(record apnxml.Object) string {
+		if record.Limit == nil {
+			return ""
+		}
+		return sheetInt(record.Limit.MaxConnTime)
+	}},
+	{"modem_cognitive", "other.modemcognitive", func(record apnxml.Object) string {
+		if record.Other == nil {
+			return ""
+		}
+		return sheetBool(record.Other.ModemCognitive)
+	}},
+	{"mcc", "root.mcc", func(record apnxml.Object) string {
+		if record.ObjectRoot == nil {
+			return ""
+		}
+		return sheetInt(record.Mcc)
+	}},
+	{"mnc", "root.mnc", func(record apnxml.Object) string {
+		if record.ObjectRoot == nil {
+			return ""
+		}
+		return sheetInt(record.Mnc)
+	}},
+}
+
+func init() {
+	if _, err := apnxml.RegisterFormat(string(FormatCSV), []string{".csv"}, decodeCSV, encodeCSV); err != nil {
+		panic(err)
+	}
+	if _, err := apnxml.RegisterFormat(string(FormatTSV), []string{".tsv"}, decodeTSV, encodeTSV); err != nil {
+		panic(err)
+	}
+}
+
+func CSVColumns() []string {
+	var nameArray []string
+	for _, column := range sheetColumnArray {
+		if column.name != "mcc" && column.name != "mnc" {
+			nameArray = append(nameArray, column.name)
+		}
+	}
+
+	return nameArray
+}
+
+func (err CSVError) Error() string {
+	if err.Column == "" {
+		return fmt.Sprintf("row %d: %v", err.Row, err.Err)
+	}
+
+	return fmt.Sprintf("row %d, column %s: %v", err.Row, err.Column, err.Err)
+}
+
+func (err CSVError) Unwrap() error {
+	return err.Err
+}
+
+func (errs CSVErrors) Error() string {
+	messageArray := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messageArray = append(messageArray, err.Error())
+	}
+
+	return strings.Join(messageArray, "\n")
+}
+
+func lookupSheetColumn(name string) (sheetColumn, bool) {
+	normalize := func(value string) string {
+		value = strings.ToLower(strings.TrimSpace(value))
+		return strings.NewReplacer("_", "", "-", "", " ", "").Replace(value)
+	}
+
+	name = normalize(name)
+	for _, column := range sheetColumnArray {
+		if name == normalize(column.name) || (column.path != "" && name == column.path) {
+			return column, true
+		}
+	}
+
+	return sheetColumn{}, false
+}
+
+func sheetColumns(nameArray []string) ([]sheetColumn, error) {
+	if len(nameArray) == 0 {
+		nameArray = CSVColumns()
+	}
+
+	columnArray := make([]sheetColumn, 0, len(nameArray))
+	for _, name := range nameArray {
+		column, ok := lookupSheetColumn(name)
+		if !ok {
+			return nil, fmt.Errorf("unsupported csv column: %s", name)
+		}
+		columnArray = append(columnArray, column)
+	}
+
+	return columnArray, nil
+}
+
+func newSheetReader(reader io.Reader, comma rune) *csv.Reader {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	if comma != 0 {
+		csvReader.Comma = comma
+	}
+
+	return csvReader
+}
+
+func WriteCSV(writer io.Writer, apnArray apnxml.Array, options CSVOptions) error {
+	columnArray, err := sheetColumns(options.Columns)
+	if err != nil {
+		return err
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	if options.Comma != 0 {
+		csvWriter.Comma = options.Comma
+	}
+
+	header := make([]string, 0, len(columnArray))
+	for _, column := range columnArray {
+		header = append(header, column.name)
+	}
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	err = apntool.From(apnArray, apntool.WithTrustedInput()).ForEach(func(record apnxml.Object) error {
+		row := make([]string, 0, len(columnArray))
+		for _, column := range columnArray {
+			row = append(row, column.value(record))
+		}
+		return csvWriter.Write(row)
+	})
+	if err != nil {
+		return err
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func ReadCSV(reader io.Reader, options CSVOptions) (apnxml.Array, error) {
+	csvReader := newSheetReader(reader, options.Comma)
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("csv has no header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		errs        CSVErrors
+		columnArray = make([]sheetColumn, len(header))
+	)
+	for index, name := range header {
+		column, ok := lookupSheetColumn(name)
+		if !ok {
+			errs = append(errs, CSVError{Row: 1, Column: name, Err: fmt.Errorf("unsupported column")})
+			continue
+		}
+		columnArray[index] = column
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var recordArray apnxml.Array
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := csvReader.FieldPos(0)
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		if len(row) != len(header) {
+			errs = append(errs, CSVError{Row: line, Err: fmt.Errorf("has %d cells, header has %d", len(row), len(header))})
+			continue
+		}
+
+		record, rowErrs := readSheetRecord(line, header, columnArray, row)
+		if len(rowErrs) > 0 {
+			errs = append(errs, rowErrs...)
+			continue
+		}
+		recordArray = append(recordArray, record)
+	}
+
+	apnArray := apntool.From(recordArray, apntool.WithTrustedInput()).GroupByIdentity().Data()
+	if len(errs) > 0 {
+		return apnArray, errs
+	}
+
+	return apnArray, nil
+}
+
+func readSheetRecord(line int, header []string, columnArray []sheetColumn, row []string) (apnxml.Object, CSVErrors) {
+	var (
+		errs   CSVErrors
+		record apnxml.Object
+	)
+
+	for index, value := range row {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		column := columnArray[index]
+		switch column.name {
+		case csvPLMNColumn:
+			if err := setSheetPLMN(&record, value); err != nil {
+				errs = append(errs, CSVError{Row: line, Column: header[index], Value: value, Err: err})
+			}
+			continue
+		case "type", "authtype", "network":
+			value = strings.ReplaceAll(value, "|", ",")
+		}
+
+		if err := apntool.SetObjectField(&record, column.path, value); err != nil {
+			if cause := errors.Unwrap(err); cause != nil {
+				err = cause
+			}
+			errs = append(errs, CSVError{Row: line, Column: header[index], Value: value, Err: err})
+		}
+	}
+	if len(errs) == 0 && (record.ObjectRoot == nil || !record.ObjectRoot.Validate()) {
+		errs = append(errs, CSVError{Row: line, Err: fmt.Errorf("record has no valid MCC and MNC")})
+	}
+
+	return record, errs
+}
+
+func setSheetPLMN(record *apnxml.Object, value string) error {
+	if (len(value) != 5 && len(value) != 6) || strings.Trim(value, "0123456789") != "" {
+		return fmt.Errorf("PLMN must be 5 or 6 digits")
+	}
+
+	mcc, _ := strconv.Atoi(value[:3])
+	mnc, _ := strconv.Atoi(value[3:])
+	root := apntool.EnsureRoot(record)
+	root.Mcc, root.Mnc = &mcc, &mnc
+
+	return nil
+}
+
+func sheetString(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+func sheetInt(value *int) string {
+	if value == nil {
+		return ""
+	}
+
+	return strconv.Itoa(*value)
+}
+
+func sheetBool(value *bool) string {
+	if value == nil {
+		return ""
+	}
+
+	return strconv.FormatBool(*value)
+}
+
+func sheetText(value encoding.TextMarshaler) string {
+	text, err := value.MarshalText()
+	if err != nil {
+		return ""
+	}
+
+	return string(text)
+}
+
+func encodeSheet(apnArray apnxml.Array, comma rune) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := WriteCSV(&buffer, apnArray, CSVOptions{Comma: comma}); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func encodeCSV(apnArray apnxml.Array, _ ...apnxml.EncodeOption) ([]byte, error) {
+	return encodeSheet(apnArray, ',')
+}
+
+func encodeTSV(apnArray apnxml.Array, _ ...apnxml.EncodeOption) ([]byte, error) {
+	return encodeSheet(apnArray, '\t')
+}
+
+func decodeCSV(data []byte, _ ...apnxml.DecodeOption) (apnxml.Array, error) {
+	return ReadCSV(bytes.NewReader(data), CSVOptions{Comma: ','})
+}
+
+func decodeTSV(data []byte, _ ...apnxml.DecodeOption) (apnxml.Array, error) {
+	return ReadCSV(bytes.NewReader(data), CSVOptions{Comma: '\t'})
+}
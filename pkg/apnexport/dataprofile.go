@@ -0,0 +1,177 @@
+package apnexport
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"strconv"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+const (
+	FormatDataProfile    apnxml.Format = "dataprofile"
+	FormatDataProfileCSV apnxml.Format = "dataprofile-csv"
+)
+
+type DataProfile struct {
+	PLMN                    string `json:"plmn"`
+	ProfileID               int    `json:"profileId"`
+	APN                     string `json:"apn"`
+	Protocol                string `json:"protocol"`
+	RoamingProtocol         string `json:"roamingProtocol"`
+	AuthType                int    `json:"authType"`
+	User                    string `json:"user"`
+	Password                string `json:"password"`
+	SupportedApnTypesBitmap int    `json:"supportedApnTypesBitmap"`
+	SupportedApnTypes       string `json:"supportedApnTypes"`
+	BearerBitmap            int    `json:"bearerBitmap"`
+	BearerTypes             string `json:"bearerTypes"`
+	NetworkTypeBitmask      int    `json:"networkTypeBitmask"`
+	MtuV4                   int    `json:"mtuV4"`
+	MtuV6                   int    `json:"mtuV6"`
+	Persistent              bool   `json:"persistent"`
+	Enabled                 bool   `json:"enabled"`
+	MaxConns                int    `json:"maxConns"`
+	MaxConnsTime            int    `json:"maxConnsTime"`
+}
+
+var dataProfileCSVHeader = []string{
+	"plmn", "profile_id", "apn", "protocol", "roaming_protocol", "auth_type", "user", "password",
+	"supported_apn_types_bitmap", "supported_apn_types", "bearer_bitmap", "bearer_types",
+	"network_type_bitmask", "mtu_v4", "mtu_v6", "persistent", "enabled", "max_conns", "max_conns_time",
+}
+
+func init() {
+	if _, err := apnxml.RegisterFormat(string(FormatDataProfile), []string{".dataprofile.json"}, nil, encodeDataProfileJSON); err != nil {
+		panic(err)
+	}
+	if _, err := apnxml.RegisterFormat(string(FormatDataProfileCSV), []string{".dataprofile.csv"}, nil, encodeDataProfileCSV); err != nil {
+		panic(err)
+	}
+}
+
+func NewDataProfile(record apnxml.Object) DataProfile {
+	effective := record.Effective()
+
+	profile := DataProfile{
+		ProfileID:       *effective.Base.ProfileID,
+		Protocol:        protocolName(*effective.Bearer.Type),
+		RoamingProtocol: protocolName(*effective.Bearer.TypeRoaming),
+		AuthType:        int(*effective.Auth.Type),
+		MtuV4:           dataProfileMtu(effective.Bearer.MtuV4, *effective.Bearer.Mtu),
+		MtuV6:           dataProfileMtu(effective.Bearer.MtuV6, *effective.Bearer.Mtu),
+		Persistent:      *effective.Other.ModemCognitive,
+		Enabled:         *effective.Other.CarrierEnabled,
+		MaxConns:        *effective.Limit.MaxConn,
+		MaxConnsTime:    *effective.Limit.MaxConnTime,
+	}
+	if effective.ObjectRoot != nil {
+		profile.PLMN = effective.GetPLMN()
+	}
+	if effective.Base.Apn != nil {
+		profile.APN = *effective.Base.Apn
+	}
+	if effective.Base.Type != nil {
+		profile.SupportedApnTypesBitmap = int(*effective.Base.Type)
+		profile.SupportedApnTypes = effective.Base.Type.String()
+	}
+	if effective.Auth.Username != nil {
+		profile.User = *effective.Auth.Username
+	}
+	if effective.Auth.Password != nil {
+		profile.Password = *effective.Auth.Password
+	}
+	if effective.Other.NetworkTypeBitmask != nil {
+		profile.BearerBitmap = bearerBitmap(*effective.Other.NetworkTypeBitmask)
+		profile.BearerTypes = effective.Other.NetworkTypeBitmask.String()
+		profile.NetworkTypeBitmask = int(*effective.Other.NetworkTypeBitmask)
+	}
+
+	return profile
+}
+
+func DataProfiles(apnArray apnxml.Array) []DataProfile {
+	profileArray := []DataProfile{}
+	_ = apntool.From(apnArray, apntool.WithTrustedInput()).ForEach(func(record apnxml.Object) error {
+		if record.ObjectRoot != nil && record.ObjectRoot.Validate() {
+			profileArray = append(profileArray, NewDataProfile(record))
+		}
+		return nil
+	})
+
+	return profileArray
+}
+
+func dataProfileMtu(mtu *int, fallback int) int {
+	if mtu == nil {
+		return fallback
+	}
+
+	return *mtu
+}
+
+func bearerBitmap(networkType apnxml.ObjectNetworkType) int {
+	var bitmap int
+	for _, radioTechnology := range networkType.RadioTechnologies() {
+		bitmap |= 1 << (radioTechnology - 1)
+	}
+
+	return bitmap
+}
+
+func protocolName(protocol apnxml.ObjectBearerProtocol) string {
+	xmlAttr, err := protocol.MarshalXMLAttr(xml.Name{})
+	if err != nil {
+		return protocol.String()
+	}
+
+	return xmlAttr.Value
+}
+
+func (profile DataProfile) csvRecord() []string {
+	return []string{
+		profile.PLMN,
+		strconv.Itoa(profile.ProfileID),
+		profile.APN,
+		profile.Protocol,
+		profile.RoamingProtocol,
+		strconv.Itoa(profile.AuthType),
+		profile.User,
+		profile.Password,
+		strconv.Itoa(profile.SupportedApnTypesBitmap),
+		profile.SupportedApnTypes,
+		strconv.Itoa(profile.BearerBitmap),
+		profile.BearerTypes,
+		strconv.Itoa(profile.NetworkTypeBitmask),
+		strconv.Itoa(profile.MtuV4),
+		strconv.Itoa(profile.MtuV6),
+		strconv.FormatBool(profile.Persistent),
+		strconv.FormatBool(profile.Enabled),
+		strconv.Itoa(profile.MaxConns),
+		strconv.Itoa(profile.MaxConnsTime),
+	}
+}
+
+func encodeDataProfileJSON(apnArray apnxml.Array, _ ...apnxml.EncodeOption) ([]byte, error) {
+	return json.MarshalIndent(DataProfiles(apnArray), "", "\t")
+}
+
+func encodeDataProfileCSV(apnArray apnxml.Array, _ ...apnxml.EncodeOption) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	csvWriter := csv.NewWriter(&buffer)
+	if err := csvWriter.Write(dataProfileCSVHeader); err != nil {
+		return nil, err
+	}
+	for _, profile := range DataProfiles(apnArray) {
+		if err := csvWriter.Write(profile.csvRecord()); err != nil {
+			return nil, err
+		}
+	}
+	csvWriter.Flush()
+
+	return buffer.Bytes(), csvWriter.Error()
+}
@@ -0,0 +1,175 @@
+package apnexport
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+const FormatOpenWrt apnxml.Format = "openwrt"
+
+const (
+	openWrtInterface = "wwan"
+	openWrtQMIDevice = "/dev/cdc-wdm0"
+)
+
+type OpenWrtProto string
+
+const (
+	OpenWrtQMI          OpenWrtProto = "qmi"
+	OpenWrtModemManager OpenWrtProto = "modemmanager"
+)
+
+type OpenWrtOptions struct {
+	Proto     OpenWrtProto
+	Interface string
+	Device    string
+}
+
+func init() {
+	if _, err := apnxml.RegisterFormat(string(FormatOpenWrt), []string{".uci"}, nil, encodeOpenWrt); err != nil {
+		panic(err)
+	}
+}
+
+func ParseOpenWrtProto(value string) (OpenWrtProto, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "qmi":
+		return OpenWrtQMI, nil
+	case "modemmanager", "mm":
+		return OpenWrtModemManager, nil
+	default:
+		return "", fmt.Errorf("unsupported openwrt proto: %s", value)
+	}
+}
+
+func OpenWrtRecord(apnArray apnxml.Array) (apnxml.Object, error) {
+	var (
+		plmn        string
+		plmnMap     = map[string]bool{}
+		recordArray []apnxml.Object
+	)
+
+	_ = apntool.From(apnArray, apntool.WithTrustedInput()).ForEach(func(record apnxml.Object) error {
+		if record.ObjectRoot == nil || !record.ObjectRoot.Validate() {
+			return nil
+		}
+		plmn = record.GetPLMN()
+		plmnMap[plmn] = true
+
+		effective := record.Effective()
+		if *effective.Base.Type&apnxml.ObjectBaseTypeDefault == apnxml.ObjectBaseTypeNone || effective.Base.Apn == nil || !*effective.Other.CarrierEnabled {
+			return nil
+		}
+		recordArray = append(recordArray, *effective)
+		return nil
+	})
+	if len(plmnMap) != 1 {
+		return apnxml.Object{}, fmt.Errorf("openwrt needs the records of one PLMN, got %d", len(plmnMap))
+	}
+
+	for _, record := range recordArray {
+		if record.Mvno == nil || !record.Mvno.Validate() {
+			return record, nil
+		}
+	}
+	if len(recordArray) == 0 {
+		return apnxml.Object{}, fmt.Errorf("no default APN for PLMN %s", plmn)
+	}
+
+	return recordArray[0], nil
+}
+
+func OpenWrtNetwork(apnArray apnxml.Array, options OpenWrtOptions) ([]byte, error) {
+	if options.Proto == "" {
+		options.Proto = OpenWrtQMI
+	}
+	if options.Interface == "" {
+		options.Interface = openWrtInterface
+	}
+	if options.Device == "" && options.Proto == OpenWrtQMI {
+		options.Device = openWrtQMIDevice
+	}
+	if strings.Trim(strings.ToLower(options.Interface), "abcdefghijklmnopqrstuvwxyz0123456789_") != "" {
+		return nil, fmt.Errorf("invalid openwrt interface name: %s", options.Interface)
+	}
+
+	record, err := OpenWrtRecord(apnArray)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	writeOption := func(name string, value string) {
+		if value != "" {
+			fmt.Fprintf(&buffer, "\toption %s %s\n", name, uciQuote(value))
+		}
+	}
+
+	fmt.Fprintf(&buffer, "# %s %s\n", record.GetPLMN(), record.Carrier)
+	fmt.Fprintf(&buffer, "config interface %s\n", uciQuote(options.Interface))
+	writeOption("proto", string(options.Proto))
+	writeOption("device", options.Device)
+	writeOption("apn", *record.Base.Apn)
+
+	authType := *record.Auth.Type
+	switch options.Proto {
+	case OpenWrtModemManager:
+		switch authType {
+		case apnxml.ObjectAuthTypeNone:
+			fmt.Fprintf(&buffer, "\tlist allowedauth %s\n", uciQuote("none"))
+		default:
+			if authType&apnxml.ObjectAuthTypePAP != apnxml.ObjectAuthTypeNone {
+				fmt.Fprintf(&buffer, "\tlist allowedauth %s\n", uciQuote("pap"))
+			}
+			if authType&apnxml.ObjectAuthTypeCHAP != apnxml.ObjectAuthTypeNone {
+				fmt.Fprintf(&buffer, "\tlist allowedauth %s\n", uciQuote("chap"))
+			}
+		}
+	default:
+		switch authType {
+		case apnxml.ObjectAuthTypePAP:
+			writeOption("auth", "pap")
+		case apnxml.ObjectAuthTypeCHAP:
+			writeOption("auth", "chap")
+		case apnxml.ObjectAuthTypePAP | apnxml.ObjectAuthTypeCHAP:
+			writeOption("auth", "both")
+		default:
+			writeOption("auth", "none")
+		}
+	}
+	if authType != apnxml.ObjectAuthTypeNone {
+		if record.Auth.Username != nil {
+			writeOption("username", *record.Auth.Username)
+		}
+		if record.Auth.Password != nil {
+			writeOption("password", *record.Auth.Password)
+		}
+	}
+
+	ipType, pdpType := "ipv4", "ip"
+	switch *record.Bearer.Type {
+	case apnxml.ObjectBearerProtocolIPv6:
+		ipType, pdpType = "ipv6", "ipv6"
+	case apnxml.ObjectBearerProtocolIPv4v6:
+		ipType, pdpType = "ipv4v6", "ipv4v6"
+	}
+	if options.Proto == OpenWrtModemManager {
+		writeOption("iptype", ipType)
+	} else {
+		writeOption("pdptype", pdpType)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func uciQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func encodeOpenWrt(apnArray apnxml.Array, _ ...apnxml.EncodeOption) ([]byte, error) {
+	return OpenWrtNetwork(apnArray, OpenWrtOptions{})
+}
@@ -0,0 +1,368 @@
+package apnexport
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+const FormatShill apnxml.Format = "shill"
+
+var shillTypeMap = map[string]apnxml.ObjectBaseType{
+	"DEFAULT": apnxml.ObjectBaseTypeDefault,
+	"IA":      apnxml.ObjectBaseTypeIA,
+	"DUN":     apnxml.ObjectBaseTypeDUN,
+}
+
+var shillTypeOrder = []string{"DEFAULT", "IA", "DUN"}
+
+var shillIPTypeMap = map[string]apnxml.ObjectBearerProtocol{
+	"IPV4":   apnxml.ObjectBearerProtocolIP,
+	"IPV6":   apnxml.ObjectBearerProtocolIPv6,
+	"IPV4V6": apnxml.ObjectBearerProtocolIPv4v6,
+}
+
+var shillAuthMap = map[string]apnxml.ObjectAuthType{
+	"PAP":  apnxml.ObjectAuthTypePAP,
+	"CHAP": apnxml.ObjectAuthTypeCHAP,
+}
+
+var shillFilterMap = map[string]string{
+	"GID1":          "gid",
+	"IMSI":          "imsi",
+	"ICCID":         "iccid",
+	"OPERATOR_NAME": "spn",
+}
+
+type shillOperator struct {
+	plmn    string
+	carrier string
+	apns    []apnxml.Object
+	mvnos   []*shillOperator
+	mvno    *apnxml.ObjectMVNO
+}
+
+func init() {
+	if _, err := apnxml.RegisterFormat(string(FormatShill), []string{".textproto"}, decodeShill, encodeShill); err != nil {
+		panic(err)
+	}
+}
+
+func decodeShill(data []byte, _ ...apnxml.DecodeOption) (apnxml.Array, error) {
+	root, err := parseTextproto(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var records apnxml.Array
+	for _, mno := range root.messages("mno") {
+		host := mno.message("data")
+		records = append(records, shillRecords(host, nil, nil)...)
+		for _, mvno := range mno.messages("mvno") {
+			records = append(records, shillRecords(mvno.message("data"), host, mvno.messages("mvno_filter"))...)
+		}
+	}
+	for _, mvno := range root.messages("mvno") {
+		records = append(records, shillRecords(mvno.message("data"), nil, mvno.messages("mvno_filter"))...)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("shill: no mobile_apn entries with mccmnc found")
+	}
+
+	return records, nil
+}
+
+func shillRecords(data *textprotoMessage, parent *textprotoMessage, filterArray []*textprotoMessage) apnxml.Array {
+	if data == nil {
+		return nil
+	}
+
+	mccmncArray := data.values("mccmnc")
+	carrier := data.message("localized_name").value("name")
+	if parent != nil {
+		if len(mccmncArray) == 0 {
+			mccmncArray = parent.values("mccmnc")
+		}
+		if carrier == "" {
+			carrier = parent.message("localized_name").value("name")
+		}
+	}
+
+	var mvno *apnxml.ObjectMVNO
+	for _, filter := range filterArray {
+		filterType, regex := filter.value("type"), filter.value("regex")
+		if filterType == "MCCMNC" && len(mccmncArray) == 0 && strings.Trim(regex, "0123456789") == "" {
+			mccmncArray = []string{regex}
+			continue
+		}
+		if mvnoType, ok := shillFilterMap[filterType]; ok && mvno == nil {
+			mvnoData := shillMatchData(mvnoType, regex)
+			mvno = &apnxml.ObjectMVNO{Type: &mvnoType, Data: &mvnoData}
+		}
+	}
+
+	var records apnxml.Array
+	for _, mccmnc := range mccmncArray {
+		if len(mccmnc) < 5 {
+			continue
+		}
+		mcc, mccErr := strconv.Atoi(mccmnc[:3])
+		mnc, mncErr := strconv.Atoi(mccmnc[3:])
+		if mccErr != nil || mncErr != nil {
+			continue
+		}
+
+		for _, mobileAPN := range data.messages("mobile_apn") {
+			record := shillRecord(mobileAPN)
+			record.ObjectRoot = &apnxml.ObjectRoot{Carrier: carrier, Mcc: &mcc, Mnc: &mnc}
+			if mvno != nil {
+				record.Mvno = mvno.Clone()
+			}
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+func shillRecord(mobileAPN *textprotoMessage) apnxml.Object {
+	apn := mobileAPN.value("apn")
+
+	var apnType apnxml.ObjectBaseType
+	for _, name := range mobileAPN.values("type") {
+		apnType |= shillTypeMap[strings.ToUpper(name)]
+	}
+	if mobileAPN.value("is_attach_apn") == "true" {
+		apnType |= apnxml.ObjectBaseTypeIA
+	}
+	if apnType == apnxml.ObjectBaseTypeNone {
+		apnType = apnxml.ObjectBaseTypeDefault
+	}
+
+	record := apnxml.Object{Base: &apnxml.ObjectBase{Apn: &apn, Type: &apnType}}
+
+	auth := &apnxml.ObjectAuth{}
+	if authType, ok := shillAuthMap[strings.ToUpper(mobileAPN.value("authentication"))]; ok {
+		auth.Type = &authType
+	}
+	if username := mobileAPN.values("username"); len(username) > 0 {
+		auth.Username = &username[0]
+	}
+	if password := mobileAPN.values("password"); len(password) > 0 {
+		auth.Password = &password[0]
+	}
+	if auth.Type != nil || auth.Username != nil || auth.Password != nil {
+		record.Auth = auth
+	}
+
+	if protocol, ok := shillIPTypeMap[strings.ToUpper(mobileAPN.value("ip_type"))]; ok {
+		record.Bearer = &apnxml.ObjectBearer{Type: &protocol}
+	}
+
+	return record
+}
+
+func shillMatchData(mvnoType string, regex string) string {
+	value := regex
+	if mvnoType != "spn" {
+		value = strings.TrimSuffix(value, ".*")
+	}
+	if mvnoType == "imsi" {
+		value = strings.NewReplacer(`[0-9]`, "x", `\d`, "x", ".", "x").Replace(value)
+	}
+
+	unquoted := strings.NewReplacer(`\`, "").Replace(value)
+	if regexp.QuoteMeta(unquoted) != value {
+		return regex
+	}
+
+	return unquoted
+}
+
+func shillFilterRegex(mvnoType string, mvnoData string) string {
+	regex := regexp.QuoteMeta(mvnoData)
+	if mvnoType == "imsi" {
+		regex = strings.NewReplacer("x", "[0-9]", "X", "[0-9]").Replace(regex)
+	}
+	if mvnoType != "spn" {
+		regex += ".*"
+	}
+
+	return regex
+}
+
+func shillFilterType(mvnoType string) (string, bool) {
+	for filterType, name := range shillFilterMap {
+		if strings.EqualFold(name, mvnoType) {
+			return filterType, true
+		}
+	}
+
+	return "", false
+}
+
+func shillOperators(apnArray apnxml.Array) []*shillOperator {
+	var (
+		operatorMap   = map[string]*shillOperator{}
+		operatorOrder []string
+	)
+
+	_ = apntool.From(apnArray, apntool.WithTrustedInput()).ForEach(func(record apnxml.Object) error {
+		if record.ObjectRoot == nil || !record.ObjectRoot.Validate() || record.Base == nil || record.Base.Type == nil {
+			return nil
+		}
+		if *record.Base.Type&(apnxml.ObjectBaseTypeDefault|apnxml.ObjectBaseTypeIA|apnxml.ObjectBaseTypeDUN) == apnxml.ObjectBaseTypeNone {
+			return nil
+		}
+
+		plmn := record.GetPLMN()
+		operator := operatorMap[plmn]
+		if operator == nil {
+			operator = &shillOperator{plmn: plmn}
+			operatorMap[plmn] = operator
+			operatorOrder = append(operatorOrder, plmn)
+		}
+
+		if record.Mvno == nil || !record.Mvno.Validate() {
+			if operator.carrier == "" {
+				operator.carrier = record.Carrier
+			}
+			operator.apns = append(operator.apns, record)
+			return nil
+		}
+
+		var mvnoType, mvnoData string
+		if record.Mvno.Type != nil {
+			mvnoType = *record.Mvno.Type
+		}
+		if record.Mvno.Data != nil {
+			mvnoData = *record.Mvno.Data
+		}
+		if _, ok := shillFilterType(mvnoType); !ok {
+			return nil
+		}
+
+		var mvno *shillOperator
+		for _, candidate := range operator.mvnos {
+			if strings.EqualFold(*candidate.mvno.Type, mvnoType) && *candidate.mvno.Data == mvnoData {
+				mvno = candidate
+				break
+			}
+		}
+		if mvno == nil {
+			mvno = &shillOperator{plmn: plmn, carrier: record.Carrier, mvno: &apnxml.ObjectMVNO{Type: &mvnoType, Data: &mvnoData}}
+			operator.mvnos = append(operator.mvnos, mvno)
+		}
+		mvno.apns = append(mvno.apns, record)
+		return nil
+	})
+
+	sort.Strings(operatorOrder)
+	operatorArray := make([]*shillOperator, 0, len(operatorOrder))
+	for _, plmn := range operatorOrder {
+		operator := operatorMap[plmn]
+		if operator.carrier == "" && len(operator.mvnos) > 0 {
+			operator.carrier = operator.mvnos[0].carrier
+		}
+		operatorArray = append(operatorArray, operator)
+	}
+
+	return operatorArray
+}
+
+func encodeShill(apnArray apnxml.Array, _ ...apnxml.EncodeOption) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	for _, operator := range shillOperators(apnArray) {
+		buffer.WriteString("mno {\n")
+		writeShillData(&buffer, "  ", "apnxml."+operator.plmn, operator.carrier, operator.plmn, operator.apns)
+		for _, mvno := range operator.mvnos {
+			filterType, _ := shillFilterType(*mvno.mvno.Type)
+			buffer.WriteString("  mvno {\n")
+			buffer.WriteString("    mvno_filter {\n")
+			fmt.Fprintf(&buffer, "      type: %s\n", filterType)
+			fmt.Fprintf(&buffer, "      regex: %s\n", textprotoQuote(shillFilterRegex(*mvno.mvno.Type, *mvno.mvno.Data)))
+			buffer.WriteString("    }\n")
+			writeShillData(&buffer, "    ", "apnxml."+operator.plmn+"."+*mvno.mvno.Type+"."+*mvno.mvno.Data, mvno.carrier, "", mvno.apns)
+			buffer.WriteString("  }\n")
+		}
+		buffer.WriteString("}\n")
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func shillTypeRank(record apnxml.Object) int {
+	for index, name := range shillTypeOrder {
+		if *record.Base.Type&shillTypeMap[name] != apnxml.ObjectBaseTypeNone {
+			return index
+		}
+	}
+
+	return len(shillTypeOrder)
+}
+
+func writeShillData(buffer *bytes.Buffer, indent string, uuid string, carrier string, mccmnc string, apns []apnxml.Object) {
+	sort.SliceStable(apns, func(i, j int) bool {
+		return shillTypeRank(apns[i]) < shillTypeRank(apns[j])
+	})
+
+	buffer.WriteString(indent + "data {\n")
+	fmt.Fprintf(buffer, "%s  uuid: %s\n", indent, textprotoQuote(uuid))
+	if carrier != "" {
+		fmt.Fprintf(buffer, "%s  localized_name {\n%s    name: %s\n%s  }\n", indent, indent, textprotoQuote(carrier), indent)
+	}
+	if mccmnc != "" {
+		fmt.Fprintf(buffer, "%s  mccmnc: %s\n", indent, textprotoQuote(mccmnc))
+	}
+	for _, record := range apns {
+		writeShillAPN(buffer, indent+"  ", record)
+	}
+	buffer.WriteString(indent + "}\n")
+}
+
+func writeShillAPN(buffer *bytes.Buffer, indent string, record apnxml.Object) {
+	buffer.WriteString(indent + "mobile_apn {\n")
+
+	apn := ""
+	if record.Base.Apn != nil {
+		apn = *record.Base.Apn
+	}
+	fmt.Fprintf(buffer, "%s  apn: %s\n", indent, textprotoQuote(apn))
+	for _, name := range shillTypeOrder {
+		if *record.Base.Type&shillTypeMap[name] != apnxml.ObjectBaseTypeNone {
+			fmt.Fprintf(buffer, "%s  type: %s\n", indent, name)
+		}
+	}
+	if record.Bearer != nil && record.Bearer.Type != nil {
+		for name, protocol := range shillIPTypeMap {
+			if *record.Bearer.Type == protocol {
+				fmt.Fprintf(buffer, "%s  ip_type: %s\n", indent, name)
+			}
+		}
+	}
+	if record.Auth != nil {
+		if record.Auth.Type != nil {
+			switch *record.Auth.Type {
+			case apnxml.ObjectAuthTypePAP:
+				fmt.Fprintf(buffer, "%s  authentication: PAP\n", indent)
+			case apnxml.ObjectAuthTypeCHAP:
+				fmt.Fprintf(buffer, "%s  authentication: CHAP\n", indent)
+			}
+		}
+		if record.Auth.Username != nil {
+			fmt.Fprintf(buffer, "%s  username: %s\n", indent, textprotoQuote(*record.Auth.Username))
+		}
+		if record.Auth.Password != nil {
+			fmt.Fprintf(buffer, "%s  password: %s\n", indent, textprotoQuote(*record.Auth.Password))
+		}
+	}
+
+	buffer.WriteString(indent + "}\n")
+}
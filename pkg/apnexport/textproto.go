@@ -0,0 +1,300 @@
+package apnexport
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type textprotoField struct {
+	name    string
+	value   string
+	message *textprotoMessage
+}
+
+type textprotoMessage struct {
+	fieldArray []textprotoField
+}
+
+type textprotoParser struct {
+	data   []byte
+	offset int
+}
+
+func parseTextproto(data []byte) (*textprotoMessage, error) {
+	parser := &textprotoParser{data: data}
+	return parser.parseMessage(0)
+}
+
+func (message *textprotoMessage) values(name string) []string {
+	var valueArray []string
+	if message == nil {
+		return valueArray
+	}
+	for _, field := range message.fieldArray {
+		if field.name == name && field.message == nil {
+			valueArray = append(valueArray, field.value)
+		}
+	}
+
+	return valueArray
+}
+
+func (message *textprotoMessage) value(name string) string {
+	if valueArray := message.values(name); len(valueArray) > 0 {
+		return valueArray[0]
+	}
+
+	return ""
+}
+
+func (message *textprotoMessage) messages(name string) []*textprotoMessage {
+	var messageArray []*textprotoMessage
+	if message == nil {
+		return messageArray
+	}
+	for _, field := range message.fieldArray {
+		if field.name == name && field.message != nil {
+			messageArray = append(messageArray, field.message)
+		}
+	}
+
+	return messageArray
+}
+
+func (message *textprotoMessage) message(name string) *textprotoMessage {
+	if messageArray := message.messages(name); len(messageArray) > 0 {
+		return messageArray[0]
+	}
+
+	return nil
+}
+
+func (parser *textprotoParser) parseMessage(end byte) (*textprotoMessage, error) {
+	message := &textprotoMessage{}
+	for {
+		parser.skipSpace()
+		if parser.offset >= len(parser.data) {
+			if end != 0 {
+				return nil, fmt.Errorf("textproto: missing %q at end of input", end)
+			}
+			return message, nil
+		}
+		if end != 0 && parser.data[parser.offset] == end {
+			parser.offset++
+			return message, nil
+		}
+
+		name := parser.readToken()
+		if name == "" {
+			return nil, fmt.Errorf("textproto: unexpected %q at offset %d", parser.data[parser.offset], parser.offset)
+		}
+
+		parser.skipSpace()
+		if parser.peek() == ':' {
+			parser.offset++
+			parser.skipSpace()
+		}
+
+		var fieldArray []textprotoField
+		if parser.peek() == '[' {
+			parser.offset++
+			for {
+				parser.skipSpace()
+				if parser.peek() == ']' {
+					parser.offset++
+					break
+				}
+				field, err := parser.parseField(name)
+				if err != nil {
+					return nil, err
+				}
+				fieldArray = append(fieldArray, field)
+				parser.skipSpace()
+				if parser.peek() == ',' {
+					parser.offset++
+				}
+			}
+		} else {
+			field, err := parser.parseField(name)
+			if err != nil {
+				return nil, err
+			}
+			fieldArray = append(fieldArray, field)
+		}
+		message.fieldArray = append(message.fieldArray, fieldArray...)
+
+		parser.skipSpace()
+		if symbol := parser.peek(); symbol == ',' || symbol == ';' {
+			parser.offset++
+		}
+	}
+}
+
+func (parser *textprotoParser) parseField(name string) (textprotoField, error) {
+	switch parser.peek() {
+	case '{', '<':
+		end := byte('}')
+		if parser.data[parser.offset] == '<' {
+			end = '>'
+		}
+		parser.offset++
+
+		message, err := parser.parseMessage(end)
+		if err != nil {
+			return textprotoField{}, err
+		}
+		return textprotoField{name: name, message: message}, nil
+	case '"', '\'':
+		var value strings.Builder
+		for symbol := parser.peek(); symbol == '"' || symbol == '\''; symbol = parser.peek() {
+			text, err := parser.readString()
+			if err != nil {
+				return textprotoField{}, err
+			}
+			value.WriteString(text)
+			parser.skipSpace()
+		}
+		return textprotoField{name: name, value: value.String()}, nil
+	default:
+		value := parser.readToken()
+		if value == "" {
+			return textprotoField{}, fmt.Errorf("textproto: field %s has no value at offset %d", name, parser.offset)
+		}
+		return textprotoField{name: name, value: value}, nil
+	}
+}
+
+func (parser *textprotoParser) peek() byte {
+	if parser.offset < len(parser.data) {
+		return parser.data[parser.offset]
+	}
+
+	return 0
+}
+
+func (parser *textprotoParser) skipSpace() {
+	for parser.offset < len(parser.data) {
+		switch symbol := parser.data[parser.offset]; {
+		case symbol == ' ' || symbol == '\t' || symbol == '\r' || symbol == '\n':
+			parser.offset++
+		case symbol == '#':
+			if index := bytes.IndexByte(parser.data[parser.offset:], '\n'); index >= 0 {
+				parser.offset += index + 1
+			} else {
+				parser.offset = len(parser.data)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (parser *textprotoParser) readToken() string {
+	start := parser.offset
+	for parser.offset < len(parser.data) {
+		symbol := parser.data[parser.offset]
+		if symbol == ' ' || symbol == '\t' || symbol == '\r' || symbol == '\n' || strings.IndexByte(":,;{}[]<>#\"'", symbol) >= 0 {
+			break
+		}
+		parser.offset++
+	}
+
+	return string(parser.data[start:parser.offset])
+}
+
+func (parser *textprotoParser) readString() (string, error) {
+	quote := parser.data[parser.offset]
+	start := parser.offset
+	parser.offset++
+
+	var value strings.Builder
+	for parser.offset < len(parser.data) {
+		symbol := parser.data[parser.offset]
+		switch {
+		case symbol == quote:
+			parser.offset++
+			return value.String(), nil
+		case symbol == '\n':
+			return "", fmt.Errorf("textproto: unterminated string at offset %d", start)
+		case symbol != '\\':
+			value.WriteByte(symbol)
+			parser.offset++
+			continue
+		}
+
+		parser.offset++
+		if parser.offset >= len(parser.data) {
+			break
+		}
+		escape := parser.data[parser.offset]
+		parser.offset++
+		switch escape {
+		case 'n':
+			value.WriteByte('\n')
+		case 't':
+			value.WriteByte('\t')
+		case 'r':
+			value.WriteByte('\r')
+		case 'a':
+			value.WriteByte('\a')
+		case 'b':
+			value.WriteByte('\b')
+		case 'f':
+			value.WriteByte('\f')
+		case 'v':
+			value.WriteByte('\v')
+		case 'x', 'X':
+			value.WriteByte(parser.readNumber(16, 2))
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			parser.offset--
+			value.WriteByte(parser.readNumber(8, 3))
+		default:
+			value.WriteByte(escape)
+		}
+	}
+
+	return "", fmt.Errorf("textproto: unterminated string at offset %d", start)
+}
+
+func (parser *textprotoParser) readNumber(base int, maxDigits int) byte {
+	start := parser.offset
+	for parser.offset < len(parser.data) && parser.offset-start < maxDigits {
+		if _, err := strconv.ParseUint(string(parser.data[parser.offset]), base, 8); err != nil {
+			break
+		}
+		parser.offset++
+	}
+
+	number, _ := strconv.ParseUint(string(parser.data[start:parser.offset]), base, 8)
+	return byte(number)
+}
+
+func textprotoQuote(value string) string {
+	var builder strings.Builder
+
+	builder.WriteByte('"')
+	for index := 0; index < len(value); index++ {
+		switch symbol := value[index]; symbol {
+		case '"', '\\':
+			builder.WriteByte('\\')
+			builder.WriteByte(symbol)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		default:
+			if symbol < 0x20 || symbol == 0x7f {
+				fmt.Fprintf(&builder, `\%03o`, symbol)
+			} else {
+				builder.WriteByte(symbol)
+			}
+		}
+	}
+	builder.WriteByte('"')
+
+	return builder.String()
+}
@@ -0,0 +1,223 @@
+package apnstore
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func baseTypePtr(value apnxml.ObjectBaseType) *apnxml.ObjectBaseType {
+	return &value
+}
+
+func testData() apnxml.Array {
+	return apnxml.Array{
+		{
+			ObjectRoot: &apnxml.ObjectRoot{Carrier: "Carrier A", CarrierID: intPtr(10), Mcc: intPtr(250), Mnc: intPtr(1)},
+			GroupMapByType: map[apnxml.ObjectBaseType]*apnxml.Object{
+				apnxml.ObjectBaseTypeDefault | apnxml.ObjectBaseTypeSUPL: {
+					Base: &apnxml.ObjectBase{Apn: stringPtr("internet"), Type: baseTypePtr(apnxml.ObjectBaseTypeDefault | apnxml.ObjectBaseTypeSUPL)},
+				},
+				apnxml.ObjectBaseTypeMMS: {
+					Base: &apnxml.ObjectBase{Apn: stringPtr("mms"), Type: baseTypePtr(apnxml.ObjectBaseTypeMMS)},
+				},
+			},
+		},
+		{
+			ObjectRoot: &apnxml.ObjectRoot{Carrier: "Carrier B", CarrierID: intPtr(20), Mcc: intPtr(250), Mnc: intPtr(2)},
+			Base:       &apnxml.ObjectBase{Apn: stringPtr("Internet"), Type: baseTypePtr(apnxml.ObjectBaseTypeDefault)},
+		},
+		{
+			ObjectRoot: &apnxml.ObjectRoot{Carrier: "Broken", Mcc: intPtr(999)},
+			Base:       &apnxml.ObjectBase{Apn: stringPtr("broken"), Type: baseTypePtr(apnxml.ObjectBaseTypeDefault)},
+		},
+	}
+}
+
+func TestStoreIndexes(t *testing.T) {
+	store := New(testData())
+	if store.Len() != 4 {
+		t.Fatalf("expected four records, got %d", store.Len())
+	}
+
+	if view := store.ByPLMN(250, 1); view.Len() != 2 {
+		t.Fatalf("expected two records for 25001, got %d", view.Len())
+	}
+	if view := store.ByMCC(250); view.Len() != 3 {
+		t.Fatalf("expected three records for MCC 250, got %d", view.Len())
+	}
+	if view := store.ByCarrierID(20); view.Len() != 1 || view.At(0).Carrier != "Carrier B" {
+		t.Fatal("unexpected carrier ID lookup result")
+	}
+	if view := store.ByAPN("INTERNET"); view.Len() != 2 {
+		t.Fatalf("APN lookup must be case-insensitive, got %d records", view.Len())
+	}
+	if view := store.ByType(apnxml.ObjectBaseTypeDefault); view.Len() != 3 {
+		t.Fatalf("expected three default records, got %d", view.Len())
+	}
+	if view := store.ByType(apnxml.ObjectBaseTypeDefault | apnxml.ObjectBaseTypeSUPL); view.Len() != 1 {
+		t.Fatalf("multi-bit type lookup must intersect bits, got %d", view.Len())
+	}
+
+	plmns := store.PLMNs()
+	if len(plmns) != 2 || plmns[0] != "25001" || plmns[1] != "25002" {
+		t.Fatalf("unexpected PLMNs: %#v", plmns)
+	}
+}
+
+func TestViewCombinatorsAndStats(t *testing.T) {
+	store := New(testData())
+
+	view := store.ByMCC(250).Intersect(store.ByType(apnxml.ObjectBaseTypeDefault))
+	if view.Len() != 2 {
+		t.Fatalf("expected two default records in MCC 250, got %d", view.Len())
+	}
+	if union := store.ByPLMN(250, 2).Union(store.ByAPN("mms")); union.Len() != 2 {
+		t.Fatalf("expected union of two records, got %d", union.Len())
+	}
+	if filtered := store.All().Filter(apntool.HasValidRoot); filtered.Len() != 3 {
+		t.Fatalf("expected three valid records, got %d", filtered.Len())
+	}
+
+	stats := store.All().Stats()
+	if stats.Groups != 3 || stats.Records != 4 || stats.Invalid != 1 || stats.ByPLMN["25001"] != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestViewDataIsDetachedFromStore(t *testing.T) {
+	source := testData()
+	store := New(source)
+
+	*source[1].Base.Apn = "changed"
+	if *store.ByCarrierID(20).At(0).Base.Apn != "Internet" {
+		t.Fatal("New must detach the store from its input")
+	}
+
+	data := store.ByCarrierID(20).Data()
+	*data[0].Base.Apn = "changed"
+	if *store.ByCarrierID(20).At(0).Base.Apn != "Internet" {
+		t.Fatal("Data must return a clone")
+	}
+}
+
+func TestViewRecordsAreDetachedFromStore(t *testing.T) {
+	store := New(testData())
+
+	record := store.ByCarrierID(20).At(0)
+	*record.Base.Apn = "changed"
+	_ = store.All().ForEach(func(record apnxml.Object) error {
+		if record.Base != nil && record.Base.Apn != nil {
+			*record.Base.Apn = "changed"
+		}
+		return nil
+	})
+	if first, ok := store.All().First(apntool.ByCarrierID(20)); ok {
+		*first.Base.Apn = "changed"
+	}
+
+	if *store.ByCarrierID(20).At(0).Base.Apn != "Internet" {
+		t.Fatal("At, ForEach and First must return clones")
+	}
+	if store.ByAPN("changed").Len() != 0 || store.ByAPN("internet").Len() == 0 {
+		t.Fatal("APN index must stay in sync with the stored records")
+	}
+}
+
+func benchmarkData() apnxml.Array {
+	var data apnxml.Array
+	for mnc := 0; mnc < 1000; mnc++ {
+		data = append(data, apnxml.Object{
+			ObjectRoot: &apnxml.ObjectRoot{Carrier: fmt.Sprintf("Carrier %d", mnc), CarrierID: intPtr(mnc), Mcc: intPtr(250 + mnc%10), Mnc: intPtr(mnc)},
+			GroupMapByType: map[apnxml.ObjectBaseType]*apnxml.Object{
+				apnxml.ObjectBaseTypeDefault: {Base: &apnxml.ObjectBase{Apn: stringPtr("internet"), Type: baseTypePtr(apnxml.ObjectBaseTypeDefault)}},
+				apnxml.ObjectBaseTypeMMS:     {Base: &apnxml.ObjectBase{Apn: stringPtr("mms"), Type: baseTypePtr(apnxml.ObjectBaseTypeMMS)}},
+				apnxml.ObjectBaseTypeIMS:     {Base: &apnxml.ObjectBase{Apn: stringPtr("ims"), Type: baseTypePtr(apnxml.ObjectBaseTypeIMS)}},
+			},
+		})
+	}
+
+	return data
+}
+
+func BenchmarkStoreByPLMN(b *testing.B) {
+	store := New(benchmarkData())
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		if store.ByPLMN(250, 500).Len() != 3 {
+			b.Fatal("unexpected lookup result")
+		}
+	}
+}
+
+func BenchmarkToolFilterByPLMN(b *testing.B) {
+	tool := apntool.From(benchmarkData())
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		if tool.Filter(apntool.ByPLMN(250, 500)).CountRecords() != 3 {
+			b.Fatal("unexpected filter result")
+		}
+	}
+}
+
+func BenchmarkStorePLMNs(b *testing.B) {
+	store := New(benchmarkData())
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		_ = store.PLMNs()
+	}
+}
+
+func BenchmarkToolPLMNs(b *testing.B) {
+	tool := apntool.From(benchmarkData())
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		_ = tool.PLMNs()
+	}
+}
+
+func BenchmarkStoreByType(b *testing.B) {
+	store := New(benchmarkData())
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		_ = store.ByType(apnxml.ObjectBaseTypeMMS).Len()
+	}
+}
+
+func BenchmarkToolFilterByType(b *testing.B) {
+	tool := apntool.From(benchmarkData())
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		_ = tool.Filter(apntool.ByType(apnxml.ObjectBaseTypeMMS)).CountRecords()
+	}
+}
+
+func BenchmarkViewAt(b *testing.B) {
+	view := New(benchmarkData()).ByType(apnxml.ObjectBaseTypeMMS)
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		for recordIndex := 0; recordIndex < view.Len(); recordIndex++ {
+			_ = view.At(recordIndex)
+		}
+	}
+}
+
+func BenchmarkViewRecord(b *testing.B) {
+	view := New(benchmarkData()).ByType(apnxml.ObjectBaseTypeMMS)
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		for recordIndex := 0; recordIndex < view.Len(); recordIndex++ {
+			_ = view.record(recordIndex)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package apnstore
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+type Store struct {
+	records []apnxml.Object
+	groups  []int
+	invalid []bool
+
+	byPLMN      map[string][]int
+	byMCC       map[int][]int
+	byCarrierID map[int][]int
+	byAPN       map[string][]int
+	byTypeBit   map[apnxml.ObjectBaseType][]int
+
+	plmns      []string
+	carrierIDs []int
+}
+
+func New(data apnxml.Array) *Store {
+	store := &Store{
+		byPLMN:      map[string][]int{},
+		byMCC:       map[int][]int{},
+		byCarrierID: map[int][]int{},
+		byAPN:       map[string][]int{},
+		byTypeBit:   map[apnxml.ObjectBaseType][]int{},
+	}
+
+	for groupIndex := range data {
+		group := &data[groupIndex]
+		for _, record := range group.Records() {
+			store.add(groupIndex, apntool.MaterializeRecord(group, record))
+		}
+	}
+
+	for plmn := range store.byPLMN {
+		store.plmns = append(store.plmns, plmn)
+	}
+	sort.Strings(store.plmns)
+
+	for carrierID := range store.byCarrierID {
+		store.carrierIDs = append(store.carrierIDs, carrierID)
+	}
+	sort.Ints(store.carrierIDs)
+
+	return store
+}
+
+func (store *Store) add(groupIndex int, record apnxml.Object) {
+	index := len(store.records)
+	store.records = append(store.records, record)
+	store.groups = append(store.groups, groupIndex)
+
+	valid := record.ObjectRoot != nil && record.ObjectRoot.Validate()
+	store.invalid = append(store.invalid, !valid)
+	if valid {
+		plmn := record.GetPLMN()
+		store.byPLMN[plmn] = append(store.byPLMN[plmn], index)
+		store.byMCC[*record.Mcc] = append(store.byMCC[*record.Mcc], index)
+	}
+
+	if record.ObjectRoot != nil && record.CarrierID != nil {
+		store.byCarrierID[*record.CarrierID] = append(store.byCarrierID[*record.CarrierID], index)
+	}
+
+	if record.Base != nil && record.Base.Apn != nil {
+		apn := apnKey(*record.Base.Apn)
+		store.byAPN[apn] = append(store.byAPN[apn], index)
+	}
+
+	if record.Base != nil && record.Base.Type != nil {
+		for _, bit := range typeBits(*record.Base.Type) {
+			store.byTypeBit[bit] = append(store.byTypeBit[bit], index)
+		}
+	}
+}
+
+func apnKey(apn string) string {
+	return strings.ToLower(strings.TrimSpace(apn))
+}
+
+func typeBits(apnType apnxml.ObjectBaseType) []apnxml.ObjectBaseType {
+	var bits []apnxml.ObjectBaseType
+	for bit := apnxml.ObjectBaseType(1); bit > 0 && bit <= apnType; bit <<= 1 {
+		if apnType&bit == bit {
+			bits = append(bits, bit)
+		}
+	}
+
+	return bits
+}
+
+func (store *Store) Len() int {
+	return len(store.records)
+}
+
+func (store *Store) All() View {
+	indexes := make([]int, len(store.records))
+	for index := range indexes {
+		indexes[index] = index
+	}
+
+	return View{store: store, indexes: indexes}
+}
+
+func (store *Store) ByPLMN(mcc int, mnc int) View {
+	return store.ByPLMNString(apnxml.ObjectRoot{Mcc: &mcc, Mnc: &mnc}.GetPLMN())
+}
+
+func (store *Store) ByPLMNString(plmn string) View {
+	return View{store: store, indexes: store.byPLMN[strings.TrimSpace(plmn)]}
+}
+
+func (store *Store) ByMCC(mcc int) View {
+	return View{store: store, indexes: store.byMCC[mcc]}
+}
+
+func (store *Store) ByCarrierID(carrierID int) View {
+	return View{store: store, indexes: store.byCarrierID[carrierID]}
+}
+
+func (store *Store) ByAPN(apn string) View {
+	return View{store: store, indexes: store.byAPN[apnKey(apn)]}
+}
+
+func (store *Store) ByType(apnType apnxml.ObjectBaseType) View {
+	bits := typeBits(apnType)
+	if len(bits) == 0 {
+		return View{store: store}
+	}
+
+	view := View{store: store, indexes: store.byTypeBit[bits[0]]}
+	for _, bit := range bits[1:] {
+		view = view.Intersect(View{store: store, indexes: store.byTypeBit[bit]})
+	}
+
+	return view
+}
+
+func (store *Store) PLMNs() []string {
+	return append([]string(nil), store.plmns...)
+}
+
+func (store *Store) CarrierIDs() []int {
+	return append([]int(nil), store.carrierIDs...)
+}
@@ -0,0 +1,159 @@
+package apnstore
+
+import (
+	"fmt"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apntool"
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+type View struct {
+	store   *Store
+	indexes []int
+}
+
+func (view View) Len() int {
+	return len(view.indexes)
+}
+
+func (view View) At(index int) apnxml.Object {
+	return *view.record(index).Clone()
+}
+
+func (view View) ForEach(visitor apntool.Visitor) error {
+	if visitor == nil {
+		return nil
+	}
+
+	for index, recordIndex := range view.indexes {
+		if err := visitor(*view.store.records[recordIndex].Clone()); err != nil {
+			return fmt.Errorf("for each record %d: %w", index, err)
+		}
+	}
+
+	return nil
+}
+
+func (view View) First(predicate apntool.Predicate) (apnxml.Object, bool) {
+	if predicate == nil {
+		predicate = apntool.All
+	}
+
+	for _, recordIndex := range view.indexes {
+		if predicate(view.store.records[recordIndex]) {
+			return *view.store.records[recordIndex].Clone(), true
+		}
+	}
+
+	return apnxml.Object{}, false
+}
+
+func (view View) Filter(predicate apntool.Predicate) View {
+	if predicate == nil {
+		return view
+	}
+
+	result := View{store: view.store}
+	for _, recordIndex := range view.indexes {
+		if predicate(view.store.records[recordIndex]) {
+			result.indexes = append(result.indexes, recordIndex)
+		}
+	}
+
+	return result
+}
+
+func (view View) Intersect(other View) View {
+	result := View{store: view.store}
+	if view.store != other.store {
+		return result
+	}
+
+	left, right := 0, 0
+	for left < len(view.indexes) && right < len(other.indexes) {
+		switch {
+		case view.indexes[left] == other.indexes[right]:
+			result.indexes = append(result.indexes, view.indexes[left])
+			left++
+			right++
+		case view.indexes[left] < other.indexes[right]:
+			left++
+		default:
+			right++
+		}
+	}
+
+	return result
+}
+
+func (view View) Union(other View) View {
+	if view.store == nil {
+		return other
+	}
+	if other.store == nil || view.store != other.store {
+		return view
+	}
+
+	result := View{store: view.store, indexes: make([]int, 0, len(view.indexes)+len(other.indexes))}
+	left, right := 0, 0
+	for left < len(view.indexes) || right < len(other.indexes) {
+		switch {
+		case right == len(other.indexes) || (left < len(view.indexes) && view.indexes[left] < other.indexes[right]):
+			result.indexes = append(result.indexes, view.indexes[left])
+			left++
+		case left == len(view.indexes) || other.indexes[right] < view.indexes[left]:
+			result.indexes = append(result.indexes, other.indexes[right])
+			right++
+		default:
+			result.indexes = append(result.indexes, view.indexes[left])
+			left++
+			right++
+		}
+	}
+
+	return result
+}
+
+func (view View) Data() apnxml.Array {
+	result := make(apnxml.Array, 0, len(view.indexes))
+	for _, recordIndex := range view.indexes {
+		result = append(result, *view.store.records[recordIndex].Clone())
+	}
+
+	return result
+}
+
+func (view View) record(index int) *apnxml.Object {
+	return &view.store.records[view.indexes[index]]
+}
+
+func (view View) Tool() apntool.Array {
+	return apntool.From(view.Data(), apntool.WithTrustedInput())
+}
+
+func (view View) Stats() apntool.Stats {
+	stats := apntool.Stats{
+		Records: len(view.indexes),
+		ByType:  map[apnxml.ObjectBaseType]int{},
+		ByPLMN:  map[string]int{},
+	}
+
+	groupSet := map[int]bool{}
+	for _, recordIndex := range view.indexes {
+		groupSet[view.store.groups[recordIndex]] = true
+
+		if view.store.invalid[recordIndex] {
+			stats.Invalid++
+			continue
+		}
+
+		record := view.store.records[recordIndex]
+		stats.ByPLMN[record.GetPLMN()]++
+		if record.Base != nil && record.Base.Type != nil {
+			stats.ByType[*record.Base.Type]++
+		}
+	}
+	stats.Groups = len(groupSet)
+
+	return stats
+}
@@ -0,0 +1,180 @@
+package apntool
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+type Anonymizer struct {
+	key []byte
+}
+
+func NewAnonymizer(key []byte) (Anonymizer, error) {
+	if len(key) == 0 {
+		return Anonymizer{}, fmt.Errorf("anonymizer key is empty")
+	}
+
+	return Anonymizer{key: append([]byte(nil), key...)}, nil
+}
+
+func (anonymizer Anonymizer) sum(kind string, value string) []byte {
+	mac := hmac.New(sha256.New, anonymizer.key)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+func (anonymizer Anonymizer) Token(kind string, value string) string {
+	return hex.EncodeToString(anonymizer.sum(kind, value)[:6])
+}
+
+func (anonymizer Anonymizer) Carrier(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return value
+	}
+	return "carrier-" + anonymizer.Token("carrier", value)
+}
+
+func (anonymizer Anonymizer) Host(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return value
+	}
+
+	sum := anonymizer.sum("host", strings.ToLower(value))
+	if ip := net.ParseIP(value); ip != nil {
+		if ip.To4() != nil {
+			return fmt.Sprintf("10.%d.%d.%d", sum[0], sum[1], sum[2])
+		}
+		return fmt.Sprintf("fd00::%x:%x", uint16(sum[0])<<8|uint16(sum[1]), uint16(sum[2])<<8|uint16(sum[3]))
+	}
+
+	return "h" + hex.EncodeToString(sum[:6]) + ".example.invalid"
+}
+
+func (anonymizer Anonymizer) URL(value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return value
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		return anonymizer.Host(value)
+	}
+
+	host := anonymizer.Host(parsed.Hostname())
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port := parsed.Port(); port != "" {
+		host += ":" + port
+	}
+
+	result := url.URL{Scheme: parsed.Scheme, Host: host}
+	if parsed.Path != "" && parsed.Path != "/" {
+		result.Path = "/" + anonymizer.Token("path", parsed.Path)
+	}
+	if parsed.RawQuery != "" {
+		result.RawQuery = anonymizer.Token("query", parsed.RawQuery)
+	}
+
+	return result.String()
+}
+
+func (anonymizer Anonymizer) replaceChars(kind string, value string, alphabet string, keep int) string {
+	sum := anonymizer.sum(kind, value)
+	result := []byte(value)
+	for index := keep; index < len(result); index++ {
+		if strings.IndexByte(alphabet, result[index]) < 0 {
+			continue
+		}
+		result[index] = alphabet[int(sum[index%len(sum)]+byte(index))%len(alphabet)]
+	}
+	return string(result)
+}
+
+func (anonymizer Anonymizer) MVNOData(mvnoType string, value string) string {
+	if strings.TrimSpace(value) == "" {
+		return value
+	}
+
+	switch strings.ToLower(strings.TrimSpace(mvnoType)) {
+	case "imsi":
+		return anonymizer.replaceChars("mvno-imsi", value, "0123456789", 5)
+	case "iccid":
+		return anonymizer.replaceChars("mvno-iccid", value, "0123456789", 2)
+	case "gid":
+		return anonymizer.replaceChars("mvno-gid", strings.ToLower(value), "0123456789abcdef", 0)
+	default:
+		return "mvno-" + anonymizer.Token("mvno", value)
+	}
+}
+
+func (anonymizer Anonymizer) Record(record *apnxml.Object) {
+	if record == nil {
+		return
+	}
+
+	if record.ObjectRoot != nil {
+		record.Carrier = anonymizer.Carrier(record.Carrier)
+	}
+	if record.Auth != nil {
+		anonymizeString(record.Auth.Username, func(value string) string {
+			return "user-" + anonymizer.Token("username", value)
+		})
+		anonymizeString(record.Auth.Password, func(value string) string {
+			return "pass-" + anonymizer.Token("password", value)
+		})
+	}
+	if record.Bearer != nil {
+		anonymizeString(record.Bearer.Server, anonymizer.Host)
+	}
+	if record.Proxy != nil {
+		anonymizeString(record.Proxy.Server, anonymizer.Host)
+	}
+	if record.Mms != nil {
+		anonymizeString(record.Mms.Center, anonymizer.URL)
+		anonymizeString(record.Mms.Server, anonymizer.Host)
+	}
+	if record.Mvno != nil && record.Mvno.Data != nil {
+		var mvnoType string
+		if record.Mvno.Type != nil {
+			mvnoType = *record.Mvno.Type
+		}
+		anonymizeString(record.Mvno.Data, func(value string) string {
+			return anonymizer.MVNOData(mvnoType, value)
+		})
+	}
+
+	record.Source = nil
+}
+
+func anonymizeString(value *string, replace func(string) string) {
+	if value != nil && *value != "" {
+		*value = replace(*value)
+	}
+}
+
+func (array Array) Anonymize(anonymizer Anonymizer) Array {
+	result := array.data.Clone()
+	for index := range result {
+		group := &result[index]
+		anonymizer.Record(group)
+		for _, record := range group.Records() {
+			if record != group {
+				anonymizer.Record(record)
+			}
+		}
+	}
+
+	return Array{data: result}
+}
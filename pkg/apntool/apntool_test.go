@@ -129,6 +129,21 @@ func TestArrayGroupByPLMNAndIdentityUseDifferentKeys(t *testing.T) {
 	if byIdentity.Len() != 2 {
 		t.Fatalf("expected two identity groups, got %d", byIdentity.Len())
 	}
+
+	root := func() *apnxml.ObjectRoot {
+		return &apnxml.ObjectRoot{Carrier: "Carrier A", CarrierID: intPtr(10), Mcc: intPtr(250), Mnc: intPtr(1)}
+	}
+	sameType := append(flat[:1:1],
+		apnxml.Object{ObjectRoot: root(), Base: &apnxml.ObjectBase{Apn: stringPtr("a2"), Type: &apnType}},
+		apnxml.Object{ObjectRoot: root(), Base: &apnxml.ObjectBase{Apn: stringPtr("virt"), Type: &apnType}, Mvno: &apnxml.ObjectMVNO{Type: stringPtr("spn"), Data: stringPtr("Virt")}},
+	)
+	grouped := From(sameType).GroupByIdentity()
+	if grouped.Len() != 3 || grouped.CountRecords() != 3 {
+		t.Fatalf("same-type and MVNO records must open their own groups, got len=%d count=%d", grouped.Len(), grouped.CountRecords())
+	}
+	if IdentityKey(sameType[2]) == IdentityKey(sameType[0]) || From(sameType).DedupeByIdentity().CountRecords() != 2 {
+		t.Fatal("MVNO records must not share the identity of the host record")
+	}
 }
 
 func TestSetPolicy(t *testing.T) {
@@ -419,6 +434,7 @@ func TestSetObjectFieldAndUpdateByFilter(t *testing.T) {
 	for _, expr := range []string{
 		"base.profileID=42",
 		"bearer.type=ipv4v6",
+		"mtu_v6=1280",
 		"other.carrierEnabled=false",
 	} {
 		if err := SetObjectFieldExpr(&patch, expr); err != nil {
@@ -448,6 +464,9 @@ func TestSetObjectFieldAndUpdateByFilter(t *testing.T) {
 	if record.Bearer == nil || record.Bearer.Type == nil || *record.Bearer.Type != apnxml.ObjectBearerProtocolIPv4v6 {
 		t.Fatal("protocol was not patched")
 	}
+	if record.Bearer.MtuV6 == nil || *record.Bearer.MtuV6 != 1280 || record.Bearer.MtuV4 != nil {
+		t.Fatal("mtu_v6 was not patched")
+	}
 	if record.Other == nil || record.Other.CarrierEnabled == nil || *record.Other.CarrierEnabled != false {
 		t.Fatal("carrier enabled was not patched")
 	}
@@ -473,3 +492,278 @@ func TestApplyUpdateReplacesExistingFields(t *testing.T) {
 		t.Fatal("apply update must replace existing fields with source shape")
 	}
 }
+
+func TestArrayResolvePrefersMatchingMVNO(t *testing.T) {
+	apnType := apnxml.ObjectBaseTypeDefault
+	root := func() *apnxml.ObjectRoot {
+		return &apnxml.ObjectRoot{Carrier: "Host", Mcc: intPtr(250), Mnc: intPtr(1)}
+	}
+	data := apnxml.Array{
+		{ObjectRoot: root(), Base: &apnxml.ObjectBase{Apn: stringPtr("host"), Type: &apnType}},
+		{ObjectRoot: root(), Base: &apnxml.ObjectBase{Apn: stringPtr("spn"), Type: &apnType}, Mvno: &apnxml.ObjectMVNO{Type: stringPtr("spn"), Data: stringPtr("Virtual")}},
+		{ObjectRoot: root(), Base: &apnxml.ObjectBase{Apn: stringPtr("imsi"), Type: &apnType}, Mvno: &apnxml.ObjectMVNO{Type: stringPtr("imsi"), Data: stringPtr("25001x9")}},
+	}
+
+	tests := []struct {
+		profile SIMProfile
+		want    string
+	}{
+		{SIMProfile{Mcc: 250, Mnc: 1}, "host"},
+		{SIMProfile{Mcc: 250, Mnc: 1, SPN: "virtual"}, "spn"},
+		{SIMProfile{Mcc: 250, Mnc: 1, IMSI: "250015912345678"}, "imsi"},
+	}
+	for _, test := range tests {
+		resolved := From(data).Flatten().Resolve(test.profile)
+		if resolved.CountRecords() != 1 {
+			t.Fatalf("expected one resolved record for %+v, got %d", test.profile, resolved.CountRecords())
+		}
+		if !resolved.Any(ByAPN(test.want)) {
+			t.Fatalf("expected APN %q for %+v", test.want, test.profile)
+		}
+	}
+}
+
+func TestRegisteredBaseTypeFiltersAndStats(t *testing.T) {
+	oemType, err := apnxml.ParseObjectBaseType("oem_tool_test")
+	if err != nil {
+		oemType, err = apnxml.RegisterObjectBaseType("oem_tool_test")
+	}
+	if err != nil {
+		t.Fatalf("RegisterObjectBaseType returned error: %v", err)
+	}
+
+	apnType := apnxml.ObjectBaseTypeDefault | oemType
+	tool := From(apnxml.Array{
+		{
+			ObjectRoot: &apnxml.ObjectRoot{Carrier: "A", Mcc: intPtr(250), Mnc: intPtr(1)},
+			Base:       &apnxml.ObjectBase{Apn: stringPtr("oem"), Type: &apnType},
+		},
+		{
+			ObjectRoot: &apnxml.ObjectRoot{Carrier: "A", Mcc: intPtr(250), Mnc: intPtr(1)},
+			Base:       &apnxml.ObjectBase{Apn: stringPtr("internet"), Type: baseTypePtr(apnxml.ObjectBaseTypeDefault)},
+		},
+	})
+
+	if count := tool.Count(ByType(oemType)); count != 1 {
+		t.Fatalf("expected one record with the registered type, got %d", count)
+	}
+	if stats := tool.Stats(); stats.ByType[apnType] != 1 || apnType.String() != "default|oem_tool_test" {
+		t.Fatalf("unexpected stats for registered type: %+v", stats.ByType)
+	}
+}
+
+func TestAnonymizeIsDeterministicAndKeepsStructure(t *testing.T) {
+	data := testData()
+	data[0].GroupMapByType[apnxml.ObjectBaseTypeDefault].Auth = &apnxml.ObjectAuth{Username: stringPtr("bob"), Password: stringPtr("secret")}
+	data[0].GroupMapByType[apnxml.ObjectBaseTypeDefault].Mms = &apnxml.ObjectMMS{Center: stringPtr("http://mms.operator.example:8002/servlets/mms")}
+	data[0].GroupMapByType[apnxml.ObjectBaseTypeMMS].Mvno = &apnxml.ObjectMVNO{Type: stringPtr("imsi"), Data: stringPtr("25001x12")}
+
+	if _, err := NewAnonymizer(nil); err == nil {
+		t.Fatal("empty anonymizer key must return error")
+	}
+	anonymizer, err := NewAnonymizer([]byte("report-key"))
+	if err != nil {
+		t.Fatalf("NewAnonymizer returned error: %v", err)
+	}
+
+	first := From(data).Anonymize(anonymizer)
+	second := From(data).Anonymize(anonymizer)
+	if first.Data().String() != second.Data().String() {
+		t.Fatal("anonymization must be deterministic for one key")
+	}
+
+	result := first.Data()
+	record := result[0].GroupMapByType[apnxml.ObjectBaseTypeDefault]
+	mvno := result[0].GroupMapByType[apnxml.ObjectBaseTypeMMS].Mvno
+	switch {
+	case result[0].Carrier == "Carrier A" || !strings.HasPrefix(result[0].Carrier, "carrier-"):
+		t.Fatalf("carrier name was not pseudonymized: %q", result[0].Carrier)
+	case *record.Auth.Username == "bob" || *record.Auth.Password == "secret":
+		t.Fatalf("credentials were not pseudonymized: %v", record.Auth)
+	case strings.Contains(*record.Mms.Center, "operator") || !strings.HasPrefix(*record.Mms.Center, "http://") || !strings.Contains(*record.Mms.Center, ":8002/"):
+		t.Fatalf("unexpected MMSC: %q", *record.Mms.Center)
+	case len(*mvno.Data) != len("25001x12") || !strings.HasPrefix(*mvno.Data, "25001x"):
+		t.Fatalf("IMSI pattern shape must be kept: %q", *mvno.Data)
+	case *record.Base.Apn != "internet":
+		t.Fatalf("APN names must be kept: %q", *record.Base.Apn)
+	}
+
+	stats, anonymizedStats := From(data).Stats(), first.Stats()
+	if stats.Records != anonymizedStats.Records || len(stats.ByPLMN) != len(anonymizedStats.ByPLMN) || stats.ByType[apnxml.ObjectBaseTypeMMS] != anonymizedStats.ByType[apnxml.ObjectBaseTypeMMS] {
+		t.Fatalf("structure changed: %+v %+v", stats, anonymizedStats)
+	}
+
+	otherAnonymizer, _ := NewAnonymizer([]byte("other-key"))
+	if From(data).Anonymize(otherAnonymizer).Data()[0].Carrier == result[0].Carrier {
+		t.Fatal("different keys must produce different pseudonyms")
+	}
+}
+
+func TestBuildMatrixReportsSourcesAndConflicts(t *testing.T) {
+	other := testData()
+	other[0].GroupMapByType[apnxml.ObjectBaseTypeDefault].Base.Apn = stringPtr("internet.other")
+	delete(other[0].GroupMapByType, apnxml.ObjectBaseTypeMMS)
+
+	matrix := BuildMatrix([]Source{
+		{Name: "device/a", Data: From(testData())},
+		{Name: "device/b", Data: From(other)},
+	})
+
+	if len(matrix.Sources) != 2 || len(matrix.Rows) != 4 {
+		t.Fatalf("unexpected matrix: %+v", matrix)
+	}
+	for _, row := range matrix.Rows {
+		if row.PLMN == "25102" && strings.Join(row.Sources, ",") != "device/a,device/b" {
+			t.Fatalf("shared row must list both sources: %+v", row)
+		}
+		if row.APN == "mms" && strings.Join(row.Sources, ",") != "device/a" {
+			t.Fatalf("mms row must list only device/a: %+v", row)
+		}
+	}
+
+	if len(matrix.Conflicts) != 1 {
+		t.Fatalf("unexpected conflicts: %+v", matrix.Conflicts)
+	}
+	conflict := matrix.Conflicts[0]
+	if conflict.PLMN != "25001" || conflict.Type != "default" || strings.Join(conflict.Fields, ",") != "base.apn" || len(conflict.Variants) != 2 {
+		t.Fatalf("unexpected conflict: %+v", conflict)
+	}
+	if conflict.Variants[0].APN != "internet" || conflict.Variants[1].APN != "internet.other" {
+		t.Fatalf("unexpected conflict variants: %+v", conflict.Variants)
+	}
+
+	defaultType := apnxml.ObjectBaseTypeDefault
+	record := func(carrierID *int, apn string) apnxml.Object {
+		return apnxml.Object{
+			ObjectRoot: &apnxml.ObjectRoot{Carrier: "Op", CarrierID: carrierID, Mcc: intPtr(250), Mnc: intPtr(1)},
+			Base:       &apnxml.ObjectBase{Apn: stringPtr(apn), Type: &defaultType},
+		}
+	}
+	matrix = BuildMatrix([]Source{
+		{Name: "dev1/apns-conf.xml", Data: From(apnxml.Array{record(nil, "internet"), record(intPtr(10), "internet.cid")})},
+		{Name: "dev2/apns-conf.xml", Data: From(apnxml.Array{record(intPtr(10), "internet.other")})},
+	})
+	if len(matrix.Conflicts) != 1 {
+		t.Fatalf("records of one file or of different carrier IDs must not conflict: %+v", matrix.Conflicts)
+	}
+	if conflict := matrix.Conflicts[0]; conflict.CarrierID != "10" || conflict.Variants[0].Sources[0] != "dev1/apns-conf.xml" || conflict.Variants[1].Sources[0] != "dev2/apns-conf.xml" {
+		t.Fatalf("unexpected carrier ID conflict: %+v", conflict)
+	}
+
+	mvno := record(nil, "internet.mvno")
+	mvno.Mvno = &apnxml.ObjectMVNO{Type: stringPtr("spn"), Data: stringPtr("Virtual")}
+	matrix = BuildMatrix([]Source{
+		{Name: "dev1/apns-conf.xml", Data: From(apnxml.Array{record(nil, "internet"), mvno})},
+		{Name: "dev2/apns-conf.xml", Data: From(apnxml.Array{record(nil, "internet"), record(nil, "internet.second")})},
+	})
+	if len(matrix.Conflicts) != 0 {
+		t.Fatalf("mvno records and same-type records of one file must follow identity grouping: %+v", matrix.Conflicts)
+	}
+	if len(matrix.Rows) != 3 {
+		t.Fatalf("expected a row for every grouped record: %+v", matrix.Rows)
+	}
+}
+
+func TestSimulateSelectsProfileAndExplainsRejections(t *testing.T) {
+	lteNR := apnxml.ObjectNetworkTypeLTE | apnxml.ObjectNetworkTypeNR
+	iwlan := apnxml.ObjectNetworkTypeIWLAN
+	ipv4v6, ip := apnxml.ObjectBearerProtocolIPv4v6, apnxml.ObjectBearerProtocolIP
+	disabled := false
+	defaultType := apnxml.ObjectBaseTypeDefault | apnxml.ObjectBaseTypeSUPL
+	data := apnxml.Array{
+		{
+			ObjectRoot: &apnxml.ObjectRoot{Carrier: "Carrier A", Mcc: intPtr(250), Mnc: intPtr(1)},
+			GroupMapByType: map[apnxml.ObjectBaseType]*apnxml.Object{
+				defaultType: {
+					Base:   &apnxml.ObjectBase{Apn: stringPtr("internet"), Type: &defaultType},
+					Bearer: &apnxml.ObjectBearer{Type: &ipv4v6, TypeRoaming: &ip},
+					Other:  &apnxml.ObjectOther{NetworkTypeBitmask: &lteNR},
+				},
+				apnxml.ObjectBaseTypeDefault: {
+					Base:  &apnxml.ObjectBase{Apn: stringPtr("wifi"), Type: baseTypePtr(apnxml.ObjectBaseTypeDefault)},
+					Other: &apnxml.ObjectOther{NetworkTypeBitmask: &iwlan},
+				},
+				apnxml.ObjectBaseTypeMMS: {
+					Base:  &apnxml.ObjectBase{Apn: stringPtr("mms"), Type: baseTypePtr(apnxml.ObjectBaseTypeMMS)},
+					Other: &apnxml.ObjectOther{CarrierEnabled: &disabled},
+				},
+			},
+		},
+	}
+	tool := From(data)
+
+	home := tool.Simulate(SimulationRequest{NetworkType: apnxml.ObjectNetworkTypeNR, Capability: apnxml.ObjectBaseTypeDefault})
+	if home.Selected == nil || *home.Selected.Record.Base.Apn != "internet" || home.Selected.Protocol != ipv4v6 {
+		t.Fatalf("unexpected home selection: %+v", home.Selected)
+	}
+	roaming := tool.Simulate(SimulationRequest{NetworkType: apnxml.ObjectNetworkTypeLTECA, Roaming: true, Capability: apnxml.ObjectBaseTypeDefault})
+	if roaming.Selected == nil || roaming.Selected.Protocol != ip {
+		t.Fatalf("roaming must use the roaming protocol: %+v", roaming.Selected)
+	}
+	wifi := tool.Simulate(SimulationRequest{NetworkType: apnxml.ObjectNetworkTypeIWLAN, Capability: apnxml.ObjectBaseTypeDefault})
+	if wifi.Selected == nil || *wifi.Selected.Record.Base.Apn != "wifi" {
+		t.Fatalf("unexpected IWLAN selection: %+v", wifi.Selected)
+	}
+
+	mms := tool.Simulate(SimulationRequest{NetworkType: apnxml.ObjectNetworkTypeLTE, Capability: apnxml.ObjectBaseTypeMMS})
+	if mms.Selected != nil || len(mms.Rejected) != 3 {
+		t.Fatalf("disabled MMS APN must not be selected: %+v", mms)
+	}
+	reasons := map[string]string{}
+	for _, candidate := range mms.Rejected {
+		reasons[*candidate.Record.Base.Apn] = strings.Join(candidate.Reasons, "; ")
+	}
+	if reasons["mms"] != "carrier_enabled is false" || reasons["wifi"] != "type default does not include mms; network_type_bitmask iwlan does not include lte" {
+		t.Fatalf("unexpected rejection reasons: %v", reasons)
+	}
+}
+
+func TestSimulateFollowsFileOrderAndMatchesMVNO(t *testing.T) {
+	defaultSUPL := apnxml.ObjectBaseTypeDefault | apnxml.ObjectBaseTypeSUPL
+	root := func() *apnxml.ObjectRoot {
+		return &apnxml.ObjectRoot{Carrier: "Carrier A", Mcc: intPtr(250), Mnc: intPtr(1)}
+	}
+	data := apnxml.Array{
+		{
+			ObjectRoot: root(),
+			Base:       &apnxml.ObjectBase{Apn: stringPtr("internet"), Type: baseTypePtr(defaultSUPL)},
+			Source:     &apnxml.ObjectSource{Index: 2},
+		},
+		{
+			ObjectRoot: root(),
+			Base:       &apnxml.ObjectBase{Apn: stringPtr("mvno"), Type: baseTypePtr(apnxml.ObjectBaseTypeDefault)},
+			Mvno:       &apnxml.ObjectMVNO{Type: stringPtr("spn"), Data: stringPtr("Virtual")},
+			Source:     &apnxml.ObjectSource{Index: 0},
+		},
+		{
+			ObjectRoot: root(),
+			Base:       &apnxml.ObjectBase{Apn: stringPtr("plain"), Type: baseTypePtr(apnxml.ObjectBaseTypeDefault)},
+			Source:     &apnxml.ObjectSource{Index: 1},
+		},
+	}
+	tool := From(data)
+	request := SimulationRequest{NetworkType: apnxml.ObjectNetworkTypeLTE, Capability: apnxml.ObjectBaseTypeDefault}
+
+	host := tool.Simulate(request)
+	if host.Selected == nil || *host.Selected.Record.Base.Apn != "plain" {
+		t.Fatalf("first non-MVNO record in file order must win: %+v", host.Selected)
+	}
+	if len(host.Rejected) != 2 || strings.Join(host.Rejected[0].Reasons, "; ") != `mvno spn "Virtual" needs a SIM profile` || strings.Join(host.Rejected[1].Reasons, "; ") != `listed after apn "plain"` {
+		t.Fatalf("unexpected rejections without SIM: %+v", host.Rejected)
+	}
+
+	request.SIM = &SIMProfile{SPN: "virtual"}
+	mvno := tool.Simulate(request)
+	if mvno.Selected == nil || *mvno.Selected.Record.Base.Apn != "mvno" {
+		t.Fatalf("matching MVNO record must win: %+v", mvno.Selected)
+	}
+	if len(mvno.Rejected) != 2 || strings.Join(mvno.Rejected[0].Reasons, "; ") != "an mvno record matches the SIM" {
+		t.Fatalf("unexpected rejections with matching SIM: %+v", mvno.Rejected)
+	}
+
+	request.SIM = &SIMProfile{SPN: "Other"}
+	other := tool.Simulate(request)
+	if other.Selected == nil || *other.Selected.Record.Base.Apn != "plain" || strings.Join(other.Rejected[0].Reasons, "; ") != `mvno spn "Virtual" does not match the SIM` {
+		t.Fatalf("unexpected selection with other SIM: %+v", other)
+	}
+}
@@ -1,13 +1,17 @@
 package apntool
 
-import "github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+import (
+	"fmt"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
 
 func (array Array) DedupeByPLMN() Array {
 	return Array{data: groupByPLMN(flatten(array.data))}
 }
 
 func (array Array) DedupeByIdentity() Array {
-	return Array{data: groupByIdentity(flatten(array.data))}
+	return Array{data: groupBy(flatten(array.data), IdentityKey, false)}
 }
 
 func (array Array) Merge(other apnxml.Array) Array {
@@ -34,19 +38,35 @@ func flatten(data apnxml.Array) apnxml.Array {
 	return result
 }
 
+func IdentityKey(record apnxml.Object) string {
+	identity := record.GetID()
+	if HasMVNO(record) {
+		identity += "MVNO:" + mvnoString(record.Mvno) + ";"
+	}
+
+	return identity
+}
+
+func groupIdentityKey(group apnxml.Object) string {
+	records := group.Records()
+	if len(records) == 0 {
+		return group.GetID()
+	}
+
+	return IdentityKey(MaterializeRecord(&group, records[0]))
+}
+
 func groupByPLMN(data apnxml.Array) apnxml.Array {
 	return groupBy(data, func(record apnxml.Object) string {
 		return record.GetPLMN()
-	})
+	}, false)
 }
 
 func groupByIdentity(data apnxml.Array) apnxml.Array {
-	return groupBy(data, func(record apnxml.Object) string {
-		return record.GetID()
-	})
+	return groupBy(data, IdentityKey, true)
 }
 
-func groupBy(data apnxml.Array, key func(apnxml.Object) string) apnxml.Array {
+func groupBy(data apnxml.Array, key func(apnxml.Object) string, isLossless bool) apnxml.Array {
 	groupMap := map[string]*apnxml.Object{}
 	var groupOrder []string
 
@@ -58,6 +78,13 @@ func groupBy(data apnxml.Array, key func(apnxml.Object) string) apnxml.Array {
 
 		groupID := key(record)
 		group := groupMap[groupID]
+		for overflow := 1; isLossless && group != nil && record.Base != nil && record.Base.Type != nil; overflow++ {
+			if _, exists := group.GroupMapByType[*record.Base.Type]; !exists {
+				break
+			}
+			groupID = fmt.Sprintf("%s#%d", key(record), overflow)
+			group = groupMap[groupID]
+		}
 		if group == nil {
 			group = &apnxml.Object{
 				ObjectRoot:     record.ObjectRoot.Clone(),
@@ -88,20 +115,20 @@ func groupBy(data apnxml.Array, key func(apnxml.Object) string) apnxml.Array {
 }
 
 func combine(left apnxml.Array, right apnxml.Array, mode apnxml.ObjectUpdateMode) apnxml.Array {
-	result := groupByIdentity(flatten(left))
+	result := groupBy(flatten(left), IdentityKey, false)
 	indexByID := make(map[string]int, len(result))
 	for index := range result {
-		indexByID[result[index].GetID()] = index
+		indexByID[groupIdentityKey(result[index])] = index
 	}
 
-	source := groupByIdentity(flatten(right))
+	source := groupBy(flatten(right), IdentityKey, false)
 	for index := range source {
 		sourceGroup := source[index].Clone()
 		if sourceGroup == nil {
 			continue
 		}
 
-		groupID := sourceGroup.GetID()
+		groupID := groupIdentityKey(*sourceGroup)
 		targetIndex, ok := indexByID[groupID]
 		if !ok {
 			indexByID[groupID] = len(result)
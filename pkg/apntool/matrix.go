@@ -0,0 +1,301 @@
+package apntool
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+type Source struct {
+	Name string
+	Data Array
+}
+
+type MatrixRow struct {
+	PLMN    string   `json:"plmn"`
+	Type    string   `json:"type"`
+	APN     string   `json:"apn"`
+	Sources []string `json:"sources"`
+}
+
+type MatrixVariant struct {
+	APN     string   `json:"apn"`
+	Sources []string `json:"sources"`
+}
+
+type MatrixConflict struct {
+	PLMN      string          `json:"plmn"`
+	CarrierID string          `json:"carrierID,omitempty"`
+	Type      string          `json:"type"`
+	MVNO      string          `json:"mvno,omitempty"`
+	Fields    []string        `json:"fields"`
+	Variants  []MatrixVariant `json:"variants"`
+}
+
+type Matrix struct {
+	Sources   []string         `json:"sources"`
+	Rows      []MatrixRow      `json:"rows"`
+	Conflicts []MatrixConflict `json:"conflicts"`
+}
+
+type matrixVariant struct {
+	apn     string
+	fields  map[string]string
+	sources map[string]bool
+}
+
+type matrixRowKey struct {
+	plmn    string
+	apnType string
+	apn     string
+}
+
+type matrixSlot struct {
+	conflict MatrixConflict
+	variants map[string]*matrixVariant
+}
+
+func BuildMatrix(sources []Source) Matrix {
+	var (
+		matrix    = Matrix{Rows: []MatrixRow{}, Conflicts: []MatrixConflict{}}
+		rowMap    = map[matrixRowKey]map[string]bool{}
+		slotMap   = map[string]*matrixSlot{}
+		slotOrder []string
+	)
+
+	for _, source := range sources {
+		matrix.Sources = append(matrix.Sources, source.Name)
+
+		recordArray := flatten(source.Data.data)
+		for recordIndex := range recordArray {
+			recordArray[recordIndex].Normalize()
+		}
+
+		slotCount := map[string]int{}
+		groupArray := groupByIdentity(recordArray)
+		for groupIndex := range groupArray {
+			group := &groupArray[groupIndex]
+			identity := groupIdentityKey(*group)
+
+			for _, groupRecord := range group.Records() {
+				record := MaterializeRecord(group, groupRecord)
+				apnType := matrixTypeString(record.Base)
+				apn := apnValueString(record.Base)
+
+				rowKey := matrixRowKey{plmn: record.GetPLMN(), apnType: apnType, apn: apn}
+				if rowMap[rowKey] == nil {
+					rowMap[rowKey] = map[string]bool{}
+				}
+				rowMap[rowKey][source.Name] = true
+
+				slotID := identity + "TYPE:" + apnType + ";"
+				overflow := slotCount[slotID]
+				slotCount[slotID]++
+				if overflow > 0 {
+					slotID = fmt.Sprintf("%s#%d", slotID, overflow)
+				}
+				slot := slotMap[slotID]
+				if slot == nil {
+					slot = &matrixSlot{
+						conflict: MatrixConflict{
+							PLMN:      record.GetPLMN(),
+							CarrierID: matrixCarrierIDString(record.ObjectRoot),
+							Type:      apnType,
+							MVNO:      matrixMVNOString(record),
+						},
+						variants: map[string]*matrixVariant{},
+					}
+					slotMap[slotID] = slot
+					slotOrder = append(slotOrder, slotID)
+				}
+
+				fields := recordFields(record)
+				fingerprint := fieldsFingerprint(fields)
+				variant := slot.variants[fingerprint]
+				if variant == nil {
+					variant = &matrixVariant{apn: apn, fields: fields, sources: map[string]bool{}}
+					slot.variants[fingerprint] = variant
+				}
+				variant.sources[source.Name] = true
+			}
+		}
+	}
+
+	for rowKey, sourceSet := range rowMap {
+		matrix.Rows = append(matrix.Rows, MatrixRow{PLMN: rowKey.plmn, Type: rowKey.apnType, APN: rowKey.apn, Sources: setKeys(sourceSet)})
+	}
+	sort.Slice(matrix.Rows, func(i, j int) bool {
+		left, right := matrix.Rows[i], matrix.Rows[j]
+		if left.PLMN != right.PLMN {
+			return left.PLMN < right.PLMN
+		}
+		if left.Type != right.Type {
+			return left.Type < right.Type
+		}
+		return left.APN < right.APN
+	})
+
+	for _, slotID := range slotOrder {
+		slot := slotMap[slotID]
+		if len(slot.variants) < 2 {
+			continue
+		}
+
+		conflict := slot.conflict
+		var (
+			fieldMaps []map[string]string
+			sourceSet = map[string]bool{}
+		)
+		for _, variant := range slot.variants {
+			conflict.Variants = append(conflict.Variants, MatrixVariant{APN: variant.apn, Sources: setKeys(variant.sources)})
+			fieldMaps = append(fieldMaps, variant.fields)
+			for name := range variant.sources {
+				sourceSet[name] = true
+			}
+		}
+		if len(sourceSet) < 2 {
+			continue
+		}
+		conflict.Fields = differentFields(fieldMaps)
+		sort.Slice(conflict.Variants, func(i, j int) bool {
+			return conflict.Variants[i].Sources[0] < conflict.Variants[j].Sources[0]
+		})
+
+		matrix.Conflicts = append(matrix.Conflicts, conflict)
+	}
+	sort.SliceStable(matrix.Conflicts, func(i, j int) bool {
+		left, right := matrix.Conflicts[i], matrix.Conflicts[j]
+		if left.PLMN != right.PLMN {
+			return left.PLMN < right.PLMN
+		}
+		if left.CarrierID != right.CarrierID {
+			return left.CarrierID < right.CarrierID
+		}
+		if left.Type != right.Type {
+			return left.Type < right.Type
+		}
+		return left.MVNO < right.MVNO
+	})
+
+	return matrix
+}
+
+func matrixTypeString(base *apnxml.ObjectBase) string {
+	if base == nil || base.Type == nil {
+		return ""
+	}
+	return base.Type.String()
+}
+
+func apnValueString(base *apnxml.ObjectBase) string {
+	if base == nil || base.Apn == nil {
+		return ""
+	}
+	return *base.Apn
+}
+
+func matrixCarrierIDString(root *apnxml.ObjectRoot) string {
+	if root == nil || root.CarrierID == nil {
+		return ""
+	}
+	return strconv.Itoa(*root.CarrierID)
+}
+
+func matrixMVNOString(record apnxml.Object) string {
+	if !HasMVNO(record) {
+		return ""
+	}
+
+	var mvnoType, mvnoData string
+	if record.Mvno.Type != nil {
+		mvnoType = *record.Mvno.Type
+	}
+	if record.Mvno.Data != nil {
+		mvnoData = *record.Mvno.Data
+	}
+	return mvnoType + ":" + mvnoData
+}
+
+func recordFields(record apnxml.Object) map[string]string {
+	fields := map[string]string{}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fields
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fields
+	}
+
+	flattenFields(fields, "", value)
+	return fields
+}
+
+func flattenFields(fields map[string]string, prefix string, value any) {
+	switch typed := value.(type) {
+	case map[string]any:
+		for key, item := range typed {
+			name := key
+			if prefix != "" {
+				name = prefix + "." + key
+			}
+			flattenFields(fields, name, item)
+		}
+	default:
+		data, _ := json.Marshal(typed)
+		fields[prefix] = string(data)
+	}
+}
+
+func fieldsFingerprint(fields map[string]string) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&builder, "%s=%s;", key, fields[key])
+	}
+	return builder.String()
+}
+
+func differentFields(fieldMaps []map[string]string) []string {
+	keySet := map[string]bool{}
+	for _, fields := range fieldMaps {
+		for key := range fields {
+			keySet[key] = true
+		}
+	}
+
+	var result []string
+	for key := range keySet {
+		for _, fields := range fieldMaps[1:] {
+			value, ok := fields[key]
+			firstValue, firstOk := fieldMaps[0][key]
+			if ok != firstOk || value != firstValue {
+				result = append(result, key)
+				break
+			}
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+func setKeys(set map[string]bool) []string {
+	result := make([]string, 0, len(set))
+	for key := range set {
+		result = append(result, key)
+	}
+
+	sort.Strings(result)
+	return result
+}
@@ -142,6 +142,18 @@ func SetObjectField(record *apnxml.Object, name string, value string) error {
 			return fieldError(name, err)
 		}
 		EnsureBearer(record).Mtu = &v
+	case "bearer.mtuv4", "mtuv4":
+		v, err := parseInt(value)
+		if err != nil {
+			return fieldError(name, err)
+		}
+		EnsureBearer(record).MtuV4 = &v
+	case "bearer.mtuv6", "mtuv6":
+		v, err := parseInt(value)
+		if err != nil {
+			return fieldError(name, err)
+		}
+		EnsureBearer(record).MtuV6 = &v
 	case "bearer.server":
 		EnsureBearer(record).Server = &value
 	case "proxy.server", "proxy":
@@ -0,0 +1,77 @@
+package apntool
+
+import (
+	"strings"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+type SIMProfile struct {
+	Mcc       int    `json:"mcc"`
+	Mnc       int    `json:"mnc"`
+	CarrierID *int   `json:"carrierID,omitempty"`
+	SPN       string `json:"spn,omitempty"`
+	IMSI      string `json:"imsi,omitempty"`
+	GID1      string `json:"gid1,omitempty"`
+	ICCID     string `json:"iccid,omitempty"`
+}
+
+func (profile SIMProfile) MatchMVNO(mvno *apnxml.ObjectMVNO) bool {
+	if mvno == nil || mvno.Type == nil || mvno.Data == nil {
+		return false
+	}
+
+	data := strings.TrimSpace(*mvno.Data)
+	if data == "" {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(*mvno.Type)) {
+	case "spn":
+		return strings.EqualFold(strings.TrimSpace(profile.SPN), data)
+	case "imsi":
+		return matchIMSIPattern(profile.IMSI, data)
+	case "gid":
+		return profile.GID1 != "" && strings.HasPrefix(strings.ToLower(profile.GID1), strings.ToLower(data))
+	case "iccid":
+		return profile.ICCID != "" && strings.HasPrefix(profile.ICCID, data)
+	default:
+		return false
+	}
+}
+
+func matchIMSIPattern(imsi string, pattern string) bool {
+	if len(imsi) < len(pattern) {
+		return false
+	}
+
+	for index := 0; index < len(pattern); index++ {
+		if pattern[index] == 'x' || pattern[index] == 'X' {
+			continue
+		}
+		if pattern[index] != imsi[index] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func BySIM(profile SIMProfile) Predicate {
+	return func(record apnxml.Object) bool {
+		return profile.MatchMVNO(record.Mvno)
+	}
+}
+
+func (array Array) Resolve(profile SIMProfile) Array {
+	candidates := array.Filter(ByPLMN(profile.Mcc, profile.Mnc))
+	if profile.CarrierID != nil && candidates.Any(ByCarrierID(*profile.CarrierID)) {
+		candidates = candidates.Filter(ByCarrierID(*profile.CarrierID))
+	}
+
+	if candidates.Any(BySIM(profile)) {
+		return candidates.Filter(BySIM(profile))
+	}
+
+	return candidates.Exclude(HasMVNO)
+}
@@ -0,0 +1,150 @@
+package apntool
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/GlshchnkLx/go-aospapn/pkg/apnxml"
+)
+
+type SimulationRequest struct {
+	NetworkType apnxml.ObjectNetworkType `json:"networkType"`
+	Roaming     bool                     `json:"roaming"`
+	Capability  apnxml.ObjectBaseType    `json:"capability"`
+	SIM         *SIMProfile              `json:"sim,omitempty"`
+}
+
+type SimulationCandidate struct {
+	Record   apnxml.Object               `json:"record"`
+	Protocol apnxml.ObjectBearerProtocol `json:"protocol"`
+	Reasons  []string                    `json:"reasons,omitempty"`
+}
+
+type SimulationResult struct {
+	Request  SimulationRequest     `json:"request"`
+	Selected *SimulationCandidate  `json:"selected,omitempty"`
+	Rejected []SimulationCandidate `json:"rejected"`
+}
+
+func (array Array) Simulate(request SimulationRequest) SimulationResult {
+	result := SimulationResult{Request: request, Rejected: []SimulationCandidate{}}
+
+	records := simulationRecords(array.data)
+	isMVNOMatched := false
+	for _, record := range records {
+		if request.SIM != nil && request.SIM.MatchMVNO(record.Mvno) {
+			isMVNOMatched = true
+			break
+		}
+	}
+
+	for _, record := range records {
+		candidate := SimulationCandidate{
+			Record:   record,
+			Protocol: simulationProtocol(record.Bearer, request.Roaming),
+			Reasons:  append(simulationMVNOReasons(record, request.SIM, isMVNOMatched), simulationReasons(record, request)...),
+		}
+		if len(candidate.Reasons) == 0 && result.Selected != nil {
+			candidate.Reasons = []string{fmt.Sprintf("listed after apn %q", apnValueString(result.Selected.Record.Base))}
+		}
+
+		if len(candidate.Reasons) == 0 {
+			result.Selected = &candidate
+			continue
+		}
+		result.Rejected = append(result.Rejected, candidate)
+	}
+
+	return result
+}
+
+func simulationRecords(data apnxml.Array) apnxml.Array {
+	var records apnxml.Array
+	for _, record := range flatten(data) {
+		if record.ObjectRoot != nil && record.ObjectRoot.Validate() {
+			records = append(records, record)
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		sourceI, sourceJ := records[i].Source, records[j].Source
+		if sourceI == nil || sourceJ == nil {
+			return sourceI != nil
+		}
+
+		return sourceI.Index < sourceJ.Index
+	})
+
+	return records
+}
+
+func simulationMVNOReasons(record apnxml.Object, profile *SIMProfile, isMVNOMatched bool) []string {
+	switch {
+	case !HasMVNO(record):
+		if isMVNOMatched {
+			return []string{"an mvno record matches the SIM"}
+		}
+	case profile == nil:
+		return []string{fmt.Sprintf("mvno %s needs a SIM profile", mvnoString(record.Mvno))}
+	case !profile.MatchMVNO(record.Mvno):
+		return []string{fmt.Sprintf("mvno %s does not match the SIM", mvnoString(record.Mvno))}
+	}
+
+	return nil
+}
+
+func mvnoString(mvno *apnxml.ObjectMVNO) string {
+	var mvnoType, mvnoData string
+	if mvno.Type != nil {
+		mvnoType = *mvno.Type
+	}
+	if mvno.Data != nil {
+		mvnoData = *mvno.Data
+	}
+
+	return fmt.Sprintf("%s %q", mvnoType, mvnoData)
+}
+
+func simulationReasons(record apnxml.Object, request SimulationRequest) []string {
+	var reasons []string
+
+	var apnType apnxml.ObjectBaseType
+	if record.Base != nil && record.Base.Type != nil {
+		apnType = *record.Base.Type
+	}
+	if apnType&request.Capability != request.Capability {
+		reasons = append(reasons, fmt.Sprintf("type %s does not include %s", apnType, request.Capability))
+	}
+
+	if record.Other != nil && record.Other.CarrierEnabled != nil && !*record.Other.CarrierEnabled {
+		reasons = append(reasons, "carrier_enabled is false")
+	}
+
+	if record.Other != nil && record.Other.NetworkTypeBitmask != nil && *record.Other.NetworkTypeBitmask != apnxml.ObjectNetworkTypeNone {
+		networkType := request.NetworkType
+		if networkType == apnxml.ObjectNetworkTypeLTECA {
+			networkType = apnxml.ObjectNetworkTypeLTE
+		}
+		if *record.Other.NetworkTypeBitmask&networkType == apnxml.ObjectNetworkTypeNone {
+			reasons = append(reasons, fmt.Sprintf("network_type_bitmask %s does not include %s", *record.Other.NetworkTypeBitmask, request.NetworkType))
+		}
+	}
+
+	return reasons
+}
+
+func simulationProtocol(bearer *apnxml.ObjectBearer, roaming bool) apnxml.ObjectBearerProtocol {
+	if bearer == nil {
+		return apnxml.ObjectBearerProtocolIP
+	}
+
+	protocol := bearer.Type
+	if roaming {
+		protocol = bearer.TypeRoaming
+	}
+	if protocol == nil || *protocol == apnxml.ObjectBearerProtocolNone {
+		return apnxml.ObjectBearerProtocolIP
+	}
+
+	return *protocol
+}
@@ -3,14 +3,10 @@ package apnxml
 import (
 	"context"
 	"encoding/base64"
-	"encoding/json"
-	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"path/filepath"
-	"strings"
 )
 
 //--------------------------------------------------------------------------------//
@@ -24,72 +20,62 @@ const (
 	FormatXML  Format = "xml"
 )
 
-func FormatFromFilename(filename string) (Format, error) {
-	switch strings.ToLower(filepath.Ext(filename)) {
-	case ".json":
-		return FormatJSON, nil
-	case ".xml":
-		return FormatXML, nil
-	default:
-		return "", fmt.Errorf("unsupported apn file extension: %s", filepath.Ext(filename))
-	}
-}
-
 //--------------------------------------------------------------------------------//
 // Decode
 //--------------------------------------------------------------------------------//
 
-func decode(data []byte, format Format) (Array, error) {
-	var records Array
-
-	switch format {
-	case FormatJSON:
-		if err := json.Unmarshal(data, &records); err != nil {
-			return nil, err
-		}
-	case FormatXML:
-		if err := xml.Unmarshal(data, &records); err != nil {
+func decode(data []byte, format Format, optionList []DecodeOption) (Array, error) {
+	if format == FormatAuto {
+		var err error
+		data, format, err = unwrapContent(data)
+		if err != nil {
 			return nil, err
 		}
-	default:
+	}
+
+	codec, ok := lookupFormat(format)
+	if !ok {
 		return nil, fmt.Errorf("unsupported apn format: %s", format)
 	}
+	if codec.decoder == nil {
+		return nil, fmt.Errorf("apn format %s does not support import", codec.name)
+	}
 
-	return records, nil
+	return codec.decoder(data, optionList...)
 }
 
-func ImportFromJSONByte(jsonByte []byte) (apnArray Array, err error) {
-	return decode(jsonByte, FormatJSON)
+func ImportFromJSONByte(jsonByte []byte, optionList ...DecodeOption) (apnArray Array, err error) {
+	return decode(jsonByte, FormatJSON, optionList)
 }
 
-func ImportFromXMLByte(xmlByte []byte) (apnArray Array, err error) {
-	return decode(xmlByte, FormatXML)
+func ImportFromXMLByte(xmlByte []byte, optionList ...DecodeOption) (apnArray Array, err error) {
+	return decode(xmlByte, FormatXML, optionList)
 }
 
-func ImportFromReader(reader io.Reader, format Format) (Array, error) {
+func ImportFromReader(reader io.Reader, format Format, optionList ...DecodeOption) (Array, error) {
 	data, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, fmt.Errorf("read apn data: %w", err)
 	}
 
-	return decode(data, format)
+	return decode(data, format, optionList)
 }
 
-func ImportFromFile(filename string) (apnArray Array, err error) {
+func ImportFromFile(filename string, optionList ...DecodeOption) (apnArray Array, err error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	format, err := FormatFromFilename(filename)
+	data, format, err := readArchiveFile(filename, data, "")
 	if err != nil {
 		return nil, err
 	}
 
-	return decode(data, format)
+	return decode(data, format, optionList)
 }
 
-func ImportFromURL(ctx context.Context, httpClient *http.Client, url string, format Format, isBase64 bool) (apnArray Array, err error) {
+func ImportFromURL(ctx context.Context, httpClient *http.Client, url string, format Format, isBase64 bool, optionList ...DecodeOption) (apnArray Array, err error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -124,7 +110,7 @@ func ImportFromURL(ctx context.Context, httpClient *http.Client, url string, for
 		}
 	}
 
-	return decode(data, format)
+	return decode(data, format, optionList)
 }
 
 func ImportFromSimpleURL(url string, isBase64 bool) (apnArray Array, err error) {
@@ -135,27 +121,36 @@ func ImportFromSimpleURL(url string, isBase64 bool) (apnArray Array, err error)
 // Encode
 //--------------------------------------------------------------------------------//
 
-func encode(records Array, format Format) ([]byte, error) {
-	switch format {
-	case FormatJSON:
-		return json.MarshalIndent(records, "", "\t")
-	case FormatXML:
-		return xml.MarshalIndent(records, "", "\t")
-	default:
+func encode(records Array, format Format, optionList []EncodeOption) ([]byte, error) {
+	codec, ok := lookupFormat(format)
+	if !ok {
 		return nil, fmt.Errorf("unsupported apn format: %s", format)
 	}
+	if codec.encoder == nil {
+		return nil, fmt.Errorf("apn format %s does not support export", codec.name)
+	}
+
+	if options := newEncodeOptions(optionList); options.secretResolver != nil {
+		var err error
+		records, err = records.ResolveSecrets(options.secretResolver)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codec.encoder(records, optionList...)
 }
 
-func ExportToJSONByte(apnArray Array) (jsonByte []byte, err error) {
-	return encode(apnArray, FormatJSON)
+func ExportToJSONByte(apnArray Array, optionList ...EncodeOption) (jsonByte []byte, err error) {
+	return encode(apnArray, FormatJSON, optionList)
 }
 
-func ExportToXMLByte(apnArray Array) (xmlByte []byte, err error) {
-	return encode(apnArray, FormatXML)
+func ExportToXMLByte(apnArray Array, optionList ...EncodeOption) (xmlByte []byte, err error) {
+	return encode(apnArray, FormatXML, optionList)
 }
 
-func ExportToWriter(apnArray Array, writer io.Writer, format Format) error {
-	data, err := encode(apnArray, format)
+func ExportToWriter(apnArray Array, writer io.Writer, format Format, optionList ...EncodeOption) error {
+	data, err := encode(apnArray, format, optionList)
 	if err != nil {
 		return err
 	}
@@ -167,18 +162,18 @@ func ExportToWriter(apnArray Array, writer io.Writer, format Format) error {
 	return nil
 }
 
-func ExportToFile(apnArray Array, filename string) error {
+func ExportToFile(apnArray Array, filename string, optionList ...EncodeOption) error {
 	format, err := FormatFromFilename(filename)
 	if err != nil {
 		return err
 	}
 
-	data, err := encode(apnArray, format)
+	data, err := encode(apnArray, format, optionList)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(filename, data, 0644)
+	return WriteArchiveFile(filename, data)
 }
 
 //--------------------------------------------------------------------------------//
@@ -1,11 +1,18 @@
 package apnxml
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -27,6 +34,557 @@ func stringPtr(value string) *string {
 // Test
 //--------------------------------------------------------------------------------//
 
+func TestCanonicalizeSortsAttributesAndKeepsDocumentOrder(t *testing.T) {
+	input := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<apns version="8">
+  <!-- Operator B -->
+  <apn type="default" apn="b" mnc="02" mcc="250" carrier="B" vendor_flag="1"></apn>
+  <apn mcc="250" carrier="A" mnc="01"
+       apn="a" />
+</apns>
+`)
+
+	formatted, err := Canonicalize(input, CanonicalLayoutElement)
+	if err != nil {
+		t.Fatalf("Canonicalize returned error: %v", err)
+	}
+
+	want := `<?xml version="1.0" encoding="utf-8"?>
+<apns version="8">
+	<!-- Operator B -->
+	<apn carrier="B" mcc="250" mnc="02" apn="b" type="default" vendor_flag="1" />
+	<apn carrier="A" mcc="250" mnc="01" apn="a" />
+</apns>
+`
+	if string(formatted) != want {
+		t.Fatalf("unexpected canonical output:\n%s", formatted)
+	}
+
+	canonical, err := IsCanonical(formatted, CanonicalLayoutElement)
+	if err != nil || !canonical {
+		t.Fatalf("canonical output must be stable: %v %v", canonical, err)
+	}
+	canonical, err = IsCanonical(formatted, CanonicalLayoutAttribute)
+	if err != nil || canonical {
+		t.Fatalf("element layout must not be canonical for attribute layout: %v %v", canonical, err)
+	}
+}
+
+func TestObjectMarshalXMLUsesCanonicalAttributeOrder(t *testing.T) {
+	data, err := ExportToXMLByte(Array{{
+		ObjectRoot: &ObjectRoot{Carrier: "A", Mcc: intPtr(250), Mnc: intPtr(1)},
+		Base:       &ObjectBase{Apn: stringPtr("internet")},
+		Mms:        &ObjectMMS{Center: stringPtr("http://mmsc")},
+		Auth:       &ObjectAuth{Username: stringPtr("user")},
+	}})
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+
+	if !strings.Contains(string(data), `<apn carrier="A" mcc="250" mnc="1" apn="internet" user="user" mmsc="http://mmsc" type="default">`) {
+		t.Fatalf("unexpected attribute order:\n%s", data)
+	}
+}
+
+func TestPreserveDocumentKeepsCommentsOrderAndSpelling(t *testing.T) {
+	input := `<?xml version="1.0" encoding="utf-8"?>
+<!-- License header -->
+<apns version="8">
+	<!-- Operator Z -->
+	<apn carrier="Z" mcc="310" mnc="260" apn="z" vendor_flag="1" />
+	<!-- Operator A -->
+	<apn carrier="A Internet" mcc="250" mnc="01" apn="internet" type="supl,default" authtype="1" user="u" />
+	<apn carrier="A MMS" mcc="250" mnc="01" apn="mms" type="mms" mmsc="http://mms" />
+	<!-- trailing -->
+</apns>
+`
+
+	apnArray, err := ImportFromXMLByte([]byte(input), WithPreserveDocument())
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+	if len(apnArray) != 3 || apnArray[0].Carrier != "Z" || apnArray[0].HasGroup() {
+		t.Fatalf("document mode must keep flat records in file order: %v", apnArray)
+	}
+	if comments := apnArray[1].Source.Comments; len(comments) != 1 || comments[0] != " Operator A " {
+		t.Fatalf("comment must attach to the following record: %#v", comments)
+	}
+
+	unchanged, err := ExportToXMLByte(apnArray.Clone())
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+	if string(unchanged) != input {
+		t.Fatalf("unchanged document must round-trip:\n%s", unchanged)
+	}
+
+	*apnArray[1].Base.Apn = "internet2"
+	apnArray[0], apnArray[2] = apnArray[2], apnArray[0]
+	patched, err := ExportToXMLByte(apnArray)
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+
+	want := strings.Replace(input, `apn="internet"`, `apn="internet2"`, 1)
+	if string(patched) != want {
+		t.Fatalf("patch must change exactly one line:\n%s", patched)
+	}
+}
+
+func TestPreserveDocumentKeepsLayoutByteForByte(t *testing.T) {
+	input := "<?xml version='1.0' encoding='utf-8'?>\r\n" +
+		"<apns version=\"8\">\r\n" +
+		"\r\n" +
+		"  <apn carrier='A &amp; B' mcc=\"250\" mnc=\"01\"   apn=\"internet\" type=\"default\" />\r\n" +
+		"\r\n" +
+		"  <!-- multi-line layout -->\r\n" +
+		"  <apn\r\n" +
+		"      carrier=\"A MMS\"\r\n" +
+		"      mcc=\"250\"\r\n" +
+		"      mnc=\"01\"\r\n" +
+		"      apn=\"mms\"\r\n" +
+		"      type=\"mms\"\r\n" +
+		"      user=\"u\"\r\n" +
+		"  />\r\n" +
+		"</apns>\r\n"
+
+	apnArray, err := ImportFromXMLByte([]byte(input), WithPreserveDocument())
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+
+	unchanged, err := ExportToXMLByte(apnArray.Clone())
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+	if string(unchanged) != input {
+		t.Fatalf("unchanged document must round-trip byte for byte:\n%q", unchanged)
+	}
+	versioned, err := ExportToXMLByte(apnArray.Clone(), WithXMLVersion("9"))
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+	if string(versioned) != strings.Replace(input, `version="8"`, `version="9"`, 1) {
+		t.Fatalf("version change must only touch the root tag:\n%q", versioned)
+	}
+
+	*apnArray[1].Base.Apn = "mms2"
+	apnArray[1].Auth.Username = nil
+	patched, err := ExportToXMLByte(apnArray.Clone())
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+	want := strings.Replace(input, "apn=\"mms\"", "apn=\"mms2\"", 1)
+	want = strings.Replace(want, "      user=\"u\"\r\n", "", 1)
+	if string(patched) != want {
+		t.Fatalf("patch must only touch the changed lines:\n%q", patched)
+	}
+
+	added := apnArray.Clone()
+	added = append(added, Object{
+		ObjectRoot: &ObjectRoot{Carrier: "C", Mcc: intPtr(250), Mnc: intPtr(2)},
+		Base:       &ObjectBase{Apn: stringPtr("c")},
+	})
+	appended, err := ExportToXMLByte(added)
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+	want = strings.Replace(want, "  />\r\n</apns>", "  />\r\n  <apn carrier=\"C\" mcc=\"250\" mnc=\"2\" apn=\"c\" type=\"default\" />\r\n</apns>", 1)
+	if string(appended) != want {
+		t.Fatalf("new record must follow the last record's indentation:\n%q", appended)
+	}
+}
+
+func TestExportProfilesTranslateAttributesAndVersion(t *testing.T) {
+	var report DecodeReport
+	apnArray, err := ImportFromXMLByte([]byte(`<apns version="7">
+	<apn carrier="A" mcc="250" mnc="01" apn="internet" type="default,rcs" authtype="3" user="u" network_type_bitmask="13|20" />
+	<apn carrier="A" mcc="250" mnc="01" apn="rcs" type="rcs" />
+</apns>`), WithDecodeReport(&report))
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+	if report.Version != "7" {
+		t.Fatalf("import must record root version, got %q", report.Version)
+	}
+	if _, err := ImportFromXMLByte([]byte(`<apns version="8"></apns>`), WithDecodeReport(&report)); err != nil || report.Version != "7" {
+		t.Fatalf("shared report must keep the first version, got %q, %v", report.Version, err)
+	}
+
+	aosp, err := ParseExportProfile("aosp")
+	if err != nil {
+		t.Fatalf("ParseExportProfile returned error: %v", err)
+	}
+	document, err := ImportFromXMLByte([]byte(`<apns version="7"><apn carrier="A" mcc="250" mnc="01" apn="internet" type="default" /></apns>`), WithPreserveDocument())
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+	if data, err := ExportToXMLByte(document, WithExportProfile(aosp)); err != nil || !strings.HasPrefix(string(data), `<apns version="7">`) {
+		t.Fatalf("aosp profile must keep the input version: %s, %v", data, err)
+	}
+
+	legacy, err := ParseExportProfile("android-9")
+	if err != nil {
+		t.Fatalf("ParseExportProfile returned error: %v", err)
+	}
+	data, err := ExportToXMLByte(apnArray, WithExportProfile(legacy), WithCanonicalLayout(CanonicalLayoutElement))
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+	want := `<apns version="8">
+	<apn carrier="A" mcc="250" mnc="1" apn="internet" user="u" password="" authtype="3" type="default" bearer_bitmask="14|20" />
+</apns>
+`
+	if string(data) != want {
+		t.Fatalf("unexpected android-9 output:\n%s", data)
+	}
+
+	named := ExportProfile{Name: "oem", Version: "9", AuthType: ExportAuthTypeNamed}
+	data, err = ExportToXMLByte(apnArray, WithExportProfile(named), WithXMLVersion("10"))
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+	if !strings.Contains(string(data), `<apns version="10">`) || !strings.Contains(string(data), `authtype="pap,chap"`) {
+		t.Fatalf("unexpected named profile output:\n%s", data)
+	}
+
+	roundTrip, err := ImportFromXMLByte(data)
+	if err != nil {
+		t.Fatalf("named authtype must import: %v", err)
+	}
+	if record := roundTrip[0].GroupMapByType[ObjectBaseTypeDefault|ObjectBaseTypeRCS]; record == nil || *record.Auth.Type != ObjectAuthTypePAP|ObjectAuthTypeCHAP {
+		t.Fatalf("unexpected named authtype round trip: %v", roundTrip)
+	}
+
+	if _, err := ParseExportProfile("android-1"); err == nil {
+		t.Fatal("unknown export profile must return error")
+	}
+	if radio, err := ParseRadioTechnologyBitmask("4|5|14"); err != nil || radio != ObjectNetworkTypeCDMA|ObjectNetworkTypeLTE {
+		t.Fatalf("unexpected radio technology mapping: %v %v", radio, err)
+	}
+}
+
+func TestLenientDecodeKeepsUnknownEnumTokens(t *testing.T) {
+	input := []byte(`<apns version="8">
+	<apn carrier="A" mcc="250" mnc="01" apn="internet" type="default,wap" protocol="IPV7" network_type_bitmask="13|21" />
+	<apn carrier="A" mcc="250" mnc="01" apn="oem" type="oem_only" />
+</apns>`)
+
+	if _, err := ImportFromXMLByte(input); err == nil {
+		t.Fatal("strict decode must reject unknown enum tokens")
+	}
+
+	var report DecodeReport
+	apnArray, err := ImportFromXMLByte(input, WithLenientDecode(), WithDecodeReport(&report))
+	if err != nil {
+		t.Fatalf("lenient decode returned error: %v", err)
+	}
+	if len(report.Warnings) != 4 || report.Warnings[0].String() != `apn 0: unknown type token "wap"` || report.Warnings[3].Record != 1 {
+		t.Fatalf("unexpected warnings: %v", report.Warnings)
+	}
+
+	record := apnArray[0].GroupMapByType[ObjectBaseTypeDefault]
+	if record == nil || *record.Other.NetworkTypeBitmask != ObjectNetworkTypeLTE || record.Unknown["protocol"][0] != "IPV7" {
+		t.Fatalf("known bits must be kept next to unknown tokens: %v", apnArray)
+	}
+	if oem := apnArray[0].GroupMapByType[ObjectBaseTypeNone]; oem == nil || oem.Unknown["type"][0] != "oem_only" {
+		t.Fatalf("record with only unknown types must not become default: %v", apnArray)
+	}
+
+	data, err := ExportToXMLByte(apnArray.Clone(), WithCanonicalLayout(CanonicalLayoutElement))
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+	for _, want := range []string{
+		`apn="internet" type="default,wap" protocol="IPV7" network_type_bitmask="13|21" />`,
+		`apn="oem" type="oem_only" />`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("unknown tokens must be re-emitted, missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestExportProfilesKeepUnknownOnlyAttrs(t *testing.T) {
+	profile := ExportProfile{
+		SupportedTypes: apnTypeAndroid9,
+		AuthType:       ExportAuthTypeNamed,
+		NetworkAttr:    ExportNetworkAndBearerBitmask,
+	}
+	tests := []struct {
+		name  string
+		input string
+		clear func(record *Object)
+		attr  xml.Attr
+	}{
+		{
+			name:  "type",
+			input: `<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="a" type="default,wap" /></apns>`,
+			clear: func(record *Object) { record.Base.Type = nil },
+			attr:  xml.Attr{Name: xml.Name{Local: "type"}, Value: "wap"},
+		},
+		{
+			name:  "authtype",
+			input: `<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="a" type="default" authtype="1" /></apns>`,
+			clear: func(record *Object) { record.Auth.Type = nil },
+			attr:  xml.Attr{Name: xml.Name{Local: "authtype"}, Value: "9"},
+		},
+		{
+			name:  "network_type_bitmask",
+			input: `<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="a" type="default" network_type_bitmask="99" /></apns>`,
+			clear: func(record *Object) {},
+			attr:  xml.Attr{Name: xml.Name{Local: "network_type_bitmask"}, Value: "99"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			apnArray, err := ImportFromXMLByte([]byte(test.input), WithLenientDecode())
+			if err != nil {
+				t.Fatalf("lenient decode returned error: %v", err)
+			}
+			record := apnArray[0].Records()[0]
+			test.clear(record)
+
+			xmlAttrArray, err := profile.transformAttrs(record, []xml.Attr{test.attr})
+			if err != nil || len(xmlAttrArray) != 1 || xmlAttrArray[0] != test.attr {
+				t.Fatalf("attribute without a struct field must pass unchanged: %v, %v", xmlAttrArray, err)
+			}
+
+			data, err := ExportToXMLByte(apnArray, WithExportProfile(profile))
+			if err != nil {
+				t.Fatalf("ExportToXMLByte returned error: %v", err)
+			}
+			if test.name == "network_type_bitmask" && !strings.Contains(string(data), `network_type_bitmask="99"`) {
+				t.Fatalf("unknown-only attribute must be written unchanged:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestLenientDecodeWarnsOnDroppedRecordsAndReadsJSON(t *testing.T) {
+	var report DecodeReport
+	_, err := ImportFromXMLByte([]byte(`<apns version="8">
+	<apn carrier="A" mcc="250" mnc="01" apn="oem1" type="oem_x" />
+	<apn carrier="A" mcc="250" mnc="01" apn="oem2" type="oem_y" />
+</apns>`), WithLenientDecode(), WithDecodeReport(&report))
+	if err != nil {
+		t.Fatalf("lenient decode returned error: %v", err)
+	}
+	if len(report.Warnings) != 3 || !strings.Contains(report.Warnings[2].String(), `apn 1: type "oem_y" duplicates the type slot`) {
+		t.Fatalf("dropped record must be reported: %v", report.Warnings)
+	}
+
+	input := []byte(`[{"carrierName":"A","mcc":250,"mnc":1,"groupMap":{
+		"default":{"base":{"apn":"internet","type":["default","oem_x"]},"bearer":{"type":"ipv7","typeRoaming":"ip"},"other":{"networkTypeBitmask":["lte","oem_radio_x"]}},
+		"None":{"base":{"apn":"oem","type":["oem_only"]}}}}]`)
+	if _, err := ImportFromJSONByte(input); err == nil {
+		t.Fatal("strict JSON decode must reject unknown enum tokens")
+	}
+
+	report = DecodeReport{}
+	apnArray, err := ImportFromJSONByte(input, WithLenientDecode(), WithDecodeReport(&report))
+	if err != nil {
+		t.Fatalf("lenient JSON decode returned error: %v", err)
+	}
+	if len(report.Warnings) != 4 {
+		t.Fatalf("unexpected JSON warnings: %v", report.Warnings)
+	}
+	record := apnArray[0].GroupMapByType[ObjectBaseTypeDefault]
+	if record == nil || *record.Other.NetworkTypeBitmask != ObjectNetworkTypeLTE || record.Bearer.Type != nil || record.Unknown["protocol"][0] != "ipv7" {
+		t.Fatalf("known JSON tokens must be kept next to unknown ones: %v", apnArray)
+	}
+	if oem := apnArray[0].GroupMapByType[ObjectBaseTypeNone]; oem == nil || oem.Unknown["type"][0] != "oem_only" {
+		t.Fatalf("JSON record with only unknown types must be kept: %v", apnArray)
+	}
+}
+
+func TestLegacyBearerMigratesToNetworkTypeBitmask(t *testing.T) {
+	input := []byte(`<apns version="8">
+	<!-- vendor -->
+	<apn carrier="A" mcc="250" mnc="01" apn="internet" type="default" bearer_bitmask="4|5|14" />
+	<apn carrier="A" mcc="250" mnc="01" apn="mms" type="mms" bearer="20" network_type_bitmask="13" />
+</apns>`)
+
+	var report DecodeReport
+	apnArray, err := ImportFromXMLByte(input, WithPreserveDocument(), WithDecodeReport(&report))
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+	if *apnArray[0].Other.NetworkTypeBitmask != ObjectNetworkTypeCDMA|ObjectNetworkTypeLTE || *apnArray[1].Other.NetworkTypeBitmask != ObjectNetworkTypeLTE {
+		t.Fatalf("unexpected network type bitmask: %v", apnArray)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].String() != `apn 1: bearer "20" conflicts with network_type_bitmask "13"` {
+		t.Fatalf("unexpected warnings: %v", report.Warnings)
+	}
+
+	if count := apnArray.MigrateLegacyBearer(); count != 2 {
+		t.Fatalf("MigrateLegacyBearer migrated %d records, want 2", count)
+	}
+	data, err := ExportToXMLByte(apnArray)
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+	output := string(data)
+	if strings.Contains(output, "bearer") || !strings.Contains(output, `type="default" network_type_bitmask="4|13" />`) || !strings.Contains(output, "<!-- vendor -->") {
+		t.Fatalf("unexpected migrated document:\n%s", output)
+	}
+}
+
+func TestRedactionAndSecretReferences(t *testing.T) {
+	input := []byte(`<apns version="8">
+	<apn carrier="A" mcc="250" mnc="01" apn="internet" type="default" user="operator" password="${env:APNXML_TEST_PASS}" authtype="1" />
+</apns>`)
+
+	apnArray, err := ImportFromXMLByte(input)
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+
+	redacted := apnArray.Redacted()[0].GroupMapByType[ObjectBaseTypeDefault]
+	record := apnArray[0].GroupMapByType[ObjectBaseTypeDefault]
+	if *redacted.Auth.Password != RedactedValue || *redacted.Auth.Username != RedactedValue || *record.Auth.Username != "operator" {
+		t.Fatalf("Redacted must replace credentials in a clone: %v", redacted)
+	}
+	if text := apnArray.String(); strings.Contains(text, "operator") || strings.Contains(text, "APNXML_TEST_PASS") {
+		t.Fatalf("String must not print credentials:\n%s", text)
+	}
+
+	if _, err := ExportToXMLByte(apnArray, WithSecretResolver(LookupSecret)); err == nil {
+		t.Fatal("unset secret environment variable must return error")
+	}
+
+	t.Setenv("APNXML_TEST_PASS", "s3cret")
+	data, err := ExportToXMLByte(apnArray, WithSecretResolver(nil))
+	if err != nil {
+		t.Fatalf("ExportToXMLByte returned error: %v", err)
+	}
+	if !strings.Contains(string(data), `password="s3cret"`) || *record.Auth.Password != "${env:APNXML_TEST_PASS}" {
+		t.Fatalf("secret must be resolved on export only:\n%s", data)
+	}
+
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	if value, err := ResolveSecretString("x-${file:"+path+"}", nil); err != nil || value != "x-from-file" {
+		t.Fatalf("unexpected file secret: %q %v", value, err)
+	}
+}
+
+func TestRegisterFormatDispatchesImportAndExport(t *testing.T) {
+	format, err := ParseFormat("plmn-list")
+	if err != nil {
+		format, err = RegisterFormat("plmn-list", []string{"plmns.txt"},
+			func(data []byte, _ ...DecodeOption) (Array, error) {
+				var apnArray Array
+				for _, line := range strings.Fields(string(data)) {
+					var mcc, mnc int
+					if _, err := fmt.Sscanf(line, "%3d%d", &mcc, &mnc); err != nil {
+						return nil, err
+					}
+					apnArray = append(apnArray, Object{ObjectRoot: &ObjectRoot{Mcc: intPtr(mcc), Mnc: intPtr(mnc)}})
+				}
+				return apnArray, nil
+			},
+			func(apnArray Array, _ ...EncodeOption) ([]byte, error) {
+				var builder strings.Builder
+				for _, apnObject := range apnArray {
+					builder.WriteString(apnObject.GetPLMN() + "\n")
+				}
+				return []byte(builder.String()), nil
+			},
+		)
+		if err != nil {
+			t.Fatalf("RegisterFormat returned error: %v", err)
+		}
+	}
+
+	if _, err := RegisterFormat("PLMN-LIST", nil, decodeJSON, nil); err == nil {
+		t.Fatal("duplicate format name must return error")
+	}
+	if _, err := RegisterFormat("other-json", []string{"JSON"}, decodeJSON, nil); err == nil {
+		t.Fatal("duplicate file extension must return error")
+	}
+	if detected, err := FormatFromFilename("/tmp/operators.PLMNS.TXT"); err != nil || detected != format {
+		t.Fatalf("unexpected format from filename: %q %v", detected, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.plmns.txt")
+	apnArray, err := ImportFromReader(strings.NewReader("25001 25102"), format)
+	if err != nil {
+		t.Fatalf("ImportFromReader returned error: %v", err)
+	}
+	if err := ExportToFile(apnArray, path); err != nil {
+		t.Fatalf("ExportToFile returned error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "25001\n25102\n" {
+		t.Fatalf("unexpected exported data: %q", data)
+	}
+	if !FormatXML.CanDecode() || !format.CanEncode() || Format("missing").CanEncode() {
+		t.Fatal("unexpected format capabilities")
+	}
+}
+
+func TestRegisteredEnumValuesRoundTrip(t *testing.T) {
+	t.Cleanup(snapshotEnumRegistry())
+
+	oemType, err := RegisterObjectBaseType("oem_registry_test")
+	if err != nil {
+		t.Fatalf("RegisterObjectBaseType returned error: %v", err)
+	}
+	if oemType < ObjectBaseTypeMax || oemType&(oemType-1) != 0 {
+		t.Fatalf("registered type must get a fresh bit, got %d", oemType)
+	}
+	oemNetwork, err := RegisterObjectNetworkType("oem_radio_test", 90)
+	if err != nil {
+		t.Fatalf("RegisterObjectNetworkType returned error: %v", err)
+	}
+
+	if _, err := RegisterObjectBaseType("OEM_REGISTRY_TEST"); err == nil {
+		t.Fatal("duplicate type name must be rejected")
+	}
+	if _, err := RegisterObjectBaseType("mms"); err == nil {
+		t.Fatal("built-in type name must be rejected")
+	}
+	if _, err := RegisterObjectNetworkType("oem_radio_other", 20); err == nil {
+		t.Fatal("network type number collision must be rejected")
+	}
+	if _, err := RegisterObjectNetworkType("oem_radio_pinned", len(apnTypeNetworkTypeStorage.xml.IndexArray)+2); err != nil {
+		t.Fatalf("RegisterObjectNetworkType returned error: %v", err)
+	}
+	if _, err := RegisterObjectNetworkType("oem_radio_auto", 0); err != nil {
+		t.Fatalf("auto network type number must skip registered numbers: %v", err)
+	}
+
+	parsed, err := ParseObjectBaseType("default,oem_registry_test")
+	if err != nil || parsed != ObjectBaseTypeDefault|oemType || parsed.String() != "default|oem_registry_test" {
+		t.Fatalf("registered type must parse: %v %v", parsed, err)
+	}
+
+	apnArray, err := ImportFromXMLByte([]byte(`<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="oem" type="default,oem_registry_test" network_type_bitmask="13|90" /></apns>`))
+	if err != nil {
+		t.Fatalf("registered values must decode: %v", err)
+	}
+	record := apnArray[0].GroupMapByType[ObjectBaseTypeDefault|oemType]
+	if record == nil || *record.Other.NetworkTypeBitmask != ObjectNetworkTypeLTE|oemNetwork {
+		t.Fatalf("unexpected decoded record: %v", apnArray)
+	}
+
+	data, err := ExportToXMLByte(apnArray)
+	if err != nil || !strings.Contains(string(data), `type="default,oem_registry_test" network_type_bitmask="13|90"`) {
+		t.Fatalf("registered values must round-trip: %v\n%s", err, data)
+	}
+
+	jsonData, err := ExportToJSONByte(apnArray)
+	if err != nil {
+		t.Fatalf("ExportToJSONByte returned error: %v", err)
+	}
+	if jsonArray, err := ImportFromJSONByte(jsonData); err != nil || jsonArray.CountRecords() != 1 {
+		t.Fatalf("registered values must round-trip through JSON: %v", err)
+	}
+}
+
 func TestImportFromXMLGroupsEntriesByPLMNAndKeepsFirstTypeEntry(t *testing.T) {
 	apns, err := ImportFromXMLByte([]byte(`<apns version="8">
 		<apn carrier="Carrier Internet" mcc="250" mnc="01" apn="internet" type="default" protocol="IPV4V6" />
@@ -197,6 +755,43 @@ func TestObjectUpdateModes(t *testing.T) {
 	}
 }
 
+func TestEffectiveFillsPlatformDefaults(t *testing.T) {
+	input := []byte(`<apns version="8">
+	<apn carrier="A" mcc="250" mnc="01" apn="internet" type="default,mcx" />
+	<apn carrier="A" mcc="250" mnc="01" apn="mms" type="mms" user="operator" protocol="IPV6" carrier_enabled="false" />
+</apns>`)
+
+	apnArray, err := ImportFromXMLByte(input)
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+
+	effective := apnArray.Effective()
+	internet := effective[0].GroupMapByType[ObjectBaseTypeDefault|ObjectBaseTypeMCX]
+	mms := effective[0].GroupMapByType[ObjectBaseTypeMMS]
+	switch {
+	case apnArray[0].GroupMapByType[ObjectBaseTypeMMS].Other.UserVisible != nil:
+		t.Fatal("Effective must not modify the source array")
+	case *internet.Bearer.Type != ObjectBearerProtocolIP || *internet.Bearer.TypeRoaming != ObjectBearerProtocolIP || *internet.Bearer.Mtu != 0:
+		t.Fatalf("unexpected bearer defaults: %+v", internet.Bearer)
+	case *internet.Auth.Type != ObjectAuthTypeNone || *mms.Auth.Type != ObjectAuthTypePAP|ObjectAuthTypeCHAP:
+		t.Fatalf("auth type must follow credentials: %v %v", *internet.Auth.Type, *mms.Auth.Type)
+	case !*internet.Other.CarrierEnabled || !*internet.Other.UserVisible || !*internet.Other.UserEditable || *internet.Other.ModemCognitive:
+		t.Fatalf("unexpected other defaults: %+v", internet.Other)
+	case *mms.Other.CarrierEnabled || *mms.Bearer.Type != ObjectBearerProtocolIPv6:
+		t.Fatalf("explicit values must be kept: %+v %+v", mms.Other, mms.Bearer)
+	}
+
+	profile, err := ParseExportProfile("android-9")
+	if err != nil {
+		t.Fatalf("ParseExportProfile returned error: %v", err)
+	}
+	android9 := apnArray.EffectiveFor(profile)[0].GroupMapByType[ObjectBaseTypeDefault|ObjectBaseTypeMCX]
+	if *android9.Base.Type != ObjectBaseTypeDefault {
+		t.Fatalf("android-9 must drop unsupported APN types: %v", *android9.Base.Type)
+	}
+}
+
 func TestImportExportReaderWriter(t *testing.T) {
 	input := strings.NewReader(`<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="internet" type="default" /></apns>`)
 
@@ -234,6 +829,220 @@ func TestImportFromURLUsesContextAndClient(t *testing.T) {
 	}
 }
 
+func TestDetectFormatUnwrapsPayloads(t *testing.T) {
+	xmlPayload := "\ufeff<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<apns version=\"8\"><apn carrier=\"A\" mcc=\"250\" mnc=\"01\" /></apns>"
+
+	var gzipBuffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipBuffer)
+	_, _ = gzipWriter.Write([]byte(xmlPayload))
+	_ = gzipWriter.Close()
+
+	gitiles := base64.StdEncoding.EncodeToString([]byte(xmlPayload))
+	gitiles = gitiles[:20] + "\n" + gitiles[20:] + "\n"
+
+	for name, test := range map[string]struct {
+		data []byte
+		want Format
+	}{
+		"xml with bom":   {data: []byte(xmlPayload), want: FormatXML},
+		"json":           {data: []byte("  [{\"carrierName\":\"A\",\"mcc\":250,\"mnc\":1}]"), want: FormatJSON},
+		"yaml":           {data: []byte("# overlay\n- carrierName: A\n  mcc: 250\n  mnc: 1\n"), want: FormatYAML},
+		"gitiles base64": {data: []byte(gitiles), want: FormatXML},
+		"gzip":           {data: gzipBuffer.Bytes(), want: FormatXML},
+		"base64 of gzip": {data: []byte(base64.StdEncoding.EncodeToString(gzipBuffer.Bytes())), want: FormatXML},
+	} {
+		format, err := DetectFormat(test.data)
+		if err != nil || format != test.want {
+			t.Fatalf("%s: DetectFormat = %q %v, want %q", name, format, err, test.want)
+		}
+
+		apns, err := ImportFromReader(bytes.NewReader(test.data), FormatAuto)
+		if err != nil || len(apns) != 1 || apns[0].GetPLMN() != "25001" {
+			t.Fatalf("%s: unexpected auto import: %v %v", name, apns, err)
+		}
+	}
+
+	if _, err := DetectFormat([]byte("plain text")); err == nil {
+		t.Fatal("unknown payload must return error")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		_, _ = response.Write([]byte(gitiles))
+	}))
+	defer server.Close()
+
+	apns, err := ImportFromURL(context.Background(), server.Client(), server.URL, FormatAuto, false)
+	if err != nil || len(apns) != 1 {
+		t.Fatalf("auto URL import must detect base64: %v %v", apns, err)
+	}
+}
+
+func TestImportDeviceDumps(t *testing.T) {
+	contentQuery := "Row: 0 _id=1, name=Operator One, numeric=25001, mcc=250, mnc=01, carrier_id=-1, apn=internet, user=NULL, password=NULL, proxy=, port=, authtype=-1, type=default,supl, current=1, protocol=IPV4V6, roaming_protocol=IP, carrier_enabled=1, network_type_bitmask=528384, mvno_type=, sub_id=-1, mtu=0, mtu_v4=1400, user_visible=1\n" +
+		"Row: 1 _id=2, name=Operator Two, numeric=25002, apn=ims, type=ims, protocol=IPV6, bearer_bitmask=8192, carrier_enabled=0\n"
+	dumpsys := "DcTracker:\n" +
+		"   [ApnSetting] Operator One, 1, 25001, internet, , , , , , -1, default | supl, IPV4V6, IP, true, 0, false, 0, 0, 0, 1400, 0, null, , 0, LTE|NR, 0, 0, -1, -1, false, 3, false, 0\n" +
+		"   [ApnSettingV7] Operator, Two, 2, 25002, ims, , , , , , 0, ims, IPV6, IPV6, false, 2, true, 0, 0, 0, 1280, spn, Two, 0, 8192, 0, 42, -1\n" +
+		" mPreferredApn=[ApnSetting] Operator One, 1, 25001, internet, , , , , , -1, default | supl, IPV4V6, IP, true, 0, false, 0, 0, 0, 1400, 0, null, , 0, LTE|NR, 0, 0, -1, -1, false, 3, false, 0\n"
+
+	for name, test := range map[string]struct {
+		data string
+		want Format
+		xml  string
+	}{
+		"content query": {
+			data: contentQuery,
+			want: FormatContentQuery,
+			xml: `<apns><apn carrier="Operator One" mcc="250" mnc="01" apn="internet" type="default,supl" protocol="IPV4V6" roaming_protocol="IP" mtu="1400" mtu_v4="1400" network_type_bitmask="13|20" carrier_enabled="true" user_visible="true" />
+				<apn carrier="Operator Two" mcc="250" mnc="02" apn="ims" type="ims" protocol="IPV6" network_type_bitmask="13" carrier_enabled="false" /></apns>`,
+		},
+		"dumpsys": {
+			data: dumpsys,
+			want: FormatDumpsys,
+			xml: `<apns><apn carrier="Operator One" mcc="250" mnc="01" apn="internet" type="default,supl" protocol="IPV4V6" roaming_protocol="IP" mtu="1400" profile_id="0" network_type_bitmask="13|20" modem_cognitive="false" max_conns="0" max_conns_time="0" carrier_enabled="true" />
+				<apn carrier="Operator, Two" carrier_id="42" mcc="250" mnc="02" apn="ims" authtype="0" type="ims" protocol="IPV6" roaming_protocol="IPV6" mtu="1280" profile_id="2" network_type_bitmask="14" modem_cognitive="true" max_conns="0" max_conns_time="0" carrier_enabled="false" mvno_type="spn" mvno_match_data="Two" /></apns>`,
+		},
+	} {
+		format, err := DetectFormat([]byte(test.data))
+		if err != nil || format != test.want {
+			t.Fatalf("%s: DetectFormat = %q %v, want %q", name, format, err, test.want)
+		}
+
+		apns, err := ImportFromReader(strings.NewReader(test.data), FormatAuto)
+		if err != nil {
+			t.Fatalf("%s: import returned error: %v", name, err)
+		}
+		want, err := ImportFromXMLByte([]byte(test.xml))
+		if err != nil {
+			t.Fatalf("%s: ImportFromXMLByte returned error: %v", name, err)
+		}
+		got, _ := ExportToXMLByte(apns)
+		wantXML, _ := ExportToXMLByte(want)
+		if string(got) != string(wantXML) {
+			t.Fatalf("%s: unexpected records:\n got %s\nwant %s", name, got, wantXML)
+		}
+	}
+
+	if format, err := FormatFromFilename("phone.dumpsys.txt"); err != nil || format != FormatDumpsys || FormatDumpsys.CanEncode() {
+		t.Fatalf("dumpsys must be an import-only format: %q %v", format, err)
+	}
+	if _, err := ImportFromReader(strings.NewReader("Row: 0 _id=1, sub_id=-1"), FormatContentQuery); err == nil {
+		t.Fatal("content query rows without carrier columns must return error")
+	}
+	if _, err := ImportFromReader(strings.NewReader("no apn settings here"), FormatDumpsys); err == nil {
+		t.Fatal("dumpsys without ApnSetting lines must return error")
+	}
+}
+
+func TestYAMLUsesJSONFieldsAndKeepsComments(t *testing.T) {
+	data := `# curated overlay for operator one
+---
+- carrierName: "Operator: One" # quoted because of the colon
+  mcc: 250
+  mnc: 01
+  base:
+    apn: internet
+    type: [default, supl]
+  auth:
+    type:
+      - pap
+      - chap
+    username: 'o''one'
+    password: "#secret"
+  bearer: {type: ipv4v6, typeRoaming: ip}
+  other:
+    networkTypeBitmask: [lte, nr]
+    IsEnabled: true
+`
+	apns, err := ImportFromReader(strings.NewReader(data), FormatAuto)
+	if err != nil {
+		t.Fatalf("yaml import returned error: %v", err)
+	}
+	want, _ := ImportFromXMLByte([]byte(`<apns><apn carrier="Operator: One" mcc="250" mnc="01" apn="internet" type="default,supl" authtype="3" user="o'one" password="#secret" protocol="IPV4V6" roaming_protocol="IP" network_type_bitmask="13|20" carrier_enabled="true" /></apns>`))
+	got, _ := ExportToXMLByte(apns)
+	wantXML, _ := ExportToXMLByte(want)
+	if string(got) != string(wantXML) {
+		t.Fatalf("unexpected yaml records:\n got %s\nwant %s", got, wantXML)
+	}
+
+	var buffer bytes.Buffer
+	if err := ExportToWriter(apns, &buffer, FormatYAML); err != nil {
+		t.Fatalf("yaml export returned error: %v", err)
+	}
+	for _, line := range []string{`- carrierName: "Operator: One"`, "    type: [default, supl]", `    password: "#secret"`, "    typeRoaming: ip"} {
+		if !strings.Contains(buffer.String(), line+"\n") {
+			t.Fatalf("yaml export misses %q:\n%s", line, buffer.String())
+		}
+	}
+	roundTrip, err := ImportFromReader(bytes.NewReader(buffer.Bytes()), FormatYAML)
+	if err != nil {
+		t.Fatalf("yaml round trip returned error: %v", err)
+	}
+	if got, _ := ExportToXMLByte(roundTrip); string(got) != string(wantXML) {
+		t.Fatalf("yaml round trip changed records:\n got %s\nwant %s", got, wantXML)
+	}
+
+	if format, err := FormatFromFilename("overlay.yml"); err != nil || format != FormatYAML {
+		t.Fatalf("unexpected yaml extension format: %q %v", format, err)
+	}
+	for name, data := range map[string]string{
+		"tab indentation": "- carrierName: A\n\tmcc: 250\n",
+		"duplicate key":   "- mcc: 250\n  mcc: 251\n",
+		"anchor":          "- base: &base {apn: internet}\n",
+		"two documents":   "- mcc: 250\n---\n- mcc: 251\n",
+		"unknown enum":    "- mcc: 250\n  mnc: 1\n  base: {type: [default, bogus]}\n",
+	} {
+		if _, err := ImportFromReader(strings.NewReader(data), FormatYAML); err == nil {
+			t.Fatalf("%s: yaml import must return error", name)
+		}
+	}
+}
+
+func TestCompressedAndArchivedFiles(t *testing.T) {
+	apnArray, err := ImportFromXMLByte([]byte(`<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="internet" type="default" /></apns>`))
+	if err != nil {
+		t.Fatalf("ImportFromXMLByte returned error: %v", err)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"apns-conf.xml.gz", "apns-conf.json.zip", "apns-conf.xml.tar", "apns-conf.xml.tar.gz", "apns-conf.json.tgz"} {
+		filename := filepath.Join(dir, name)
+		if err := ExportToFile(apnArray, filename); err != nil {
+			t.Fatalf("%s: ExportToFile returned error: %v", name, err)
+		}
+
+		imported, err := ImportFromFile(filename)
+		if err != nil || len(imported) != 1 || imported[0].GetPLMN() != "25001" {
+			t.Fatalf("%s: unexpected import: %v %v", name, imported, err)
+		}
+	}
+	if format, err := FormatFromFilename("apns-conf.json.gz"); err != nil || format != FormatJSON {
+		t.Fatalf("unexpected compound extension format: %q %v", format, err)
+	}
+
+	var buffer bytes.Buffer
+	zipWriter := zip.NewWriter(&buffer)
+	for _, name := range []string{"system/etc/apns-conf.xml", "vendor/etc/apns-conf.xml", "system/build.prop"} {
+		writer, _ := zipWriter.Create(name)
+		_, _ = writer.Write([]byte(`<apns version="8"><apn carrier="A" mcc="250" mnc="01" apn="internet" type="default" /></apns>`))
+	}
+	_ = zipWriter.Close()
+
+	otaPath := filepath.Join(dir, "ota.zip")
+	if err := os.WriteFile(otaPath, buffer.Bytes(), 0o600); err != nil {
+		t.Fatalf("write ota: %v", err)
+	}
+	if _, err := ImportFromFile(otaPath); err == nil || !strings.Contains(err.Error(), "several apn files") {
+		t.Fatalf("ambiguous archive must return error, got %v", err)
+	}
+	if imported, err := ImportFromArchive(otaPath, "/vendor/etc/apns-conf.xml"); err != nil || len(imported) != 1 {
+		t.Fatalf("unexpected archive member import: %v %v", imported, err)
+	}
+	if _, err := ImportFromArchive(otaPath, "product/etc/apns-conf.xml"); err == nil {
+		t.Fatal("missing archive member must return error")
+	}
+}
+
 func TestImportFromSimpleURL(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
 		_, _ = response.Write([]byte(`<apns version="8"><apn carrier="A" mcc="250" mnc="01" /></apns>`))
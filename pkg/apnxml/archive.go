@@ -0,0 +1,345 @@
+package apnxml
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//--------------------------------------------------------------------------------//
+// Archive
+//--------------------------------------------------------------------------------//
+
+type archiveKind int
+
+const (
+	archiveNone archiveKind = iota
+	archiveGzip
+	archiveZip
+	archiveTar
+	archiveTarGzip
+)
+
+var archiveSuffixArray = []struct {
+	suffix string
+	kind   archiveKind
+}{
+	{suffix: ".tar.gz", kind: archiveTarGzip},
+	{suffix: ".tgz", kind: archiveTarGzip},
+	{suffix: ".tar", kind: archiveTar},
+	{suffix: ".zip", kind: archiveZip},
+	{suffix: ".gz", kind: archiveGzip},
+}
+
+func splitArchiveFilename(filename string) (string, archiveKind) {
+	lowerFilename := strings.ToLower(filename)
+	for _, archiveSuffix := range archiveSuffixArray {
+		if strings.HasSuffix(lowerFilename, archiveSuffix.suffix) {
+			return filename[:len(filename)-len(archiveSuffix.suffix)], archiveSuffix.kind
+		}
+	}
+
+	return filename, archiveNone
+}
+
+func IsArchiveFilename(filename string) bool {
+	_, kind := splitArchiveFilename(filename)
+	return kind != archiveNone
+}
+
+func formatFromFilenameOrAuto(filename string) Format {
+	format, err := FormatFromFilename(filename)
+	if err != nil {
+		return FormatAuto
+	}
+
+	return format
+}
+
+func gunzipData(data []byte) ([]byte, error) {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode apn gzip payload: %w", err)
+	}
+	defer gzipReader.Close()
+
+	data, err = io.ReadAll(gzipReader)
+	if err != nil {
+		return nil, fmt.Errorf("decode apn gzip payload: %w", err)
+	}
+
+	return data, nil
+}
+
+func gzipData(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&buffer)
+	if _, err := gzipWriter.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+//--------------------------------------------------------------------------------//
+// Archive Read
+//--------------------------------------------------------------------------------//
+
+type archiveMember struct {
+	name string
+	open func() ([]byte, error)
+}
+
+func listArchiveMembers(data []byte, kind archiveKind) ([]archiveMember, error) {
+	var memberArray []archiveMember
+
+	switch kind {
+	case archiveZip:
+		zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("read apn zip archive: %w", err)
+		}
+
+		for _, zipFile := range zipReader.File {
+			if zipFile.FileInfo().IsDir() {
+				continue
+			}
+
+			zipFile := zipFile
+			memberArray = append(memberArray, archiveMember{
+				name: zipFile.Name,
+				open: func() ([]byte, error) {
+					reader, err := zipFile.Open()
+					if err != nil {
+						return nil, err
+					}
+					defer reader.Close()
+
+					return io.ReadAll(reader)
+				},
+			})
+		}
+	case archiveTar, archiveTarGzip:
+		if kind == archiveTarGzip {
+			var err error
+			data, err = gunzipData(data)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		tarReader := tar.NewReader(bytes.NewReader(data))
+		for {
+			tarHeader, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("read apn tar archive: %w", err)
+			}
+			if tarHeader.Typeflag != tar.TypeReg {
+				continue
+			}
+
+			memberData, err := io.ReadAll(tarReader)
+			if err != nil {
+				return nil, fmt.Errorf("read apn tar archive: %w", err)
+			}
+
+			memberArray = append(memberArray, archiveMember{
+				name: tarHeader.Name,
+				open: func() ([]byte, error) {
+					return memberData, nil
+				},
+			})
+		}
+	default:
+		return nil, fmt.Errorf("apn file is not an archive")
+	}
+
+	return memberArray, nil
+}
+
+func cleanArchiveMemberName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+}
+
+func selectArchiveMember(memberArray []archiveMember, memberName string) (archiveMember, error) {
+	if memberName != "" {
+		memberName = cleanArchiveMemberName(memberName)
+		for _, member := range memberArray {
+			if cleanArchiveMemberName(member.name) == memberName {
+				return member, nil
+			}
+		}
+
+		return archiveMember{}, fmt.Errorf("apn archive has no member %q", memberName)
+	}
+
+	var candidateArray []archiveMember
+	for _, member := range memberArray {
+		if _, err := FormatFromFilename(member.name); err == nil {
+			candidateArray = append(candidateArray, member)
+		}
+	}
+
+	if len(candidateArray) > 1 {
+		var apnArray []archiveMember
+		for _, member := range candidateArray {
+			if strings.HasPrefix(strings.ToLower(path.Base(member.name)), "apns") {
+				apnArray = append(apnArray, member)
+			}
+		}
+		if len(apnArray) > 0 {
+			candidateArray = apnArray
+		}
+	}
+
+	switch len(candidateArray) {
+	case 0:
+		return archiveMember{}, fmt.Errorf("apn archive has no apn file")
+	case 1:
+		return candidateArray[0], nil
+	default:
+		nameArray := make([]string, 0, len(candidateArray))
+		for _, member := range candidateArray {
+			nameArray = append(nameArray, member.name)
+		}
+
+		return archiveMember{}, fmt.Errorf("apn archive has several apn files, select one: %s", strings.Join(nameArray, ", "))
+	}
+}
+
+func readArchiveFile(filename string, data []byte, memberName string) ([]byte, Format, error) {
+	innerFilename, kind := splitArchiveFilename(filename)
+
+	switch kind {
+	case archiveNone:
+		if memberName != "" {
+			return nil, "", fmt.Errorf("apn file is not an archive: %s", filename)
+		}
+
+		return data, formatFromFilenameOrAuto(filename), nil
+	case archiveGzip:
+		if memberName != "" {
+			return nil, "", fmt.Errorf("apn gzip file has no members: %s", filename)
+		}
+
+		data, err := gunzipData(data)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return data, formatFromFilenameOrAuto(innerFilename), nil
+	default:
+		memberArray, err := listArchiveMembers(data, kind)
+		if err != nil {
+			return nil, "", err
+		}
+
+		member, err := selectArchiveMember(memberArray, memberName)
+		if err != nil {
+			return nil, "", err
+		}
+
+		data, err := member.open()
+		if err != nil {
+			return nil, "", fmt.Errorf("read apn archive member %q: %w", member.name, err)
+		}
+
+		return data, formatFromFilenameOrAuto(member.name), nil
+	}
+}
+
+func ImportFromArchive(filename string, memberName string, optionList ...DecodeOption) (Array, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, kind := splitArchiveFilename(filename); kind == archiveNone || kind == archiveGzip {
+		return nil, fmt.Errorf("apn file is not an archive: %s", filename)
+	}
+
+	data, format, err := readArchiveFile(filename, data, memberName)
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(data, format, optionList)
+}
+
+//--------------------------------------------------------------------------------//
+// Archive Write
+//--------------------------------------------------------------------------------//
+
+func WriteArchiveFile(filename string, data []byte) error {
+	data, err := writeArchiveData(filename, data)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, data, 0644)
+}
+
+func writeArchiveData(filename string, data []byte) ([]byte, error) {
+	innerFilename, kind := splitArchiveFilename(filename)
+	memberName := filepath.Base(innerFilename)
+
+	switch kind {
+	case archiveGzip:
+		return gzipData(data)
+	case archiveZip:
+		var buffer bytes.Buffer
+
+		zipWriter := zip.NewWriter(&buffer)
+		writer, err := zipWriter.CreateHeader(&zip.FileHeader{Name: memberName, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zipWriter.Close(); err != nil {
+			return nil, err
+		}
+
+		return buffer.Bytes(), nil
+	case archiveTar, archiveTarGzip:
+		var buffer bytes.Buffer
+
+		tarWriter := tar.NewWriter(&buffer)
+		tarHeader := &tar.Header{Name: memberName, Mode: 0644, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+		if err := tarWriter.WriteHeader(tarHeader); err != nil {
+			return nil, err
+		}
+		if _, err := tarWriter.Write(data); err != nil {
+			return nil, err
+		}
+		if err := tarWriter.Close(); err != nil {
+			return nil, err
+		}
+
+		if kind == archiveTarGzip {
+			return gzipData(buffer.Bytes())
+		}
+
+		return buffer.Bytes(), nil
+	default:
+		return data, nil
+	}
+}
+
+//--------------------------------------------------------------------------------//
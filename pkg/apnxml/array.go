@@ -42,7 +42,7 @@ func (apnArray Array) CountRecords() int {
 }
 
 func (apnArray Array) String() string {
-	jsonData, err := json.MarshalIndent(apnArray, "", "\t")
+	jsonData, err := json.MarshalIndent(apnArray.Redacted(), "", "\t")
 	if err != nil {
 		return fmt.Sprintf("error: %v", err)
 	}
@@ -50,7 +50,25 @@ func (apnArray Array) String() string {
 	return string(jsonData)
 }
 
+type xmlArrayCodec struct {
+	array         *Array
+	encodeOptions encodeOptions
+	decodeOptions decodeOptions
+}
+
+func (codec xmlArrayCodec) MarshalXML(xmlEncoder *xml.Encoder, _ xml.StartElement) error {
+	return codec.array.marshalXML(xmlEncoder, codec.encodeOptions)
+}
+
+func (codec xmlArrayCodec) UnmarshalXML(xmlDecoder *xml.Decoder, xmlStart xml.StartElement) error {
+	return codec.array.unmarshalXML(xmlDecoder, xmlStart, codec.decodeOptions)
+}
+
 func (apnArray Array) MarshalXML(xmlEncoder *xml.Encoder, _ xml.StartElement) error {
+	return apnArray.marshalXML(xmlEncoder, encodeOptions{})
+}
+
+func (apnArray Array) marshalXML(xmlEncoder *xml.Encoder, options encodeOptions) error {
 	var (
 		xmlStart xml.StartElement
 		err      error
@@ -67,11 +85,15 @@ func (apnArray Array) MarshalXML(xmlEncoder *xml.Encoder, _ xml.StartElement) er
 				Name: xml.Name{
 					Local: "version",
 				},
-				Value: "8",
+				Value: options.xmlVersion(apnArray.sourceVersion()),
 			},
 		},
 	}
 
+	if apnArray.hasSource() {
+		return apnArray.marshalXMLDocument(xmlEncoder, xmlStart, options)
+	}
+
 	err = xmlEncoder.EncodeToken(xmlStart)
 	if err != nil {
 		return err
@@ -84,11 +106,15 @@ func (apnArray Array) MarshalXML(xmlEncoder *xml.Encoder, _ xml.StartElement) er
 		}
 
 		if apnPointerRoot.GroupMapByType == nil {
-			err = xmlEncoder.EncodeElement(apnPointerRoot, xml.StartElement{
+			if !options.profile.acceptsObject(apnPointerRoot) {
+				continue
+			}
+
+			err = apnPointerRoot.marshalXML(xmlEncoder, xml.StartElement{
 				Name: xml.Name{
 					Local: "apn",
 				},
-			})
+			}, options)
 		} else {
 			var (
 				apnPointerBaseTypeArray []ObjectBaseType
@@ -105,16 +131,19 @@ func (apnArray Array) MarshalXML(xmlEncoder *xml.Encoder, _ xml.StartElement) er
 
 			for _, apnPointerBaseTypeString := range apnPointerBaseTypeArray {
 				apnPointer = apnPointerRoot.GroupMapByType[apnPointerBaseTypeString].NormalizedClone()
-				if apnPointer == nil {
+				if apnPointer == nil || !options.profile.acceptsObject(apnPointer) {
 					continue
 				}
 				apnPointer.ObjectRoot = apnPointerRoot.ObjectRoot.Clone()
 
-				err = xmlEncoder.EncodeElement(apnPointer, xml.StartElement{
+				err = apnPointer.marshalXML(xmlEncoder, xml.StartElement{
 					Name: xml.Name{
 						Local: "apn",
 					},
-				})
+				}, options)
+				if err != nil {
+					break
+				}
 			}
 		}
 
@@ -132,15 +161,24 @@ func (apnArray Array) MarshalXML(xmlEncoder *xml.Encoder, _ xml.StartElement) er
 }
 
 func (apnArray *Array) UnmarshalXML(xmlDecoder *xml.Decoder, xmlStart xml.StartElement) error {
+	return apnArray.unmarshalXML(xmlDecoder, xmlStart, decodeOptions{})
+}
+
+func (apnArray *Array) unmarshalXML(xmlDecoder *xml.Decoder, xmlStart xml.StartElement, options decodeOptions) error {
 	var (
 		apnPointerArrayMap = map[string][]*Object{}
 		apnPointerRootMap  = map[string]*ObjectRoot{}
+		apnPointerIndexMap = map[*Object]int{}
 	)
 
 	if xmlStart.Name.Local != "apns" {
 		return fmt.Errorf("apn xml has incorrect root element: %q", xmlStart.Name.Local)
 	}
 
+	options.recordRoot(xmlStart)
+
+	apnObjectIndex := 0
+
 	for {
 		var (
 			xmlDecoderToken xml.Token
@@ -162,14 +200,16 @@ func (apnArray *Array) UnmarshalXML(xmlDecoder *xml.Decoder, xmlStart xml.StartE
 		switch xmlDecoderElement := xmlDecoderToken.(type) {
 		case xml.StartElement:
 			if xmlDecoderElement.Name.Local == "apn" {
-				err = xmlDecoder.DecodeElement(&apnObject, &xmlDecoderElement)
+				err = apnObject.unmarshalXML(xmlDecoder, xmlDecoderElement, options, apnObjectIndex)
 				if err != nil {
 					return err
 				}
+				apnObjectIndex++
 
 				if apnObject.ObjectRoot.Validate() {
 					apnObjectBaseID = apnObject.GetID()
 					apnPointerArrayMap[apnObjectBaseID] = append(apnPointerArrayMap[apnObjectBaseID], &apnObject)
+					apnPointerIndexMap[&apnObject] = apnObjectIndex - 1
 
 					apnPointerRoot = apnPointerRootMap[apnObjectBaseID]
 					if apnPointerRoot == nil || len(apnPointerRoot.Carrier) < len(apnObject.Carrier) {
@@ -202,6 +242,8 @@ func (apnArray *Array) UnmarshalXML(xmlDecoder *xml.Decoder, xmlStart xml.StartE
 			apnPointer.ObjectRoot = nil
 			if _, ok := apnObject.GroupMapByType[*apnPointer.Base.Type]; !ok {
 				apnObject.GroupMapByType[*apnPointer.Base.Type] = apnPointer.Clone()
+			} else if *apnPointer.Base.Type == ObjectBaseTypeNone {
+				options.warnDropped(apnPointerIndexMap[apnPointer], apnPointer)
 			}
 		}
 
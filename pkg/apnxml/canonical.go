@@ -0,0 +1,326 @@
+package apnxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------//
+// Canonical Attribute Order
+//--------------------------------------------------------------------------------//
+
+var canonicalAttributeOrder = []string{
+	"carrier",
+	"carrier_id",
+	"mcc",
+	"mnc",
+	"apn",
+	"user",
+	"password",
+	"server",
+	"proxy",
+	"port",
+	"mmsc",
+	"mmsproxy",
+	"mmsport",
+	"authtype",
+	"type",
+	"protocol",
+	"roaming_protocol",
+	"mtu",
+	"mtu_v4",
+	"mtu_v6",
+	"profile_id",
+	"apn_set_id",
+	"network_type_bitmask",
+	"lingering_network_type_bitmask",
+	"bearer_bitmask",
+	"bearer",
+	"modem_cognitive",
+	"max_conns",
+	"max_conns_time",
+	"wait_time",
+	"skip_464xlat",
+	"always_on",
+	"carrier_enabled",
+	"user_visible",
+	"user_editable",
+	"mvno_type",
+	"mvno_match_data",
+}
+
+var canonicalAttributeIndex = func() map[string]int {
+	result := make(map[string]int, len(canonicalAttributeOrder))
+	for index, name := range canonicalAttributeOrder {
+		result[name] = index
+	}
+	return result
+}()
+
+func CanonicalAttributeOrder() []string {
+	return append([]string(nil), canonicalAttributeOrder...)
+}
+
+func sortCanonicalAttrs(xmlAttrArray []xml.Attr) {
+	sort.SliceStable(xmlAttrArray, func(i, j int) bool {
+		indexI, okI := canonicalAttributeIndex[xmlAttrArray[i].Name.Local]
+		indexJ, okJ := canonicalAttributeIndex[xmlAttrArray[j].Name.Local]
+		if okI != okJ {
+			return okI
+		}
+
+		return okI && indexI < indexJ
+	})
+}
+
+func objectXMLAttrs(apnPointerCore *Object) ([]xml.Attr, error) {
+	var xmlAttrArray []xml.Attr
+
+	for _, apnSection := range []any{
+		apnPointerCore.ObjectRoot,
+		apnPointerCore.Base,
+		apnPointerCore.Auth,
+		apnPointerCore.Bearer,
+		apnPointerCore.Proxy,
+		apnPointerCore.Mms,
+		apnPointerCore.Mvno,
+		apnPointerCore.Limit,
+		apnPointerCore.Other,
+	} {
+		sectionValue := reflect.ValueOf(apnSection)
+		if sectionValue.IsNil() {
+			continue
+		}
+
+		sectionValue = sectionValue.Elem()
+		sectionType := sectionValue.Type()
+		for fieldIndex := 0; fieldIndex < sectionValue.NumField(); fieldIndex++ {
+			xmlTag := sectionType.Field(fieldIndex).Tag.Get("xml")
+			xmlAttrName, xmlTagOptions, _ := strings.Cut(xmlTag, ",")
+			if xmlAttrName == "" || xmlAttrName == "-" || !strings.Contains(xmlTagOptions, "attr") {
+				continue
+			}
+
+			fieldValue := sectionValue.Field(fieldIndex)
+			if fieldValue.IsZero() {
+				continue
+			}
+
+			xmlAttr, err := marshalXMLAttrValue(fieldValue, xml.Name{Local: xmlAttrName})
+			if err != nil {
+				return nil, err
+			}
+
+			xmlAttrArray = append(xmlAttrArray, xmlAttr)
+		}
+	}
+
+	return xmlAttrArray, nil
+}
+
+func objectMarshalAttrs(apnPointerCore *Object) ([]xml.Attr, error) {
+	xmlAttrArray, err := objectXMLAttrs(apnPointerCore)
+	if err != nil {
+		return nil, err
+	}
+
+	return apnPointerCore.mergeUnknownAttrs(xmlAttrArray), nil
+}
+
+func marshalXMLAttrValue(fieldValue reflect.Value, xmlAttrName xml.Name) (xml.Attr, error) {
+	if marshaler, ok := fieldValue.Interface().(xml.MarshalerAttr); ok {
+		return marshaler.MarshalXMLAttr(xmlAttrName)
+	}
+
+	if fieldValue.Kind() == reflect.Ptr {
+		fieldValue = fieldValue.Elem()
+	}
+
+	switch fieldValue.Kind() {
+	case reflect.String:
+		return xml.Attr{Name: xmlAttrName, Value: fieldValue.String()}, nil
+	case reflect.Int:
+		return xml.Attr{Name: xmlAttrName, Value: strconv.FormatInt(fieldValue.Int(), 10)}, nil
+	case reflect.Bool:
+		return xml.Attr{Name: xmlAttrName, Value: strconv.FormatBool(fieldValue.Bool())}, nil
+	default:
+		return xml.Attr{}, fmt.Errorf("apn xml attribute %q has unsupported kind: %s", xmlAttrName.Local, fieldValue.Kind())
+	}
+}
+
+//--------------------------------------------------------------------------------//
+// Canonical Layout
+//--------------------------------------------------------------------------------//
+
+type CanonicalLayout int
+
+const (
+	CanonicalLayoutElement CanonicalLayout = iota
+	CanonicalLayoutAttribute
+)
+
+func Canonicalize(data []byte, layout CanonicalLayout) ([]byte, error) {
+	return canonicalize(data, layout, true)
+}
+
+func canonicalize(data []byte, layout CanonicalLayout, sortAttrs bool) ([]byte, error) {
+	var (
+		xmlDecoder = xml.NewDecoder(bytes.NewReader(data))
+		writer     = canonicalWriter{layout: layout, sortAttrs: sortAttrs}
+	)
+
+	for {
+		xmlToken, err := xmlDecoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		writer.writeToken(xml.CopyToken(xmlToken))
+	}
+
+	if writer.depth != 0 || writer.pending != nil {
+		return nil, fmt.Errorf("apn xml has unclosed elements")
+	}
+
+	return writer.buffer.Bytes(), nil
+}
+
+func IsCanonical(data []byte, layout CanonicalLayout) (bool, error) {
+	canonicalData, err := Canonicalize(data, layout)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(data, canonicalData), nil
+}
+
+type canonicalWriter struct {
+	buffer    bytes.Buffer
+	layout    CanonicalLayout
+	sortAttrs bool
+	depth     int
+	pending   *xml.StartElement
+	inline    bool
+}
+
+func (writer *canonicalWriter) writeToken(xmlToken xml.Token) {
+	switch xmlElement := xmlToken.(type) {
+	case xml.StartElement:
+		writer.flushPending(false)
+		writer.pending = &xmlElement
+	case xml.EndElement:
+		if writer.pending != nil {
+			writer.flushPending(true)
+			return
+		}
+
+		writer.depth--
+		if !writer.inline {
+			writer.writeIndent(writer.depth)
+		}
+		writer.inline = false
+		fmt.Fprintf(&writer.buffer, "</%s>", canonicalName(xmlElement.Name))
+		writer.writeNewline()
+	case xml.CharData:
+		if len(bytes.TrimSpace(xmlElement)) == 0 {
+			return
+		}
+
+		writer.flushPending(false)
+		if writer.buffer.Len() > 0 && writer.buffer.Bytes()[writer.buffer.Len()-1] == '\n' {
+			writer.buffer.Truncate(writer.buffer.Len() - 1)
+		}
+		_ = xml.EscapeText(&writer.buffer, bytes.TrimSpace(xmlElement))
+		writer.inline = true
+	case xml.Comment:
+		writer.flushPending(false)
+		writer.writeIndent(writer.depth)
+		fmt.Fprintf(&writer.buffer, "<!--%s-->", xmlElement)
+		writer.writeNewline()
+	case xml.ProcInst:
+		writer.flushPending(false)
+		writer.writeIndent(writer.depth)
+		fmt.Fprintf(&writer.buffer, "<?%s %s?>", xmlElement.Target, bytes.TrimSpace(xmlElement.Inst))
+		writer.writeNewline()
+	case xml.Directive:
+		writer.flushPending(false)
+		writer.writeIndent(writer.depth)
+		fmt.Fprintf(&writer.buffer, "<!%s>", xmlElement)
+		writer.writeNewline()
+	}
+}
+
+func (writer *canonicalWriter) flushPending(selfClose bool) {
+	if writer.pending == nil {
+		return
+	}
+
+	xmlStart := *writer.pending
+	writer.pending = nil
+
+	if writer.sortAttrs && xmlStart.Name.Local == "apn" && xmlStart.Name.Space == "" {
+		sortCanonicalAttrs(xmlStart.Attr)
+	}
+
+	writer.writeIndent(writer.depth)
+	fmt.Fprintf(&writer.buffer, "<%s", canonicalName(xmlStart.Name))
+
+	multiline := writer.layout == CanonicalLayoutAttribute && xmlStart.Name.Local == "apn" && len(xmlStart.Attr) > 0
+	for _, xmlAttr := range xmlStart.Attr {
+		if multiline {
+			writer.writeNewline()
+			writer.writeIndent(writer.depth + 1)
+		} else {
+			writer.buffer.WriteByte(' ')
+		}
+		fmt.Fprintf(&writer.buffer, "%s=\"", canonicalName(xmlAttr.Name))
+		_ = xml.EscapeText(&writer.buffer, []byte(xmlAttr.Value))
+		writer.buffer.WriteByte('"')
+	}
+
+	if multiline {
+		writer.writeNewline()
+		writer.writeIndent(writer.depth)
+	} else if selfClose {
+		writer.buffer.WriteByte(' ')
+	}
+
+	if selfClose {
+		writer.buffer.WriteString("/>")
+		writer.writeNewline()
+		return
+	}
+
+	writer.buffer.WriteByte('>')
+	writer.writeNewline()
+	writer.depth++
+}
+
+func (writer *canonicalWriter) writeIndent(depth int) {
+	for index := 0; index < depth; index++ {
+		writer.buffer.WriteByte('\t')
+	}
+}
+
+func (writer *canonicalWriter) writeNewline() {
+	writer.buffer.WriteByte('\n')
+}
+
+func canonicalName(xmlName xml.Name) string {
+	if xmlName.Space == "" {
+		return xmlName.Local
+	}
+
+	return xmlName.Space + ":" + xmlName.Local
+}
+
+//--------------------------------------------------------------------------------//
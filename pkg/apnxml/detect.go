@@ -0,0 +1,93 @@
+package apnxml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+)
+
+//--------------------------------------------------------------------------------//
+// Format Detection
+//--------------------------------------------------------------------------------//
+
+const FormatAuto Format = "auto"
+
+const detectFormatMaxDepth = 4
+
+var (
+	detectUTF8BOM   = []byte{0xef, 0xbb, 0xbf}
+	detectGzipMagic = []byte{0x1f, 0x8b}
+)
+
+func DetectFormat(data []byte) (Format, error) {
+	_, format, err := unwrapContent(data)
+	return format, err
+}
+
+func unwrapContent(data []byte) ([]byte, Format, error) {
+	for depth := 0; depth < detectFormatMaxDepth; depth++ {
+		data = bytes.TrimPrefix(data, detectUTF8BOM)
+
+		if bytes.HasPrefix(data, detectGzipMagic) {
+			var err error
+			data, err = gunzipData(data)
+			if err != nil {
+				return nil, "", err
+			}
+
+			continue
+		}
+
+		trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, detectUTF8BOM))
+		switch {
+		case len(trimmed) == 0:
+			return nil, "", fmt.Errorf("apn payload is empty")
+		case trimmed[0] == '<':
+			return trimmed, FormatXML, nil
+		case trimmed[0] == '{' || trimmed[0] == '[':
+			return trimmed, FormatJSON, nil
+		case isContentQueryDump(trimmed):
+			return trimmed, FormatContentQuery, nil
+		case isDumpsysDump(trimmed):
+			return trimmed, FormatDumpsys, nil
+		case isYAMLDocument(trimmed):
+			return trimmed, FormatYAML, nil
+		}
+
+		decoded, ok := decodeBase64Content(trimmed)
+		if !ok {
+			break
+		}
+		data = decoded
+	}
+
+	return nil, "", fmt.Errorf("unable to detect apn format")
+}
+
+func decodeBase64Content(data []byte) ([]byte, bool) {
+	compact := make([]byte, 0, len(data))
+	for _, symbol := range data {
+		switch {
+		case symbol == ' ' || symbol == '\t' || symbol == '\r' || symbol == '\n':
+			continue
+		case symbol >= 'A' && symbol <= 'Z', symbol >= 'a' && symbol <= 'z', symbol >= '0' && symbol <= '9', symbol == '+', symbol == '/', symbol == '=':
+			compact = append(compact, symbol)
+		default:
+			return nil, false
+		}
+	}
+
+	if len(compact) == 0 || len(compact)%4 != 0 {
+		return nil, false
+	}
+
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(compact)))
+	length, err := base64.StdEncoding.Decode(decoded, compact)
+	if err != nil {
+		return nil, false
+	}
+
+	return decoded[:length], true
+}
+
+//--------------------------------------------------------------------------------//
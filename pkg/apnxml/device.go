@@ -0,0 +1,359 @@
+package apnxml
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------//
+// Device Dumps
+//--------------------------------------------------------------------------------//
+
+const (
+	FormatContentQuery Format = "content-query"
+	FormatDumpsys      Format = "dumpsys"
+)
+
+var (
+	contentQueryRowPrefix    = "Row: "
+	contentQueryColumnRegexp = regexp.MustCompile(`, ([A-Za-z_][A-Za-z0-9_]*)=`)
+	dumpsysApnSettingMarker  = "[ApnSetting"
+)
+
+var contentQueryColumnMap = map[string]string{
+	"name":                 "carrier",
+	"carrier_id":           "carrier_id",
+	"mcc":                  "mcc",
+	"mnc":                  "mnc",
+	"apn":                  "apn",
+	"type":                 "type",
+	"profile_id":           "profile_id",
+	"authtype":             "authtype",
+	"user":                 "user",
+	"password":             "password",
+	"protocol":             "protocol",
+	"roaming_protocol":     "roaming_protocol",
+	"mtu":                  "mtu",
+	"mtu_v4":               "mtu_v4",
+	"mtu_v6":               "mtu_v6",
+	"server":               "server",
+	"proxy":                "proxy",
+	"port":                 "port",
+	"mmsc":                 "mmsc",
+	"mmsproxy":             "mmsproxy",
+	"mmsport":              "mmsport",
+	"mvno_type":            "mvno_type",
+	"mvno_match_data":      "mvno_match_data",
+	"max_conns":            "max_conns",
+	"max_conns_time":       "max_conns_time",
+	"bearer":               "bearer",
+	"modem_cognitive":      "modem_cognitive",
+	"carrier_enabled":      "carrier_enabled",
+	"user_visible":         "user_visible",
+	"user_editable":        "user_editable",
+	"network_type_bitmask": "network_type_bitmask",
+	"bearer_bitmask":       "bearer_bitmask",
+}
+
+var dumpsysNetworkTypeMap = map[string]ObjectNetworkType{
+	"gprs":               ObjectNetworkTypeGPRS,
+	"edge":               ObjectNetworkTypeEDGE,
+	"umts":               ObjectNetworkTypeUMTS,
+	"cdma":               ObjectNetworkTypeCDMA,
+	"cdma - evdo rev. 0": ObjectNetworkTypeEVDO0,
+	"cdma - evdo rev. a": ObjectNetworkTypeEVDOA,
+	"cdma - 1xrtt":       ObjectNetworkType1xRTT,
+	"hsdpa":              ObjectNetworkTypeHSDPA,
+	"hsupa":              ObjectNetworkTypeHSUPA,
+	"hspa":               ObjectNetworkTypeHSPA,
+	"iden":               ObjectNetworkTypeIDEN,
+	"cdma - evdo rev. b": ObjectNetworkTypeEVDOB,
+	"lte":                ObjectNetworkTypeLTE,
+	"cdma - ehrpd":       ObjectNetworkTypeEHRPD,
+	"hspa+":              ObjectNetworkTypeHSPAP,
+	"gsm":                ObjectNetworkTypeGSM,
+	"td_scdma":           ObjectNetworkTypeTDSCDMA,
+	"iwlan":              ObjectNetworkTypeIWLAN,
+	"lte_ca":             ObjectNetworkTypeLTECA,
+	"nr":                 ObjectNetworkTypeNR,
+}
+
+func isContentQueryDump(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(contentQueryRowPrefix)) || bytes.Contains(data, []byte("\n"+contentQueryRowPrefix))
+}
+
+func isDumpsysDump(data []byte) bool {
+	return bytes.Contains(data, []byte(dumpsysApnSettingMarker))
+}
+
+func decodeContentQuery(data []byte, optionList ...DecodeOption) (Array, error) {
+	var recordArray [][]xml.Attr
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, contentQueryRowPrefix) {
+			continue
+		}
+		if xmlAttrArray := parseContentQueryRow(line); len(xmlAttrArray) > 0 {
+			recordArray = append(recordArray, xmlAttrArray)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(recordArray) == 0 {
+		return nil, fmt.Errorf("no content query rows found")
+	}
+
+	return decodeDeviceRecords(recordArray, optionList)
+}
+
+func parseContentQueryRow(line string) []xml.Attr {
+	line = strings.TrimPrefix(line, contentQueryRowPrefix)
+	if _, rest, ok := strings.Cut(line, " "); ok {
+		line = rest
+	}
+
+	columnMap := map[string]string{}
+	for len(line) > 0 {
+		name, rest, ok := strings.Cut(line, "=")
+		if !ok {
+			break
+		}
+
+		value := rest
+		line = ""
+		if location := contentQueryColumnRegexp.FindStringIndex(rest); location != nil {
+			value, line = rest[:location[0]], rest[location[0]+2:]
+		}
+		columnMap[strings.TrimSpace(name)] = value
+	}
+
+	if numeric := deviceValue(columnMap["numeric"]); len(numeric) >= 5 {
+		if deviceValue(columnMap["mcc"]) == "" {
+			columnMap["mcc"] = numeric[:3]
+		}
+		if deviceValue(columnMap["mnc"]) == "" {
+			columnMap["mnc"] = numeric[3:]
+		}
+	}
+	if deviceValue(columnMap["mtu"]) == "" || columnMap["mtu"] == "0" {
+		if mtu := deviceValue(columnMap["mtu_v4"]); mtu != "" {
+			columnMap["mtu"] = mtu
+		}
+	}
+
+	var xmlAttrArray []xml.Attr
+	for column, value := range columnMap {
+		xmlAttrName, ok := contentQueryColumnMap[column]
+		if !ok {
+			continue
+		}
+
+		value = deviceValue(value)
+		if column == "network_type_bitmask" || column == "bearer_bitmask" {
+			value = deviceBitmaskList(value)
+		}
+		if value != "" {
+			xmlAttrArray = append(xmlAttrArray, xml.Attr{Name: xml.Name{Local: xmlAttrName}, Value: value})
+		}
+	}
+
+	return xmlAttrArray
+}
+
+func decodeDumpsys(data []byte, optionList ...DecodeOption) (Array, error) {
+	var (
+		recordArray [][]xml.Attr
+		seenMap     = map[string]bool{}
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		index := strings.Index(line, dumpsysApnSettingMarker)
+		if index < 0 {
+			continue
+		}
+
+		line = strings.TrimSpace(line[index:])
+		if seenMap[line] {
+			continue
+		}
+		seenMap[line] = true
+
+		if xmlAttrArray := parseDumpsysApnSetting(line); len(xmlAttrArray) > 0 {
+			recordArray = append(recordArray, xmlAttrArray)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(recordArray) == 0 {
+		return nil, fmt.Errorf("no ApnSetting records found")
+	}
+
+	return decodeDeviceRecords(recordArray, optionList)
+}
+
+func parseDumpsysApnSetting(line string) []xml.Attr {
+	header, body, ok := strings.Cut(line, "] ")
+	if !ok {
+		return nil
+	}
+	isLegacy := header != dumpsysApnSettingMarker
+
+	fieldArray := strings.Split(body, ", ")
+	index := 1
+	for ; index+1 < len(fieldArray); index++ {
+		if _, err := strconv.Atoi(fieldArray[index]); err != nil {
+			continue
+		}
+		if numeric := fieldArray[index+1]; (len(numeric) == 5 || len(numeric) == 6) && strings.Trim(numeric, "0123456789") == "" {
+			break
+		}
+	}
+	if index+1 >= len(fieldArray) {
+		return nil
+	}
+
+	var xmlAttrArray []xml.Attr
+	addAttr := func(xmlAttrName string, value string) {
+		if value = deviceValue(value); value != "" {
+			xmlAttrArray = append(xmlAttrArray, xml.Attr{Name: xml.Name{Local: xmlAttrName}, Value: value})
+		}
+	}
+	fieldAt := func(offset int) string {
+		if index+offset < len(fieldArray) {
+			return fieldArray[index+offset]
+		}
+		return ""
+	}
+
+	numeric := fieldAt(1)
+	addAttr("carrier", strings.Join(fieldArray[:index], ", "))
+	addAttr("mcc", numeric[:3])
+	addAttr("mnc", numeric[3:])
+	addAttr("apn", fieldAt(2))
+	addAttr("proxy", fieldAt(3))
+	addAttr("mmsc", fieldAt(4))
+	addAttr("mmsproxy", fieldAt(5))
+	addAttr("mmsport", fieldAt(6))
+	addAttr("port", fieldAt(7))
+	addAttr("authtype", fieldAt(8))
+	addAttr("type", strings.Join(strings.Fields(strings.ReplaceAll(fieldAt(9), "|", " ")), ","))
+	addAttr("protocol", fieldAt(10))
+	addAttr("roaming_protocol", fieldAt(11))
+	addAttr("carrier_enabled", fieldAt(12))
+	addAttr("profile_id", fieldAt(13))
+	addAttr("modem_cognitive", fieldAt(14))
+	addAttr("max_conns", fieldAt(15))
+	addAttr("max_conns_time", fieldAt(17))
+	addAttr("mtu", fieldAt(18))
+
+	offset := 19
+	if !isLegacy {
+		offset++
+	}
+	addAttr("mvno_type", fieldAt(offset))
+	addAttr("mvno_match_data", fieldAt(offset+1))
+	addAttr("network_type_bitmask", dumpsysNetworkTypeList(fieldAt(offset+3)))
+	if isLegacy {
+		addAttr("carrier_id", fieldAt(offset+5))
+	} else {
+		addAttr("carrier_id", fieldAt(offset+6))
+	}
+
+	return xmlAttrArray
+}
+
+func dumpsysNetworkTypeList(value string) string {
+	if _, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		return deviceBitmaskList(value)
+	}
+
+	var networkTypeValue ObjectNetworkType
+	for _, name := range strings.Split(value, "|") {
+		networkTypeValue |= dumpsysNetworkTypeMap[strings.ToLower(strings.TrimSpace(name))]
+	}
+	if networkTypeValue == ObjectNetworkTypeNone {
+		return ""
+	}
+
+	return deviceBitmaskList(strconv.Itoa(int(networkTypeValue)))
+}
+
+func deviceValue(value string) string {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "NULL", "null", "-1":
+		return ""
+	}
+
+	return value
+}
+
+func deviceBitmaskList(value string) string {
+	bitmask, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || bitmask <= 0 {
+		return ""
+	}
+
+	var indexArray []string
+	for index := 1; bitmask != 0; index++ {
+		if bitmask&1 == 1 {
+			indexArray = append(indexArray, strconv.Itoa(index))
+		}
+		bitmask >>= 1
+	}
+
+	return strings.Join(indexArray, "|")
+}
+
+func decodeDeviceRecords(recordArray [][]xml.Attr, optionList []DecodeOption) (Array, error) {
+	var buffer bytes.Buffer
+
+	xmlEncoder := xml.NewEncoder(&buffer)
+	xmlRoot := xml.StartElement{Name: xml.Name{Local: "apns"}}
+	if err := xmlEncoder.EncodeToken(xmlRoot); err != nil {
+		return nil, err
+	}
+	for _, xmlAttrArray := range recordArray {
+		sortCanonicalAttrs(xmlAttrArray)
+
+		xmlStart := xml.StartElement{Name: xml.Name{Local: "apn"}, Attr: xmlAttrArray}
+		if err := xmlEncoder.EncodeToken(xmlStart); err != nil {
+			return nil, err
+		}
+		if err := xmlEncoder.EncodeToken(xmlStart.End()); err != nil {
+			return nil, err
+		}
+	}
+	if err := xmlEncoder.EncodeToken(xmlRoot.End()); err != nil {
+		return nil, err
+	}
+	if err := xmlEncoder.Flush(); err != nil {
+		return nil, err
+	}
+
+	var (
+		records Array
+		options = newDecodeOptions(optionList)
+	)
+	options.preserveDocument = false
+	if err := xml.Unmarshal(buffer.Bytes(), &xmlArrayCodec{array: &records, decodeOptions: options}); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+//--------------------------------------------------------------------------------//
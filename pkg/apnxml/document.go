@@ -0,0 +1,691 @@
+package apnxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+//--------------------------------------------------------------------------------//
+// Decode & Encode Options
+//--------------------------------------------------------------------------------//
+
+type DecodeOption func(*decodeOptions)
+
+type decodeOptions struct {
+	preserveDocument bool
+	lenient          bool
+	report           *DecodeReport
+}
+
+type DecodeReport struct {
+	Version  string
+	Warnings []DecodeWarning
+}
+
+func WithPreserveDocument() DecodeOption {
+	return func(options *decodeOptions) {
+		options.preserveDocument = true
+	}
+}
+
+func WithDecodeReport(report *DecodeReport) DecodeOption {
+	return func(options *decodeOptions) {
+		options.report = report
+	}
+}
+
+func (options decodeOptions) recordRoot(xmlStart xml.StartElement) string {
+	var version string
+	for _, xmlAttr := range xmlStart.Attr {
+		if xmlAttr.Name.Local == "version" {
+			version = xmlAttr.Value
+		}
+	}
+
+	if options.report != nil && options.report.Version == "" {
+		options.report.Version = version
+	}
+
+	return version
+}
+
+func newDecodeOptions(optionList []DecodeOption) decodeOptions {
+	var options decodeOptions
+	for _, option := range optionList {
+		if option != nil {
+			option(&options)
+		}
+	}
+
+	return options
+}
+
+type EncodeOption func(*encodeOptions)
+
+type encodeOptions struct {
+	canonical      bool
+	layout         CanonicalLayout
+	profile        ExportProfile
+	version        string
+	secretResolver SecretResolver
+}
+
+func WithCanonicalLayout(layout CanonicalLayout) EncodeOption {
+	return func(options *encodeOptions) {
+		options.canonical = true
+		options.layout = layout
+	}
+}
+
+func WithExportProfile(profile ExportProfile) EncodeOption {
+	return func(options *encodeOptions) {
+		options.profile = profile
+	}
+}
+
+func WithXMLVersion(version string) EncodeOption {
+	return func(options *encodeOptions) {
+		options.version = version
+	}
+}
+
+func newEncodeOptions(optionList []EncodeOption) encodeOptions {
+	var options encodeOptions
+	for _, option := range optionList {
+		if option != nil {
+			option(&options)
+		}
+	}
+
+	return options
+}
+
+//--------------------------------------------------------------------------------//
+// Object Source
+//--------------------------------------------------------------------------------//
+
+type ObjectSource struct {
+	Index    int
+	Version  string
+	Comments []string
+	Attrs    []xml.Attr
+	Prolog   []xml.Token
+	Trailing []string
+
+	decoded  map[string]string
+	document *sourceDocument
+	before   []byte
+	raw      []byte
+}
+
+type sourceDocument struct {
+	head []byte
+	root []byte
+	tail []byte
+}
+
+func (apnPointerSource *ObjectSource) Clone() *ObjectSource {
+	if apnPointerSource == nil {
+		return nil
+	}
+
+	apnSource := ObjectSource{
+		Index:    apnPointerSource.Index,
+		Version:  apnPointerSource.Version,
+		Comments: append([]string(nil), apnPointerSource.Comments...),
+		Attrs:    append([]xml.Attr(nil), apnPointerSource.Attrs...),
+		Trailing: append([]string(nil), apnPointerSource.Trailing...),
+		document: apnPointerSource.document,
+		before:   append([]byte(nil), apnPointerSource.before...),
+		raw:      append([]byte(nil), apnPointerSource.raw...),
+	}
+
+	for _, xmlToken := range apnPointerSource.Prolog {
+		apnSource.Prolog = append(apnSource.Prolog, xml.CopyToken(xmlToken))
+	}
+
+	if apnPointerSource.decoded != nil {
+		apnSource.decoded = make(map[string]string, len(apnPointerSource.decoded))
+		for xmlAttrName, xmlAttrValue := range apnPointerSource.decoded {
+			apnSource.decoded[xmlAttrName] = xmlAttrValue
+		}
+	}
+
+	return &apnSource
+}
+
+func (apnPointerSource *ObjectSource) restoreAttrs(xmlAttrArray []xml.Attr) []xml.Attr {
+	var (
+		currentMap = make(map[string]string, len(xmlAttrArray))
+		usedMap    = map[string]bool{}
+		result     []xml.Attr
+		added      []xml.Attr
+	)
+
+	for _, xmlAttr := range xmlAttrArray {
+		currentMap[xmlAttr.Name.Local] = xmlAttr.Value
+	}
+
+	for _, xmlAttr := range apnPointerSource.Attrs {
+		xmlAttrName := xmlAttr.Name.Local
+		if usedMap[xmlAttrName] {
+			continue
+		}
+		usedMap[xmlAttrName] = true
+
+		currentValue, isCurrent := currentMap[xmlAttrName]
+		decodedValue, isDecoded := apnPointerSource.decoded[xmlAttrName]
+
+		switch {
+		case isCurrent && isDecoded && currentValue == decodedValue:
+			result = append(result, xmlAttr)
+		case isCurrent:
+			result = append(result, xml.Attr{Name: xmlAttr.Name, Value: currentValue})
+		case !isDecoded:
+			result = append(result, xmlAttr)
+		}
+	}
+
+	for _, xmlAttr := range xmlAttrArray {
+		if usedMap[xmlAttr.Name.Local] {
+			continue
+		}
+
+		if decodedValue, ok := apnPointerSource.decoded[xmlAttr.Name.Local]; ok && decodedValue == xmlAttr.Value {
+			continue
+		}
+
+		added = append(added, xmlAttr)
+	}
+
+	sortCanonicalAttrs(added)
+	return append(result, added...)
+}
+
+func newObjectSource(apnPointer *Object, index int, comments []string, xmlAttrArray []xml.Attr) (*ObjectSource, error) {
+	decodedAttrArray, err := objectMarshalAttrs(apnPointer)
+	if err != nil {
+		return nil, err
+	}
+
+	apnSource := ObjectSource{
+		Index:    index,
+		Comments: comments,
+		Attrs:    append([]xml.Attr(nil), xmlAttrArray...),
+		decoded:  make(map[string]string, len(decodedAttrArray)),
+	}
+
+	for _, xmlAttr := range decodedAttrArray {
+		apnSource.decoded[xmlAttr.Name.Local] = xmlAttr.Value
+	}
+
+	return &apnSource, nil
+}
+
+//--------------------------------------------------------------------------------//
+// Document Decode
+//--------------------------------------------------------------------------------//
+
+func decodeXMLDocument(data []byte, options decodeOptions) (Array, error) {
+	var (
+		xmlDecoder = xml.NewDecoder(bytes.NewReader(data))
+		prolog     []xml.Token
+	)
+
+	for {
+		xmlTokenStart := xmlDecoder.InputOffset()
+		xmlDecoderToken, err := xmlDecoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("apn xml has no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch xmlDecoderElement := xmlDecoderToken.(type) {
+		case xml.ProcInst, xml.Comment, xml.Directive:
+			prolog = append(prolog, xml.CopyToken(xmlDecoderElement))
+		case xml.StartElement:
+			document := &sourceDocument{
+				head: append([]byte(nil), data[:xmlTokenStart]...),
+				root: append([]byte(nil), data[xmlTokenStart:xmlDecoder.InputOffset()]...),
+			}
+
+			apnArray, err := unmarshalXMLDocument(xmlDecoder, xmlDecoderElement, options, data, document)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(apnArray) > 0 && len(prolog) > 0 {
+				apnArray[0].Source.Prolog = prolog
+			}
+
+			return apnArray, nil
+		}
+	}
+}
+
+func unmarshalXMLDocument(xmlDecoder *xml.Decoder, xmlStart xml.StartElement, options decodeOptions, data []byte, document *sourceDocument) (Array, error) {
+	var (
+		apnArray    Array
+		comments    []string
+		previousEnd = xmlDecoder.InputOffset()
+	)
+
+	if xmlStart.Name.Local != "apns" {
+		return nil, fmt.Errorf("apn xml has incorrect root element: %q", xmlStart.Name.Local)
+	}
+
+	version := options.recordRoot(xmlStart)
+
+	for {
+		xmlTokenStart := xmlDecoder.InputOffset()
+		xmlDecoderToken, err := xmlDecoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch xmlDecoderElement := xmlDecoderToken.(type) {
+		case xml.Comment:
+			comments = append(comments, string(xmlDecoderElement))
+		case xml.StartElement:
+			if xmlDecoderElement.Name.Local != "apn" {
+				if err := xmlDecoder.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			var apnObject Object
+			if err := apnObject.unmarshalXML(xmlDecoder, xmlDecoderElement, options, len(apnArray)); err != nil {
+				return nil, err
+			}
+
+			apnObject.Source, err = newObjectSource(&apnObject, len(apnArray), comments, xmlDecoderElement.Attr)
+			if err != nil {
+				return nil, err
+			}
+			apnObject.Source.Version = version
+			apnObject.Source.document = document
+			apnObject.Source.before = append([]byte(nil), data[previousEnd:xmlTokenStart]...)
+			apnObject.Source.raw = append([]byte(nil), data[xmlTokenStart:xmlDecoder.InputOffset()]...)
+			previousEnd = xmlDecoder.InputOffset()
+			comments = nil
+
+			apnArray = append(apnArray, apnObject)
+		case xml.EndElement:
+			if xmlDecoderElement.Name == xmlStart.Name {
+				if len(comments) > 0 && len(apnArray) > 0 {
+					apnArray[len(apnArray)-1].Source.Trailing = comments
+				}
+				document.tail = append([]byte(nil), data[previousEnd:]...)
+
+				return apnArray, nil
+			}
+		}
+	}
+}
+
+//--------------------------------------------------------------------------------//
+// Document Encode
+//--------------------------------------------------------------------------------//
+
+func (apnArray Array) hasSource() bool {
+	for index := range apnArray {
+		for _, apnPointer := range apnArray[index].Records() {
+			if apnPointer.Source != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (apnArray Array) documentRecords() []*Object {
+	var apnPointerArray []*Object
+
+	for index := range apnArray {
+		apnPointerGroup := &apnArray[index]
+		for _, apnPointer := range apnPointerGroup.Records() {
+			apnPointerRecord := apnPointer.NormalizedClone()
+			if apnPointerGroup.HasGroup() {
+				apnPointerRecord.ObjectRoot = apnPointerGroup.ObjectRoot.Clone()
+			}
+
+			apnPointerArray = append(apnPointerArray, apnPointerRecord)
+		}
+	}
+
+	sort.SliceStable(apnPointerArray, func(i, j int) bool {
+		sourceI, sourceJ := apnPointerArray[i].Source, apnPointerArray[j].Source
+		if sourceI == nil || sourceJ == nil {
+			return sourceI != nil
+		}
+
+		return sourceI.Index < sourceJ.Index
+	})
+
+	return apnPointerArray
+}
+
+func (apnArray Array) sourceDocument() *sourceDocument {
+	for index := range apnArray {
+		for _, apnPointer := range apnArray[index].Records() {
+			if apnPointer.Source != nil && apnPointer.Source.document != nil {
+				return apnPointer.Source.document
+			}
+		}
+	}
+
+	return nil
+}
+
+func (apnArray Array) sourceVersion() string {
+	for index := range apnArray {
+		for _, apnPointer := range apnArray[index].Records() {
+			if apnPointer.Source != nil && apnPointer.Source.Version != "" {
+				return apnPointer.Source.Version
+			}
+		}
+	}
+
+	return ""
+}
+
+func (apnArray Array) marshalXMLDocument(xmlEncoder *xml.Encoder, xmlStart xml.StartElement, options encodeOptions) error {
+	var (
+		apnPointerArray = apnArray.documentRecords()
+		prolog          []xml.Token
+		trailing        []string
+	)
+
+	for _, apnPointer := range apnPointerArray {
+		if apnPointer.Source == nil {
+			continue
+		}
+		if prolog == nil {
+			prolog = apnPointer.Source.Prolog
+		}
+		if len(apnPointer.Source.Trailing) > 0 {
+			trailing = apnPointer.Source.Trailing
+		}
+	}
+
+	for _, xmlToken := range prolog {
+		if err := xmlEncoder.EncodeToken(xmlToken); err != nil {
+			return err
+		}
+	}
+
+	if err := xmlEncoder.EncodeToken(xmlStart); err != nil {
+		return err
+	}
+
+	for _, apnPointer := range apnPointerArray {
+		if !options.profile.acceptsObject(apnPointer) {
+			continue
+		}
+
+		if apnPointer.Source != nil {
+			for _, comment := range apnPointer.Source.Comments {
+				if err := xmlEncoder.EncodeToken(xml.Comment(comment)); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := apnPointer.marshalXML(xmlEncoder, xml.StartElement{Name: xml.Name{Local: "apn"}}, options); err != nil {
+			return err
+		}
+	}
+
+	for _, comment := range trailing {
+		if err := xmlEncoder.EncodeToken(xml.Comment(comment)); err != nil {
+			return err
+		}
+	}
+
+	if err := xmlEncoder.EncodeToken(xmlStart.End()); err != nil {
+		return err
+	}
+
+	return xmlEncoder.Flush()
+}
+
+func (apnArray Array) encodeXMLDocument(document *sourceDocument, options encodeOptions) ([]byte, error) {
+	var (
+		buffer          bytes.Buffer
+		apnPointerArray = apnArray.documentRecords()
+		indent          = []byte("\n\t")
+	)
+
+	sort.SliceStable(apnPointerArray, func(i, j int) bool {
+		return apnPointerArray[i].Source.hasDocument(document) && !apnPointerArray[j].Source.hasDocument(document)
+	})
+
+	xmlRoot, err := rewriteRootVersion(document.root, options.xmlVersion(apnArray.sourceVersion()))
+	if err != nil {
+		return nil, err
+	}
+	buffer.Write(document.head)
+	buffer.Write(xmlRoot)
+
+	for _, apnPointer := range apnPointerArray {
+		if !options.profile.acceptsObject(apnPointer) {
+			continue
+		}
+
+		xmlAttrArray, err := apnPointer.marshalAttrs(options)
+		if err != nil {
+			return nil, err
+		}
+
+		if !apnPointer.Source.hasDocument(document) {
+			buffer.Write(indent)
+			writeStartTag(&buffer, "apn", xmlAttrArray)
+			continue
+		}
+
+		xmlRaw, err := rewriteStartTag(apnPointer.Source.raw, xmlAttrArray)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(apnPointer.Source.before)
+		buffer.Write(xmlRaw)
+
+		if space := trailingSpace(apnPointer.Source.before); len(space) > 0 {
+			indent = space
+		}
+	}
+
+	buffer.Write(document.tail)
+	return buffer.Bytes(), nil
+}
+
+func (apnPointerSource *ObjectSource) hasDocument(document *sourceDocument) bool {
+	return apnPointerSource != nil && apnPointerSource.document == document && apnPointerSource.raw != nil
+}
+
+func trailingSpace(data []byte) []byte {
+	index := len(data)
+	for index > 0 && isSpaceByte(data[index-1]) {
+		index--
+	}
+
+	return data[index:]
+}
+
+func writeStartTag(buffer *bytes.Buffer, name string, xmlAttrArray []xml.Attr) {
+	fmt.Fprintf(buffer, "<%s", name)
+	for _, xmlAttr := range xmlAttrArray {
+		fmt.Fprintf(buffer, " %s=\"", canonicalName(xmlAttr.Name))
+		_ = xml.EscapeText(buffer, []byte(xmlAttr.Value))
+		buffer.WriteByte('"')
+	}
+	buffer.WriteString(" />")
+}
+
+//--------------------------------------------------------------------------------//
+// Raw Start Tag
+//--------------------------------------------------------------------------------//
+
+type rawAttr struct {
+	xmlAttr xml.Attr
+	start   int
+	name    int
+	value   int
+	end     int
+}
+
+func scanStartTag(data []byte) ([]rawAttr, int, error) {
+	xmlToken, err := xml.NewDecoder(bytes.NewReader(data)).RawToken()
+	if err != nil {
+		return nil, 0, err
+	}
+	xmlStart, ok := xmlToken.(xml.StartElement)
+	if !ok {
+		return nil, 0, fmt.Errorf("apn xml has no start tag")
+	}
+
+	var (
+		rawAttrArray []rawAttr
+		index        = 1
+	)
+
+	for index < len(data) && !isSpaceByte(data[index]) && data[index] != '/' && data[index] != '>' {
+		index++
+	}
+
+	for {
+		start := index
+		for index < len(data) && isSpaceByte(data[index]) {
+			index++
+		}
+		if index >= len(data) || data[index] == '/' || data[index] == '>' {
+			if len(rawAttrArray) != len(xmlStart.Attr) {
+				return nil, 0, fmt.Errorf("apn xml has malformed start tag %q", data)
+			}
+
+			return rawAttrArray, start, nil
+		}
+		if len(rawAttrArray) == len(xmlStart.Attr) {
+			return nil, 0, fmt.Errorf("apn xml has malformed start tag %q", data)
+		}
+
+		attr := rawAttr{xmlAttr: xmlStart.Attr[len(rawAttrArray)], start: start, name: index}
+		for index < len(data) && data[index] != '"' && data[index] != '\'' {
+			index++
+		}
+		if index >= len(data) {
+			return nil, 0, fmt.Errorf("apn xml has malformed start tag %q", data)
+		}
+
+		quote := data[index]
+		index++
+		attr.value = index
+		for index < len(data) && data[index] != quote {
+			index++
+		}
+		if index >= len(data) {
+			return nil, 0, fmt.Errorf("apn xml has malformed start tag %q", data)
+		}
+
+		index++
+		attr.end = index
+		rawAttrArray = append(rawAttrArray, attr)
+	}
+}
+
+func rewriteStartTag(data []byte, xmlAttrArray []xml.Attr) ([]byte, error) {
+	rawAttrArray, tagEnd, err := scanStartTag(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		buffer    bytes.Buffer
+		valueMap  = make(map[string]string, len(xmlAttrArray))
+		usedMap   = map[string]bool{}
+		separator = []byte(" ")
+		nameEnd   = tagEnd
+	)
+
+	for _, xmlAttr := range xmlAttrArray {
+		valueMap[canonicalName(xmlAttr.Name)] = xmlAttr.Value
+	}
+	if len(rawAttrArray) > 0 {
+		nameEnd = rawAttrArray[0].start
+	}
+	buffer.Write(data[:nameEnd])
+
+	for _, attr := range rawAttrArray {
+		name := canonicalName(attr.xmlAttr.Name)
+		value, ok := valueMap[name]
+		if !ok || usedMap[name] {
+			continue
+		}
+		usedMap[name] = true
+		separator = data[attr.start:attr.name]
+
+		if value == attr.xmlAttr.Value {
+			buffer.Write(data[attr.start:attr.end])
+			continue
+		}
+
+		buffer.Write(data[attr.start:attr.value])
+		_ = xml.EscapeText(&buffer, []byte(value))
+		buffer.WriteByte(data[attr.end-1])
+	}
+
+	for _, xmlAttr := range xmlAttrArray {
+		name := canonicalName(xmlAttr.Name)
+		if usedMap[name] {
+			continue
+		}
+		usedMap[name] = true
+
+		buffer.Write(separator)
+		fmt.Fprintf(&buffer, "%s=\"", name)
+		_ = xml.EscapeText(&buffer, []byte(xmlAttr.Value))
+		buffer.WriteByte('"')
+	}
+
+	buffer.Write(data[tagEnd:])
+	return buffer.Bytes(), nil
+}
+
+func rewriteRootVersion(data []byte, version string) ([]byte, error) {
+	rawAttrArray, _, err := scanStartTag(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		xmlAttrArray = make([]xml.Attr, 0, len(rawAttrArray)+1)
+		hasVersion   bool
+	)
+
+	for _, attr := range rawAttrArray {
+		xmlAttr := attr.xmlAttr
+		if xmlAttr.Name.Space == "" && xmlAttr.Name.Local == "version" {
+			xmlAttr.Value = version
+			hasVersion = true
+		}
+		xmlAttrArray = append(xmlAttrArray, xmlAttr)
+	}
+	if !hasVersion {
+		xmlAttrArray = append(xmlAttrArray, xml.Attr{Name: xml.Name{Local: "version"}, Value: version})
+	}
+
+	return rewriteStartTag(data, xmlAttrArray)
+}
+
+func isSpaceByte(value byte) bool {
+	return value == ' ' || value == '\t' || value == '\n' || value == '\r'
+}
+
+//--------------------------------------------------------------------------------//
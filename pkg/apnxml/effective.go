@@ -0,0 +1,93 @@
+package apnxml
+
+//--------------------------------------------------------------------------------//
+// Effective Values
+//--------------------------------------------------------------------------------//
+
+func setDefaultValue[Type any](target **Type, value Type) {
+	if *target == nil {
+		*target = &value
+	}
+}
+
+func hasEffectiveCredentials(apnPointerAuth *ObjectAuth) bool {
+	return (apnPointerAuth.Username != nil && *apnPointerAuth.Username != "") ||
+		(apnPointerAuth.Password != nil && *apnPointerAuth.Password != "")
+}
+
+func (apnPointerCore *Object) applyEffectiveDefaults(profile ExportProfile) {
+	if apnPointerCore.Base == nil {
+		apnPointerCore.Base = &ObjectBase{}
+	}
+	setDefaultValue(&apnPointerCore.Base.ProfileID, 0)
+	if apnPointerCore.Base.Type != nil && profile.SupportedTypes != ObjectBaseTypeNone {
+		apnType := *apnPointerCore.Base.Type & profile.supportedTypes()
+		apnPointerCore.Base.Type = &apnType
+	}
+
+	if apnPointerCore.Auth == nil {
+		apnPointerCore.Auth = &ObjectAuth{}
+	}
+	if hasEffectiveCredentials(apnPointerCore.Auth) {
+		setDefaultValue(&apnPointerCore.Auth.Type, ObjectAuthTypePAP|ObjectAuthTypeCHAP)
+	} else {
+		setDefaultValue(&apnPointerCore.Auth.Type, ObjectAuthTypeNone)
+	}
+
+	if apnPointerCore.Bearer == nil {
+		apnPointerCore.Bearer = &ObjectBearer{}
+	}
+	setDefaultValue(&apnPointerCore.Bearer.Type, ObjectBearerProtocolIP)
+	setDefaultValue(&apnPointerCore.Bearer.TypeRoaming, ObjectBearerProtocolIP)
+	setDefaultValue(&apnPointerCore.Bearer.Mtu, 0)
+
+	if apnPointerCore.Limit == nil {
+		apnPointerCore.Limit = &ObjectLimit{}
+	}
+	setDefaultValue(&apnPointerCore.Limit.MaxConn, 0)
+	setDefaultValue(&apnPointerCore.Limit.MaxConnTime, 0)
+
+	if apnPointerCore.Other == nil {
+		apnPointerCore.Other = &ObjectOther{}
+	}
+	setDefaultValue(&apnPointerCore.Other.ModemCognitive, false)
+	setDefaultValue(&apnPointerCore.Other.CarrierEnabled, true)
+	setDefaultValue(&apnPointerCore.Other.UserVisible, true)
+	setDefaultValue(&apnPointerCore.Other.UserEditable, true)
+}
+
+func (apnPointerCore *Object) Effective() *Object {
+	return apnPointerCore.EffectiveFor(ExportProfile{})
+}
+
+func (apnPointerCore *Object) EffectiveFor(profile ExportProfile) *Object {
+	apnPointerClone := apnPointerCore.Clone()
+	if apnPointerClone == nil {
+		return nil
+	}
+
+	for _, apnPointer := range apnPointerClone.Records() {
+		apnPointer.applyEffectiveDefaults(profile)
+	}
+
+	return apnPointerClone
+}
+
+func (apnArray Array) Effective() Array {
+	return apnArray.EffectiveFor(ExportProfile{})
+}
+
+func (apnArray Array) EffectiveFor(profile ExportProfile) Array {
+	if apnArray == nil {
+		return nil
+	}
+
+	apnArrayClone := make(Array, 0, len(apnArray))
+	for index := range apnArray {
+		apnArrayClone = append(apnArrayClone, *apnArray[index].EffectiveFor(profile))
+	}
+
+	return apnArrayClone
+}
+
+//--------------------------------------------------------------------------------//
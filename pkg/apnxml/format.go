@@ -0,0 +1,223 @@
+package apnxml
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//--------------------------------------------------------------------------------//
+// Format Registry
+//--------------------------------------------------------------------------------//
+
+type Decoder func(data []byte, optionList ...DecodeOption) (Array, error)
+
+type Encoder func(apnArray Array, optionList ...EncodeOption) ([]byte, error)
+
+type formatCodec struct {
+	name       Format
+	extensions []string
+	decoder    Decoder
+	encoder    Encoder
+}
+
+var (
+	formatRegistryMutex   sync.RWMutex
+	formatRegistryMap     = map[Format]formatCodec{}
+	formatExtensionMap    = map[string]Format{}
+	formatRegistryBuiltin = []formatCodec{
+		{name: FormatJSON, extensions: []string{".json"}, decoder: decodeJSON, encoder: encodeJSON},
+		{name: FormatXML, extensions: []string{".xml"}, decoder: decodeXML, encoder: encodeXML},
+		{name: FormatContentQuery, extensions: []string{".content.txt"}, decoder: decodeContentQuery},
+		{name: FormatDumpsys, extensions: []string{".dumpsys.txt"}, decoder: decodeDumpsys},
+		{name: FormatYAML, extensions: []string{".yaml", ".yml"}, decoder: decodeYAML, encoder: encodeYAML},
+	}
+)
+
+func init() {
+	for _, codec := range formatRegistryBuiltin {
+		if _, err := RegisterFormat(string(codec.name), codec.extensions, codec.decoder, codec.encoder); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func normalizeFormatExtension(extension string) string {
+	extension = strings.ToLower(strings.TrimSpace(extension))
+	if extension != "" && !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+
+	return extension
+}
+
+func RegisterFormat(name string, extensions []string, decoder Decoder, encoder Encoder) (Format, error) {
+	format := Format(strings.ToLower(strings.TrimSpace(name)))
+	if format == "" {
+		return "", fmt.Errorf("apn format name is empty")
+	}
+	if decoder == nil && encoder == nil {
+		return "", fmt.Errorf("apn format %s has no decoder and no encoder", format)
+	}
+
+	formatRegistryMutex.Lock()
+	defer formatRegistryMutex.Unlock()
+
+	if _, ok := formatRegistryMap[format]; ok {
+		return "", fmt.Errorf("apn format is already registered: %s", format)
+	}
+
+	codec := formatCodec{name: format, decoder: decoder, encoder: encoder}
+	for _, extension := range extensions {
+		extension = normalizeFormatExtension(extension)
+		if extension == "" {
+			continue
+		}
+		if owner, ok := formatExtensionMap[extension]; ok {
+			return "", fmt.Errorf("apn file extension %s is already registered by format %s", extension, owner)
+		}
+
+		codec.extensions = append(codec.extensions, extension)
+	}
+
+	for _, extension := range codec.extensions {
+		formatExtensionMap[extension] = format
+	}
+	formatRegistryMap[format] = codec
+
+	return format, nil
+}
+
+func Formats() []Format {
+	formatRegistryMutex.RLock()
+	defer formatRegistryMutex.RUnlock()
+
+	formatArray := make([]Format, 0, len(formatRegistryMap))
+	for format := range formatRegistryMap {
+		formatArray = append(formatArray, format)
+	}
+
+	sort.Slice(formatArray, func(i, j int) bool {
+		return formatArray[i] < formatArray[j]
+	})
+
+	return formatArray
+}
+
+func lookupFormat(format Format) (formatCodec, bool) {
+	formatRegistryMutex.RLock()
+	defer formatRegistryMutex.RUnlock()
+
+	codec, ok := formatRegistryMap[Format(strings.ToLower(string(format)))]
+	return codec, ok
+}
+
+func (format Format) CanDecode() bool {
+	codec, ok := lookupFormat(format)
+	return ok && codec.decoder != nil
+}
+
+func (format Format) CanEncode() bool {
+	codec, ok := lookupFormat(format)
+	return ok && codec.encoder != nil
+}
+
+func (format Format) Extensions() []string {
+	codec, _ := lookupFormat(format)
+	return append([]string(nil), codec.extensions...)
+}
+
+func FormatFromFilename(filename string) (Format, error) {
+	filename, _ = splitArchiveFilename(filename)
+
+	var (
+		lowerFilename = strings.ToLower(filename)
+		result        Format
+		resultLength  int
+	)
+
+	formatRegistryMutex.RLock()
+	for extension, format := range formatExtensionMap {
+		if strings.HasSuffix(lowerFilename, extension) && len(extension) > resultLength {
+			result, resultLength = format, len(extension)
+		}
+	}
+	formatRegistryMutex.RUnlock()
+
+	if result == "" {
+		return "", fmt.Errorf("unsupported apn file extension: %s", filepath.Ext(filename))
+	}
+
+	return result, nil
+}
+
+//--------------------------------------------------------------------------------//
+// Builtin Codec
+//--------------------------------------------------------------------------------//
+
+func decodeJSON(data []byte, optionList ...DecodeOption) (Array, error) {
+	var (
+		records Array
+		options = newDecodeOptions(optionList)
+	)
+
+	if options.lenient {
+		var err error
+		if data, err = lenientJSON(data, options); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func decodeXML(data []byte, optionList ...DecodeOption) (Array, error) {
+	var (
+		records Array
+		options = newDecodeOptions(optionList)
+	)
+
+	if options.preserveDocument {
+		return decodeXMLDocument(data, options)
+	}
+	if err := xml.Unmarshal(data, &xmlArrayCodec{array: &records, decodeOptions: options}); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func encodeJSON(apnArray Array, _ ...EncodeOption) ([]byte, error) {
+	return json.MarshalIndent(apnArray, "", "\t")
+}
+
+func encodeXML(apnArray Array, optionList ...EncodeOption) ([]byte, error) {
+	options := newEncodeOptions(optionList)
+
+	if document := apnArray.sourceDocument(); document != nil {
+		return apnArray.encodeXMLDocument(document, options)
+	}
+
+	data, err := xml.MarshalIndent(xmlArrayCodec{array: &apnArray, encodeOptions: options}, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	if apnArray.hasSource() {
+		return canonicalize(data, options.layout, false)
+	}
+	if options.canonical {
+		return Canonicalize(data, options.layout)
+	}
+
+	return data, nil
+}
+
+//--------------------------------------------------------------------------------//
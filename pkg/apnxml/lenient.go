@@ -0,0 +1,357 @@
+package apnxml
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------//
+// Decode Warning
+//--------------------------------------------------------------------------------//
+
+type DecodeWarning struct {
+	Record  int
+	Attr    string
+	Token   string
+	Message string
+}
+
+func (warning DecodeWarning) String() string {
+	if warning.Message != "" {
+		return fmt.Sprintf("apn %d: %s %q %s", warning.Record, warning.Attr, warning.Token, warning.Message)
+	}
+
+	return fmt.Sprintf("apn %d: unknown %s token %q", warning.Record, warning.Attr, warning.Token)
+}
+
+func WithLenientDecode() DecodeOption {
+	return func(options *decodeOptions) {
+		options.lenient = true
+	}
+}
+
+func (options decodeOptions) warn(index int, xmlAttrName string, token string) {
+	options.report.add(DecodeWarning{Record: index, Attr: xmlAttrName, Token: token})
+}
+
+func (report *DecodeReport) add(warning DecodeWarning) {
+	if report != nil {
+		report.Warnings = append(report.Warnings, warning)
+	}
+}
+
+//--------------------------------------------------------------------------------//
+// Unknown Enum Tokens
+//--------------------------------------------------------------------------------//
+
+type lenientEnumAttr struct {
+	separator   string
+	jsonIsArray bool
+	noneValue   func() string
+	isKnown     func(string) bool
+	isKnownJSON func(string) bool
+}
+
+func newLenientEnumAttr[Type ~int](codec *enumCodec[Type], separator string) lenientEnumAttr {
+	return lenientEnumAttr{
+		separator:   separator,
+		jsonIsArray: codec.options.jsonIsArray,
+		noneValue: func() string {
+			return codec.xml.MapByIndex[codec.xml.NoneIndex]
+		},
+		isKnown: func(token string) bool {
+			_, ok := codec.xml.MapByString[strings.ToLower(strings.TrimSpace(token))]
+			return ok
+		},
+		isKnownJSON: func(token string) bool {
+			return codec.json.hasString(token)
+		},
+	}
+}
+
+var lenientEnumAttrMap = map[string]lenientEnumAttr{
+	"type":                 newLenientEnumAttr(apnTypeBaseTypeStorage, ","),
+	"protocol":             newLenientEnumAttr(apnTypeBearerProtocolStorage, ""),
+	"roaming_protocol":     newLenientEnumAttr(apnTypeBearerProtocolStorage, ""),
+	"network_type_bitmask": newLenientEnumAttr(apnTypeNetworkTypeStorage, "|"),
+}
+
+func (enumAttr lenientEnumAttr) split(value string) (known []string, unknown []string) {
+	tokenArray := []string{value}
+	if enumAttr.separator != "" {
+		tokenArray = strings.Split(value, enumAttr.separator)
+	}
+
+	for _, token := range tokenArray {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		if enumAttr.isKnown(token) {
+			known = append(known, token)
+		} else {
+			unknown = append(unknown, token)
+		}
+	}
+
+	return known, unknown
+}
+
+func (apnPointerCore *Object) splitUnknownAttrs(xmlStart xml.StartElement, options decodeOptions, index int) (xml.StartElement, bool) {
+	var (
+		xmlAttrArray  = make([]xml.Attr, 0, len(xmlStart.Attr))
+		isUnknownType bool
+	)
+
+	for _, xmlAttr := range xmlStart.Attr {
+		enumAttr, ok := lenientEnumAttrMap[xmlAttr.Name.Local]
+		if !ok || xmlAttr.Name.Space != "" {
+			xmlAttrArray = append(xmlAttrArray, xmlAttr)
+			continue
+		}
+
+		known, unknown := enumAttr.split(xmlAttr.Value)
+		if len(unknown) == 0 {
+			xmlAttrArray = append(xmlAttrArray, xmlAttr)
+			continue
+		}
+
+		if apnPointerCore.Unknown == nil {
+			apnPointerCore.Unknown = map[string][]string{}
+		}
+		apnPointerCore.Unknown[xmlAttr.Name.Local] = unknown
+		for _, token := range unknown {
+			options.warn(index, xmlAttr.Name.Local, token)
+		}
+
+		if len(known) == 0 {
+			isUnknownType = isUnknownType || xmlAttr.Name.Local == "type"
+			continue
+		}
+
+		xmlAttrArray = append(xmlAttrArray, xml.Attr{Name: xmlAttr.Name, Value: strings.Join(known, enumAttr.separator)})
+	}
+
+	xmlStart.Attr = xmlAttrArray
+	return xmlStart, isUnknownType
+}
+
+func (apnPointerCore *Object) mergeUnknownAttrs(xmlAttrArray []xml.Attr) []xml.Attr {
+	if len(apnPointerCore.Unknown) == 0 {
+		return xmlAttrArray
+	}
+
+	xmlAttrNameArray := make([]string, 0, len(apnPointerCore.Unknown))
+	for xmlAttrName := range apnPointerCore.Unknown {
+		xmlAttrNameArray = append(xmlAttrNameArray, xmlAttrName)
+	}
+	sort.Strings(xmlAttrNameArray)
+
+	for _, xmlAttrName := range xmlAttrNameArray {
+		unknown := apnPointerCore.Unknown[xmlAttrName]
+		if len(unknown) == 0 {
+			continue
+		}
+
+		enumAttr, ok := lenientEnumAttrMap[xmlAttrName]
+		if !ok {
+			continue
+		}
+
+		xmlAttrIndex := -1
+		for index := range xmlAttrArray {
+			if xmlAttrArray[index].Name.Local == xmlAttrName {
+				xmlAttrIndex = index
+			}
+		}
+
+		switch {
+		case xmlAttrIndex < 0:
+			xmlAttrArray = append(xmlAttrArray, xml.Attr{Name: xml.Name{Local: xmlAttrName}, Value: strings.Join(unknown, enumAttr.separator)})
+		case enumAttr.separator == "":
+		case xmlAttrArray[xmlAttrIndex].Value == enumAttr.noneValue():
+			xmlAttrArray[xmlAttrIndex].Value = strings.Join(unknown, enumAttr.separator)
+		default:
+			xmlAttrArray[xmlAttrIndex].Value += enumAttr.separator + strings.Join(unknown, enumAttr.separator)
+		}
+	}
+
+	return xmlAttrArray
+}
+
+func (options decodeOptions) warnDropped(index int, apnPointer *Object) {
+	options.report.add(DecodeWarning{
+		Record:  index,
+		Attr:    "type",
+		Token:   strings.Join(apnPointer.Unknown["type"], ","),
+		Message: "duplicates the type slot of an earlier record of the same PLMN; record dropped",
+	})
+}
+
+func cloneUnknown(unknownMap map[string][]string) map[string][]string {
+	if unknownMap == nil {
+		return nil
+	}
+
+	result := make(map[string][]string, len(unknownMap))
+	for xmlAttrName, unknown := range unknownMap {
+		result[xmlAttrName] = append([]string(nil), unknown...)
+	}
+
+	return result
+}
+
+//--------------------------------------------------------------------------------//
+// Lenient JSON
+//--------------------------------------------------------------------------------//
+
+var lenientJSONFieldArray = []struct {
+	section     string
+	field       string
+	xmlAttrName string
+}{
+	{"base", "type", "type"},
+	{"bearer", "type", "protocol"},
+	{"bearer", "typeRoaming", "roaming_protocol"},
+	{"other", "networkTypeBitmask", "network_type_bitmask"},
+}
+
+func lenientJSON(data []byte, options decodeOptions) ([]byte, error) {
+	var recordArray []map[string]any
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&recordArray); err != nil {
+		return nil, err
+	}
+
+	index := 0
+	for _, record := range recordArray {
+		groupMap, ok := record["groupMap"].(map[string]any)
+		if !ok {
+			lenientJSONRecord(record, options, index)
+			index++
+			continue
+		}
+
+		keyArray := make([]string, 0, len(groupMap))
+		for key := range groupMap {
+			keyArray = append(keyArray, key)
+		}
+		sort.Strings(keyArray)
+
+		resultMap := make(map[string]any, len(groupMap))
+		for _, key := range keyArray {
+			entry, _ := groupMap[key].(map[string]any)
+			if entry != nil {
+				lenientJSONRecord(entry, options, index)
+			}
+
+			known, _ := lenientJSONTokens(lenientEnumAttrMap["type"], key)
+			resultKey := strings.Join(known, ",")
+			if resultKey == "" {
+				resultKey = apnTypeBaseTypeStorage.json.GetString(ObjectBaseTypeNone)
+			}
+			if _, ok := resultMap[resultKey]; ok && entry != nil {
+				options.warnDropped(index, &Object{Unknown: lenientJSONUnknown(entry)})
+			} else {
+				resultMap[resultKey] = groupMap[key]
+			}
+			index++
+		}
+		record["groupMap"] = resultMap
+	}
+
+	return json.Marshal(recordArray)
+}
+
+func lenientJSONRecord(record map[string]any, options decodeOptions, index int) {
+	for _, field := range lenientJSONFieldArray {
+		section, ok := record[field.section].(map[string]any)
+		if !ok || section[field.field] == nil {
+			continue
+		}
+
+		enumAttr := lenientEnumAttrMap[field.xmlAttrName]
+		known, unknown := lenientJSONTokens(enumAttr, section[field.field])
+		if len(unknown) == 0 {
+			continue
+		}
+
+		unknownMap, _ := record["unknown"].(map[string]any)
+		if unknownMap == nil {
+			unknownMap = map[string]any{}
+			record["unknown"] = unknownMap
+		}
+		unknownMap[field.xmlAttrName] = unknown
+		for _, token := range unknown {
+			options.warn(index, field.xmlAttrName, token)
+		}
+
+		switch {
+		case enumAttr.jsonIsArray:
+			section[field.field] = known
+		case len(known) == 0:
+			delete(section, field.field)
+		default:
+			section[field.field] = known[0]
+		}
+	}
+}
+
+func lenientJSONTokens(enumAttr lenientEnumAttr, value any) (known []string, unknown []string) {
+	var tokenArray []string
+	switch value := value.(type) {
+	case string:
+		tokenArray = []string{value}
+		if enumAttr.jsonIsArray {
+			tokenArray = strings.Split(value, ",")
+		}
+	case []any:
+		for _, token := range value {
+			if token, ok := token.(string); ok {
+				tokenArray = append(tokenArray, token)
+			}
+		}
+	}
+
+	known = []string{}
+	for _, token := range tokenArray {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		if enumAttr.isKnownJSON(token) {
+			known = append(known, token)
+		} else {
+			unknown = append(unknown, token)
+		}
+	}
+
+	return known, unknown
+}
+
+func lenientJSONUnknown(record map[string]any) map[string][]string {
+	unknownMap, _ := record["unknown"].(map[string]any)
+	result := map[string][]string{}
+	for xmlAttrName, value := range unknownMap {
+		switch value := value.(type) {
+		case []string:
+			result[xmlAttrName] = value
+		case []any:
+			for _, token := range value {
+				result[xmlAttrName] = append(result[xmlAttrName], fmt.Sprint(token))
+			}
+		}
+	}
+
+	return result
+}
+
+//--------------------------------------------------------------------------------//
@@ -162,6 +162,9 @@ type Object struct {
 	Other  *ObjectOther  `json:"other,omitempty"`
 
 	GroupMapByType map[ObjectBaseType]*Object `json:"groupMap,omitempty"`
+
+	Unknown map[string][]string `json:"unknown,omitempty"`
+	Source  *ObjectSource       `json:"-"`
 }
 
 type helperObject struct {
@@ -191,6 +194,8 @@ func (apnPointerCore *Object) Clone() *Object {
 		Mvno:       apnPointerCore.Mvno.Clone(),
 		Limit:      apnPointerCore.Limit.Clone(),
 		Other:      apnPointerCore.Other.Clone(),
+		Unknown:    cloneUnknown(apnPointerCore.Unknown),
+		Source:     apnPointerCore.Source.Clone(),
 	}
 
 	if apnPointerCore.GroupMapByType != nil {
@@ -385,7 +390,7 @@ func (apnPointerCore *Object) Match(apnPointerQuery *Object) bool {
 }
 
 func (apnObjectCore Object) String() string {
-	jsonData, err := json.MarshalIndent(apnObjectCore, "", "\t")
+	jsonData, err := json.MarshalIndent(apnObjectCore.Redacted(), "", "\t")
 	if err != nil {
 		return fmt.Sprintf("error: %v", err)
 	}
@@ -394,29 +399,57 @@ func (apnObjectCore Object) String() string {
 }
 
 func (apnObjectCore Object) MarshalXML(xmlEncoder *xml.Encoder, xmlStart xml.StartElement) error {
-	apnPointerCore := apnObjectCore.NormalizedClone()
-	apnObjectHelper := helperObject{
-		ObjectRoot:   apnPointerCore.ObjectRoot,
-		ObjectBase:   apnPointerCore.Base,
-		ObjectAuth:   apnPointerCore.Auth,
-		ObjectBearer: apnPointerCore.Bearer,
-		ObjectProxy:  apnPointerCore.Proxy,
-		ObjectMMS:    apnPointerCore.Mms,
-		ObjectMVNO:   apnPointerCore.Mvno,
-		ObjectLimit:  apnPointerCore.Limit,
-		ObjectOther:  apnPointerCore.Other,
-	}
-
-	err := xmlEncoder.EncodeElement(apnObjectHelper, xmlStart)
+	return apnObjectCore.marshalXML(xmlEncoder, xmlStart, encodeOptions{})
+}
+
+func (apnObjectCore Object) marshalXML(xmlEncoder *xml.Encoder, xmlStart xml.StartElement, options encodeOptions) error {
+	xmlAttrArray, err := apnObjectCore.marshalAttrs(options)
 	if err != nil {
 		return err
 	}
+	xmlStart.Attr = append(xmlStart.Attr, xmlAttrArray...)
 
-	return nil
+	err = xmlEncoder.EncodeToken(xmlStart)
+	if err != nil {
+		return err
+	}
+
+	return xmlEncoder.EncodeToken(xmlStart.End())
+}
+
+func (apnObjectCore Object) marshalAttrs(options encodeOptions) ([]xml.Attr, error) {
+	apnPointerCore := apnObjectCore.NormalizedClone()
+
+	xmlAttrArray, err := objectMarshalAttrs(apnPointerCore)
+	if err != nil {
+		return nil, err
+	}
+	xmlAttrArray, err = options.profile.transformAttrs(apnPointerCore, xmlAttrArray)
+	if err != nil {
+		return nil, err
+	}
+	if apnPointerCore.Source != nil {
+		xmlAttrArray = apnPointerCore.Source.restoreAttrs(xmlAttrArray)
+	} else {
+		sortCanonicalAttrs(xmlAttrArray)
+	}
+
+	return options.profile.omitAttrs(xmlAttrArray), nil
 }
 
 func (apnPointerCore *Object) UnmarshalXML(xmlDecoder *xml.Decoder, xmlStart xml.StartElement) error {
-	var apnObjectHelper helperObject
+	return apnPointerCore.unmarshalXML(xmlDecoder, xmlStart, decodeOptions{}, 0)
+}
+
+func (apnPointerCore *Object) unmarshalXML(xmlDecoder *xml.Decoder, xmlStart xml.StartElement, options decodeOptions, index int) error {
+	var (
+		apnObjectHelper helperObject
+		isUnknownType   bool
+	)
+
+	if options.lenient {
+		xmlStart, isUnknownType = apnPointerCore.splitUnknownAttrs(xmlStart, options, index)
+	}
 
 	err := xmlDecoder.DecodeElement(&apnObjectHelper, &xmlStart)
 	if err != nil {
@@ -432,6 +465,16 @@ func (apnPointerCore *Object) UnmarshalXML(xmlDecoder *xml.Decoder, xmlStart xml
 	apnPointerCore.Mvno = apnObjectHelper.ObjectMVNO.Clone()
 	apnPointerCore.Limit = apnObjectHelper.ObjectLimit.Clone()
 	apnPointerCore.Other = apnObjectHelper.ObjectOther.Clone()
+	apnPointerCore.decodeLegacyBearer(xmlStart, options, index)
+
+	if isUnknownType {
+		if apnPointerCore.Base == nil {
+			apnPointerCore.Base = &ObjectBase{}
+		}
+		value := ObjectBaseTypeNone
+		apnPointerCore.Base.Type = &value
+	}
+
 	apnPointerCore.Normalize()
 
 	return nil
@@ -736,6 +779,8 @@ type ObjectBearer struct {
 	Type        *ObjectBearerProtocol `json:"type,omitempty"         xml:"protocol,attr,omitempty"`
 	TypeRoaming *ObjectBearerProtocol `json:"typeRoaming,omitempty"  xml:"roaming_protocol,attr,omitempty"`
 	Mtu         *int                  `json:"mtu,omitempty"          xml:"mtu,attr,omitempty"`
+	MtuV4       *int                  `json:"mtuV4,omitempty"        xml:"mtu_v4,attr,omitempty"`
+	MtuV6       *int                  `json:"mtuV6,omitempty"        xml:"mtu_v6,attr,omitempty"`
 	Server      *string               `json:"server,omitempty"       xml:"server,attr,omitempty"`
 }
 
@@ -777,6 +822,8 @@ func (apnPointerBearer *ObjectBearer) Match(apnPointer *ObjectBearer) bool {
 	return matchMaskPtr(apnPointerBearer.Type, apnPointer.Type) &&
 		matchMaskPtr(apnPointerBearer.TypeRoaming, apnPointer.TypeRoaming) &&
 		matchIntPtr(apnPointerBearer.Mtu, apnPointer.Mtu) &&
+		matchIntPtr(apnPointerBearer.MtuV4, apnPointer.MtuV4) &&
+		matchIntPtr(apnPointerBearer.MtuV6, apnPointer.MtuV6) &&
 		matchStringPtr(apnPointerBearer.Server, apnPointer.Server)
 }
 
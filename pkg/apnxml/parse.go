@@ -10,14 +10,37 @@ import (
 //--------------------------------------------------------------------------------//
 
 func ParseFormat(value string) (Format, error) {
+	if strings.EqualFold(strings.TrimSpace(value), string(FormatAuto)) {
+		return FormatAuto, nil
+	}
+
+	codec, ok := lookupFormat(Format(strings.TrimSpace(value)))
+	if !ok {
+		return "", fmt.Errorf("unsupported apn format: %s", value)
+	}
+
+	return codec.name, nil
+}
+
+func ParseCanonicalLayout(value string) (CanonicalLayout, error) {
 	switch strings.ToLower(strings.TrimSpace(value)) {
-	case "json":
-		return FormatJSON, nil
-	case "xml":
-		return FormatXML, nil
+	case "", "element":
+		return CanonicalLayoutElement, nil
+	case "attribute", "attr":
+		return CanonicalLayoutAttribute, nil
 	default:
-		return "", fmt.Errorf("unsupported apn format: %s", value)
+		return 0, fmt.Errorf("unsupported canonical layout: %s", value)
+	}
+}
+
+func ParseExportProfile(value string) (ExportProfile, error) {
+	profile, ok := exportProfileMap[strings.ToLower(strings.TrimSpace(value))]
+	if !ok {
+		return ExportProfile{}, fmt.Errorf("unsupported apn export profile: %s", value)
 	}
+
+	profile.OmitAttrs = append([]string(nil), profile.OmitAttrs...)
+	return profile, nil
 }
 
 func ParseObjectUpdateMode(value string) (ObjectUpdateMode, error) {
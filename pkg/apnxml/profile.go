@@ -0,0 +1,183 @@
+package apnxml
+
+import (
+	"encoding/xml"
+	"sort"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------//
+// Export Profile
+//--------------------------------------------------------------------------------//
+
+const DefaultXMLVersion = "8"
+
+type ExportAuthType int
+
+const (
+	ExportAuthTypeNumeric ExportAuthType = iota
+	ExportAuthTypeNamed
+)
+
+type ExportNetworkAttr int
+
+const (
+	ExportNetworkTypeBitmask ExportNetworkAttr = iota
+	ExportBearerBitmask
+	ExportNetworkAndBearerBitmask
+)
+
+type ExportProfile struct {
+	Name           string
+	Version        string
+	AuthType       ExportAuthType
+	NetworkAttr    ExportNetworkAttr
+	SupportedTypes ObjectBaseType
+	OmitAttrs      []string
+}
+
+const (
+	apnTypeAndroid9 = ObjectBaseTypeDefault | ObjectBaseTypeMMS | ObjectBaseTypeSUPL | ObjectBaseTypeDUN |
+		ObjectBaseTypeHIPRI | ObjectBaseTypeFOTA | ObjectBaseTypeIMS | ObjectBaseTypeCBS | ObjectBaseTypeIA |
+		ObjectBaseTypeEmergency
+	apnTypeAndroid10 = apnTypeAndroid9 | ObjectBaseTypeMCX
+)
+
+var exportProfileMap = map[string]ExportProfile{
+	"aosp": {
+		Name:     "aosp",
+		AuthType: ExportAuthTypeNumeric,
+	},
+	"android-9": {
+		Name:           "android-9",
+		Version:        "8",
+		AuthType:       ExportAuthTypeNumeric,
+		NetworkAttr:    ExportBearerBitmask,
+		SupportedTypes: apnTypeAndroid9,
+	},
+	"android-10": {
+		Name:           "android-10",
+		Version:        "8",
+		AuthType:       ExportAuthTypeNumeric,
+		SupportedTypes: apnTypeAndroid10,
+		OmitAttrs:      []string{"bearer", "bearer_bitmask"},
+	},
+	"android-14": {
+		Name:      "android-14",
+		Version:   "8",
+		AuthType:  ExportAuthTypeNumeric,
+		OmitAttrs: []string{"bearer", "bearer_bitmask"},
+	},
+	"lineage": {
+		Name:        "lineage",
+		Version:     "8",
+		AuthType:    ExportAuthTypeNumeric,
+		NetworkAttr: ExportNetworkAndBearerBitmask,
+	},
+}
+
+func ExportProfiles() []string {
+	nameArray := make([]string, 0, len(exportProfileMap))
+	for name := range exportProfileMap {
+		nameArray = append(nameArray, name)
+	}
+
+	sort.Strings(nameArray)
+	return nameArray
+}
+
+func (profile ExportProfile) supportedTypes() ObjectBaseType {
+	if profile.SupportedTypes == ObjectBaseTypeNone {
+		return ObjectBaseTypeNone
+	}
+
+	return profile.SupportedTypes | registeredObjectBaseTypes()
+}
+
+func (profile ExportProfile) acceptsObject(apnPointer *Object) bool {
+	if profile.SupportedTypes == ObjectBaseTypeNone || apnPointer.Base == nil || apnPointer.Base.Type == nil {
+		return true
+	}
+
+	return *apnPointer.Base.Type&profile.supportedTypes() != ObjectBaseTypeNone
+}
+
+func (profile ExportProfile) transformAttrs(apnPointer *Object, xmlAttrArray []xml.Attr) ([]xml.Attr, error) {
+	var result []xml.Attr
+
+	for _, xmlAttr := range xmlAttrArray {
+		switch xmlAttr.Name.Local {
+		case "type":
+			if profile.SupportedTypes != ObjectBaseTypeNone && apnPointer.Base != nil && apnPointer.Base.Type != nil {
+				supportedType := *apnPointer.Base.Type & profile.supportedTypes()
+				if supportedType == ObjectBaseTypeNone {
+					continue
+				}
+
+				var err error
+				xmlAttr, err = supportedType.MarshalXMLAttr(xmlAttr.Name)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case "authtype":
+			if profile.AuthType == ExportAuthTypeNamed && apnPointer.Auth != nil && apnPointer.Auth.Type != nil {
+				xmlAttr.Value = strings.ToLower(strings.Join(apnTypeAuthTypeStorage.json.GetStringArray(*apnPointer.Auth.Type), ","))
+			}
+		case "network_type_bitmask":
+			if apnPointer.Other == nil || apnPointer.Other.NetworkTypeBitmask == nil {
+				break
+			}
+
+			networkTypeValue := *apnPointer.Other.NetworkTypeBitmask
+			if profile.NetworkAttr != ExportNetworkTypeBitmask && networkTypeValue != ObjectNetworkTypeNone {
+				result = append(result, xml.Attr{Name: xml.Name{Local: "bearer_bitmask"}, Value: networkTypeValue.RadioTechnologyString()})
+			}
+			if profile.NetworkAttr == ExportBearerBitmask {
+				continue
+			}
+		}
+
+		result = append(result, xmlAttr)
+	}
+
+	return result, nil
+}
+
+func (profile ExportProfile) omitAttrs(xmlAttrArray []xml.Attr) []xml.Attr {
+	if len(profile.OmitAttrs) == 0 && profile.NetworkAttr != ExportBearerBitmask {
+		return xmlAttrArray
+	}
+
+	omitMap := map[string]bool{}
+	for _, xmlAttrName := range profile.OmitAttrs {
+		omitMap[xmlAttrName] = true
+	}
+	if profile.NetworkAttr == ExportBearerBitmask {
+		omitMap["network_type_bitmask"] = true
+	}
+
+	result := xmlAttrArray[:0:0]
+	for _, xmlAttr := range xmlAttrArray {
+		if !omitMap[xmlAttr.Name.Local] {
+			result = append(result, xmlAttr)
+		}
+	}
+
+	return result
+}
+
+func (options encodeOptions) xmlVersion(sourceVersion string) string {
+	switch {
+	case options.version != "":
+		return options.version
+	case options.profile.Version != "":
+		return options.profile.Version
+	case sourceVersion != "":
+		return sourceVersion
+	default:
+		return DefaultXMLVersion
+	}
+}
+
+//--------------------------------------------------------------------------------//
@@ -0,0 +1,209 @@
+package apnxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------//
+// Radio Technology
+//--------------------------------------------------------------------------------//
+
+var radioTechnologyNetworkTypeMap = map[int]ObjectNetworkType{
+	1:  ObjectNetworkTypeGPRS,
+	2:  ObjectNetworkTypeEDGE,
+	3:  ObjectNetworkTypeUMTS,
+	4:  ObjectNetworkTypeCDMA,
+	5:  ObjectNetworkTypeCDMA,
+	6:  ObjectNetworkType1xRTT,
+	7:  ObjectNetworkTypeEVDO0,
+	8:  ObjectNetworkTypeEVDOA,
+	9:  ObjectNetworkTypeHSDPA,
+	10: ObjectNetworkTypeHSUPA,
+	11: ObjectNetworkTypeHSPA,
+	12: ObjectNetworkTypeEVDOB,
+	13: ObjectNetworkTypeEHRPD,
+	14: ObjectNetworkTypeLTE,
+	15: ObjectNetworkTypeHSPAP,
+	16: ObjectNetworkTypeGSM,
+	17: ObjectNetworkTypeTDSCDMA,
+	18: ObjectNetworkTypeIWLAN,
+	19: ObjectNetworkTypeLTECA,
+	20: ObjectNetworkTypeNR,
+}
+
+func NetworkTypeFromRadioTechnology(radioTechnology int) (ObjectNetworkType, bool) {
+	networkTypeValue, ok := radioTechnologyNetworkTypeMap[radioTechnology]
+	return networkTypeValue, ok
+}
+
+func (networkTypeValue ObjectNetworkType) RadioTechnologies() []int {
+	var radioTechnologyArray []int
+	for radioTechnology, networkTypeIndex := range radioTechnologyNetworkTypeMap {
+		if networkTypeValue&networkTypeIndex == networkTypeIndex {
+			radioTechnologyArray = append(radioTechnologyArray, radioTechnology)
+		}
+	}
+
+	sort.Ints(radioTechnologyArray)
+	return radioTechnologyArray
+}
+
+func (networkTypeValue ObjectNetworkType) RadioTechnologyString() string {
+	var radioTechnologyStringArray []string
+	for _, radioTechnology := range networkTypeValue.RadioTechnologies() {
+		radioTechnologyStringArray = append(radioTechnologyStringArray, strconv.Itoa(radioTechnology))
+	}
+
+	return strings.Join(radioTechnologyStringArray, "|")
+}
+
+func ParseRadioTechnologyBitmask(value string) (ObjectNetworkType, error) {
+	var networkTypeValue ObjectNetworkType
+
+	for _, radioTechnologyString := range strings.Split(value, "|") {
+		radioTechnologyString = strings.TrimSpace(radioTechnologyString)
+		if radioTechnologyString == "" || radioTechnologyString == "0" {
+			continue
+		}
+
+		radioTechnology, err := strconv.Atoi(radioTechnologyString)
+		if err != nil {
+			return 0, fmt.Errorf("apn bearer has invalid radio technology: %q", radioTechnologyString)
+		}
+
+		networkTypeIndex, ok := NetworkTypeFromRadioTechnology(radioTechnology)
+		if !ok {
+			return 0, fmt.Errorf("apn bearer has unknown radio technology: %d", radioTechnology)
+		}
+
+		networkTypeValue |= networkTypeIndex
+	}
+
+	return networkTypeValue, nil
+}
+
+//--------------------------------------------------------------------------------//
+// Legacy Bearer
+//--------------------------------------------------------------------------------//
+
+var legacyBearerAttrArray = []string{"bearer_bitmask", "bearer"}
+
+func isLegacyBearerAttr(xmlAttr xml.Attr) bool {
+	if xmlAttr.Name.Space != "" {
+		return false
+	}
+
+	for _, xmlAttrName := range legacyBearerAttrArray {
+		if xmlAttr.Name.Local == xmlAttrName {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (apnPointerCore *Object) decodeLegacyBearer(xmlStart xml.StartElement, options decodeOptions, index int) {
+	var (
+		legacyAttr  xml.Attr
+		legacyValue ObjectNetworkType
+	)
+
+	for _, xmlAttrName := range legacyBearerAttrArray {
+		for _, xmlAttr := range xmlStart.Attr {
+			if xmlAttr.Name.Local != xmlAttrName || !isLegacyBearerAttr(xmlAttr) {
+				continue
+			}
+
+			networkTypeValue, err := ParseRadioTechnologyBitmask(xmlAttr.Value)
+			if err != nil {
+				options.report.add(DecodeWarning{Record: index, Attr: xmlAttrName, Token: xmlAttr.Value, Message: "invalid radio technology"})
+				continue
+			}
+
+			if legacyValue == ObjectNetworkTypeNone {
+				legacyAttr, legacyValue = xmlAttr, networkTypeValue
+			}
+		}
+	}
+
+	if legacyValue == ObjectNetworkTypeNone {
+		return
+	}
+
+	if apnPointerCore.Other != nil && apnPointerCore.Other.NetworkTypeBitmask != nil {
+		networkTypeValue := *apnPointerCore.Other.NetworkTypeBitmask
+		if networkTypeValue != legacyValue {
+			xmlAttr, _ := networkTypeValue.MarshalXMLAttr(xml.Name{Local: "network_type_bitmask"})
+			options.report.add(DecodeWarning{
+				Record:  index,
+				Attr:    legacyAttr.Name.Local,
+				Token:   legacyAttr.Value,
+				Message: fmt.Sprintf("conflicts with network_type_bitmask %q", xmlAttr.Value),
+			})
+		}
+
+		return
+	}
+
+	if apnPointerCore.Other == nil {
+		apnPointerCore.Other = &ObjectOther{}
+	}
+	apnPointerCore.Other.NetworkTypeBitmask = &legacyValue
+}
+
+func (apnPointerCore *Object) HasLegacyBearer() bool {
+	if apnPointerCore.Source == nil {
+		return false
+	}
+
+	for _, xmlAttr := range apnPointerCore.Source.Attrs {
+		if isLegacyBearerAttr(xmlAttr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (apnPointerCore *Object) MigrateLegacyBearer() bool {
+	if !apnPointerCore.HasLegacyBearer() {
+		return false
+	}
+
+	xmlAttrArray := apnPointerCore.Source.Attrs[:0:0]
+	for _, xmlAttr := range apnPointerCore.Source.Attrs {
+		if !isLegacyBearerAttr(xmlAttr) {
+			xmlAttrArray = append(xmlAttrArray, xmlAttr)
+		}
+	}
+	apnPointerCore.Source.Attrs = xmlAttrArray
+
+	hasNetworkType := false
+	for _, xmlAttr := range xmlAttrArray {
+		hasNetworkType = hasNetworkType || xmlAttr.Name.Local == "network_type_bitmask"
+	}
+	if !hasNetworkType {
+		delete(apnPointerCore.Source.decoded, "network_type_bitmask")
+	}
+
+	return true
+}
+
+func (apnArray Array) MigrateLegacyBearer() int {
+	var count int
+	for index := range apnArray {
+		for _, apnPointer := range apnArray[index].Records() {
+			if apnPointer.MigrateLegacyBearer() {
+				count++
+			}
+		}
+	}
+
+	return count
+}
+
+//--------------------------------------------------------------------------------//
@@ -0,0 +1,153 @@
+package apnxml
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//--------------------------------------------------------------------------------//
+// Enum Registry
+//--------------------------------------------------------------------------------//
+
+const enumRegistryMaxBit = bits.UintSize - 2
+
+var enumRegistryMutex sync.Mutex
+
+func (coreMapStorage *EnumMap[Type]) hasString(apnTypeString string) bool {
+	_, ok := coreMapStorage.MapByString[strings.ToLower(strings.TrimSpace(apnTypeString))]
+	return ok
+}
+
+func (coreMapStorage *EnumMap[Type]) add(apnTypeIndex Type, apnTypeString string) {
+	coreMapStorage.IndexArray = append(coreMapStorage.IndexArray, apnTypeIndex)
+	coreMapStorage.MapByIndex[apnTypeIndex] = apnTypeString
+	coreMapStorage.MapByString[apnTypeString] = apnTypeIndex
+	coreMapStorage.MapByString[strings.ToLower(apnTypeString)] = apnTypeIndex
+	coreMapStorage.MaxIndex = apnTypeIndex << 1
+}
+
+func (coreMapStorage *EnumMap[Type]) snapshot() func() {
+	coreMapSnapshot := EnumMap[Type]{
+		NoneIndex:   coreMapStorage.NoneIndex,
+		MaxIndex:    coreMapStorage.MaxIndex,
+		IndexArray:  append([]Type(nil), coreMapStorage.IndexArray...),
+		MapByIndex:  make(map[Type]string, len(coreMapStorage.MapByIndex)),
+		MapByString: make(map[string]Type, len(coreMapStorage.MapByString)),
+	}
+	for apnTypeIndex, apnTypeString := range coreMapStorage.MapByIndex {
+		coreMapSnapshot.MapByIndex[apnTypeIndex] = apnTypeString
+	}
+	for apnTypeString, apnTypeIndex := range coreMapStorage.MapByString {
+		coreMapSnapshot.MapByString[apnTypeString] = apnTypeIndex
+	}
+
+	return func() {
+		*coreMapStorage = coreMapSnapshot
+	}
+}
+
+func (coreProxyStorage *enumCodec[Type]) snapshot() []func() {
+	return []func(){coreProxyStorage.json.snapshot(), coreProxyStorage.xml.snapshot()}
+}
+
+func snapshotEnumRegistry() func() {
+	enumRegistryMutex.Lock()
+	defer enumRegistryMutex.Unlock()
+
+	var restoreArray []func()
+	restoreArray = append(restoreArray, apnTypeBaseTypeStorage.snapshot()...)
+	restoreArray = append(restoreArray, apnTypeBearerProtocolStorage.snapshot()...)
+	restoreArray = append(restoreArray, apnTypeNetworkTypeStorage.snapshot()...)
+
+	return func() {
+		enumRegistryMutex.Lock()
+		defer enumRegistryMutex.Unlock()
+
+		for _, restore := range restoreArray {
+			restore()
+		}
+	}
+}
+
+func (coreProxyStorage *enumCodec[Type]) register(apnTypeString string, xmlString string) (Type, error) {
+	enumRegistryMutex.Lock()
+	defer enumRegistryMutex.Unlock()
+
+	apnTypeString = strings.TrimSpace(apnTypeString)
+	xmlString = strings.TrimSpace(xmlString)
+	if apnTypeString == "" || xmlString == "" {
+		return 0, fmt.Errorf("apn enum name is empty")
+	}
+	if strings.ContainsAny(apnTypeString, ",|") || strings.ContainsAny(xmlString, ",|") {
+		return 0, fmt.Errorf("apn enum name contains a separator: %q", apnTypeString)
+	}
+
+	if coreProxyStorage.json.hasString(apnTypeString) {
+		return 0, fmt.Errorf("apn enum name is already registered: %q", apnTypeString)
+	}
+	if coreProxyStorage.xml.hasString(xmlString) {
+		return 0, fmt.Errorf("apn enum xml value is already registered: %q", xmlString)
+	}
+
+	apnTypeIndex := coreProxyStorage.json.MaxIndex
+	if apnTypeIndex <= 0 || apnTypeIndex > Type(1)<<enumRegistryMaxBit {
+		return 0, fmt.Errorf("apn enum has no free bit for %q", apnTypeString)
+	}
+	if _, ok := coreProxyStorage.json.MapByIndex[apnTypeIndex]; ok {
+		return 0, fmt.Errorf("apn enum bit is already allocated: %d", apnTypeIndex)
+	}
+
+	coreProxyStorage.json.add(apnTypeIndex, apnTypeString)
+	coreProxyStorage.xml.add(apnTypeIndex, xmlString)
+
+	return apnTypeIndex, nil
+}
+
+func RegisterObjectBaseType(name string) (ObjectBaseType, error) {
+	return apnTypeBaseTypeStorage.register(name, name)
+}
+
+func RegisterObjectBearerProtocol(name string) (ObjectBearerProtocol, error) {
+	return apnTypeBearerProtocolStorage.register(name, strings.ToUpper(name))
+}
+
+func RegisterObjectNetworkType(name string, networkType int) (ObjectNetworkType, error) {
+	if networkType == 0 {
+		networkType = nextObjectNetworkTypeNumber()
+	}
+	if networkType < 0 {
+		return 0, fmt.Errorf("apn network type has negative number: %d", networkType)
+	}
+
+	return apnTypeNetworkTypeStorage.register(name, strconv.Itoa(networkType))
+}
+
+func nextObjectNetworkTypeNumber() int {
+	enumRegistryMutex.Lock()
+	defer enumRegistryMutex.Unlock()
+
+	var networkType int
+	for _, xmlString := range apnTypeNetworkTypeStorage.xml.MapByIndex {
+		if value, err := strconv.Atoi(xmlString); err == nil && value > networkType {
+			networkType = value
+		}
+	}
+
+	return networkType + 1
+}
+
+func registeredObjectBaseTypes() ObjectBaseType {
+	var result ObjectBaseType
+	for _, apnTypeIndex := range apnTypeBaseTypeStorage.json.IndexArray {
+		if apnTypeIndex >= ObjectBaseTypeMax {
+			result |= apnTypeIndex
+		}
+	}
+
+	return result
+}
+
+//--------------------------------------------------------------------------------//
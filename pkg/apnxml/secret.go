@@ -0,0 +1,192 @@
+package apnxml
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+//--------------------------------------------------------------------------------//
+// Redaction
+//--------------------------------------------------------------------------------//
+
+const RedactedValue = "***"
+
+func redactString(value *string) {
+	if value != nil && *value != "" {
+		*value = RedactedValue
+	}
+}
+
+func (apnPointerAuth *ObjectAuth) redact() {
+	if apnPointerAuth == nil {
+		return
+	}
+
+	redactString(apnPointerAuth.Username)
+	redactString(apnPointerAuth.Password)
+}
+
+func (apnPointerCore *Object) Redacted() *Object {
+	apnPointerClone := apnPointerCore.Clone()
+	if apnPointerClone == nil {
+		return nil
+	}
+
+	apnPointerClone.Auth.redact()
+	for _, apnPointer := range apnPointerClone.GroupMapByType {
+		apnPointer.Auth.redact()
+	}
+
+	return apnPointerClone
+}
+
+func (apnArray Array) Redacted() Array {
+	if apnArray == nil {
+		return nil
+	}
+
+	apnArrayClone := make(Array, 0, len(apnArray))
+	for index := range apnArray {
+		apnArrayClone = append(apnArrayClone, *apnArray[index].Redacted())
+	}
+
+	return apnArrayClone
+}
+
+//--------------------------------------------------------------------------------//
+// Secret Reference
+//--------------------------------------------------------------------------------//
+
+type SecretResolver func(scheme string, name string) (string, error)
+
+var secretReferenceRegexp = regexp.MustCompile(`\$\{(env|file):([^}]*)\}`)
+
+func LookupSecret(scheme string, name string) (string, error) {
+	switch scheme {
+	case "env":
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("apn secret environment variable is not set: %s", name)
+		}
+
+		return value, nil
+	case "file":
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return "", fmt.Errorf("apn secret file: %w", err)
+		}
+
+		return strings.TrimRight(string(data), "\r\n"), nil
+	default:
+		return "", fmt.Errorf("apn secret has unsupported scheme: %q", scheme)
+	}
+}
+
+func HasSecretReference(value string) bool {
+	return secretReferenceRegexp.MatchString(value)
+}
+
+func ResolveSecretString(value string, resolver SecretResolver) (string, error) {
+	if resolver == nil {
+		resolver = LookupSecret
+	}
+
+	var resolveErr error
+	result := secretReferenceRegexp.ReplaceAllStringFunc(value, func(reference string) string {
+		if resolveErr != nil {
+			return reference
+		}
+
+		match := secretReferenceRegexp.FindStringSubmatch(reference)
+		if match[2] == "" {
+			resolveErr = fmt.Errorf("apn secret reference has empty name: %q", reference)
+			return reference
+		}
+
+		secret, err := resolver(match[1], match[2])
+		if err != nil {
+			resolveErr = err
+			return reference
+		}
+
+		return secret
+	})
+
+	return result, resolveErr
+}
+
+func resolveSecretPointer(value *string, resolver SecretResolver) error {
+	if value == nil || !HasSecretReference(*value) {
+		return nil
+	}
+
+	secret, err := ResolveSecretString(*value, resolver)
+	if err != nil {
+		return err
+	}
+
+	*value = secret
+	return nil
+}
+
+func (apnPointerAuth *ObjectAuth) resolveSecrets(resolver SecretResolver) error {
+	if apnPointerAuth == nil {
+		return nil
+	}
+
+	if err := resolveSecretPointer(apnPointerAuth.Username, resolver); err != nil {
+		return err
+	}
+
+	return resolveSecretPointer(apnPointerAuth.Password, resolver)
+}
+
+func (apnPointerCore *Object) ResolveSecrets(resolver SecretResolver) (*Object, error) {
+	apnPointerClone := apnPointerCore.Clone()
+	if apnPointerClone == nil {
+		return nil, nil
+	}
+
+	if err := apnPointerClone.Auth.resolveSecrets(resolver); err != nil {
+		return nil, err
+	}
+	for _, apnPointer := range apnPointerClone.GroupMapByType {
+		if err := apnPointer.Auth.resolveSecrets(resolver); err != nil {
+			return nil, err
+		}
+	}
+
+	return apnPointerClone, nil
+}
+
+func (apnArray Array) ResolveSecrets(resolver SecretResolver) (Array, error) {
+	if apnArray == nil {
+		return nil, nil
+	}
+
+	apnArrayClone := make(Array, 0, len(apnArray))
+	for index := range apnArray {
+		apnPointer, err := apnArray[index].ResolveSecrets(resolver)
+		if err != nil {
+			return nil, err
+		}
+
+		apnArrayClone = append(apnArrayClone, *apnPointer)
+	}
+
+	return apnArrayClone, nil
+}
+
+func WithSecretResolver(resolver SecretResolver) EncodeOption {
+	return func(options *encodeOptions) {
+		if resolver == nil {
+			resolver = LookupSecret
+		}
+
+		options.secretResolver = resolver
+	}
+}
+
+//--------------------------------------------------------------------------------//
@@ -2,6 +2,7 @@ package apnxml
 
 import (
 	"encoding/xml"
+	"strconv"
 	"strings"
 )
 
@@ -138,6 +139,10 @@ func (authTypeValue ObjectAuthType) MarshalXMLAttr(xmlAttrName xml.Name) (xmlAtt
 }
 
 func (authTypeValue *ObjectAuthType) UnmarshalXMLAttr(xmlAttr xml.Attr) error {
+	if _, err := strconv.Atoi(strings.TrimSpace(xmlAttr.Value)); err != nil {
+		return apnTypeAuthTypeStorage.unmarshalText(authTypeValue, []byte(xmlAttr.Value))
+	}
+
 	return apnTypeAuthTypeStorage.unmarshalXMLAttr(authTypeValue, xmlAttr)
 }
 
@@ -0,0 +1,895 @@
+package apnxml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//--------------------------------------------------------------------------------//
+// YAML
+//--------------------------------------------------------------------------------//
+
+const FormatYAML Format = "yaml"
+
+type yamlNodeKind int
+
+const (
+	yamlNodeScalar yamlNodeKind = iota
+	yamlNodeSequence
+	yamlNodeMapping
+)
+
+const yamlIndent = 2
+
+var (
+	yamlIntRegexp   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloatRegexp = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+	yamlKeyRegexp   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*:(\s|$)`)
+)
+
+type yamlNode struct {
+	kind      yamlNodeKind
+	value     string
+	keyArray  []string
+	itemArray []*yamlNode
+}
+
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+	raw    string
+}
+
+type yamlParser struct {
+	lineArray []yamlLine
+	index     int
+}
+
+func decodeYAML(data []byte, optionList ...DecodeOption) (Array, error) {
+	yamlRoot, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	yamlRoot.writeJSON(&buffer)
+
+	return decodeJSON(buffer.Bytes(), optionList...)
+}
+
+func encodeYAML(apnArray Array, optionList ...EncodeOption) ([]byte, error) {
+	jsonByte, err := encodeJSON(apnArray, optionList...)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(jsonByte))
+	decoder.UseNumber()
+	yamlRoot, err := yamlNodeFromJSON(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	switch {
+	case yamlRoot.kind == yamlNodeScalar, len(yamlRoot.itemArray) == 0:
+		buffer.WriteString(yamlRoot.flowString() + "\n")
+	default:
+		yamlRoot.writeBlock(&buffer, 0)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func isYAMLDocument(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		switch {
+		case line == "" || strings.HasPrefix(strings.TrimSpace(line), "#"):
+			continue
+		case line == "---" || strings.HasPrefix(line, "--- ") || line == "-" || strings.HasPrefix(line, "- "):
+			return true
+		default:
+			return yamlKeyRegexp.MatchString(line)
+		}
+	}
+
+	return false
+}
+
+//--------------------------------------------------------------------------------//
+// YAML Decode
+//--------------------------------------------------------------------------------//
+
+func parseYAML(data []byte) (*yamlNode, error) {
+	data = bytes.TrimPrefix(data, detectUTF8BOM)
+	yamlParserCore := &yamlParser{}
+	for index, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		yamlLineCore := yamlLine{number: index + 1, raw: raw}
+		content := strings.TrimLeft(raw, " ")
+		yamlLineCore.indent = len(raw) - len(content)
+		yamlLineCore.text = strings.TrimRight(stripYAMLComment(content), " \t")
+		if yamlLineCore.text != "" && strings.HasPrefix(content, "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed in indentation", yamlLineCore.number)
+		}
+		yamlParserCore.lineArray = append(yamlParserCore.lineArray, yamlLineCore)
+	}
+
+	if err := yamlParserCore.skipDocumentStart(); err != nil {
+		return nil, err
+	}
+	yamlLineCore, ok := yamlParserCore.peek()
+	if !ok {
+		return &yamlNode{value: "null"}, nil
+	}
+
+	yamlRoot, err := yamlParserCore.parseBlock(yamlLineCore.indent)
+	if err != nil {
+		return nil, err
+	}
+	if yamlLineCore, ok := yamlParserCore.peek(); ok {
+		if yamlLineCore.indent == 0 && yamlLineCore.text == "..." {
+			yamlParserCore.index++
+			if yamlLineCore, ok = yamlParserCore.peek(); !ok {
+				return yamlRoot, nil
+			}
+		}
+		if yamlLineCore.indent == 0 && (yamlLineCore.text == "---" || strings.HasPrefix(yamlLineCore.text, "--- ")) {
+			return nil, fmt.Errorf("yaml: line %d: multiple documents are not supported", yamlLineCore.number)
+		}
+		return nil, fmt.Errorf("yaml: line %d: unexpected content %q", yamlLineCore.number, yamlLineCore.text)
+	}
+
+	return yamlRoot, nil
+}
+
+func stripYAMLComment(text string) string {
+	var quote byte
+	for index := 0; index < len(text); index++ {
+		symbol := text[index]
+		switch {
+		case quote == '"' && symbol == '\\':
+			index++
+		case quote != 0:
+			if symbol == quote {
+				quote = 0
+			}
+		case symbol == '"' || symbol == '\'':
+			if index == 0 || strings.IndexByte(" [{,", text[index-1]) >= 0 {
+				quote = symbol
+			}
+		case symbol == '#':
+			if index == 0 || text[index-1] == ' ' || text[index-1] == '\t' {
+				return text[:index]
+			}
+		}
+	}
+
+	return text
+}
+
+func (yamlParserCore *yamlParser) peek() (yamlLine, bool) {
+	for yamlParserCore.index < len(yamlParserCore.lineArray) {
+		yamlLineCore := yamlParserCore.lineArray[yamlParserCore.index]
+		if yamlLineCore.text != "" {
+			return yamlLineCore, true
+		}
+		yamlParserCore.index++
+	}
+
+	return yamlLine{}, false
+}
+
+func (yamlParserCore *yamlParser) skipDocumentStart() error {
+	yamlLineCore, ok := yamlParserCore.peek()
+	if !ok || yamlLineCore.indent != 0 {
+		return nil
+	}
+	if strings.HasPrefix(yamlLineCore.text, "%") {
+		return fmt.Errorf("yaml: line %d: directives are not supported", yamlLineCore.number)
+	}
+	if yamlLineCore.text == "---" {
+		yamlParserCore.index++
+	} else if rest, ok := strings.CutPrefix(yamlLineCore.text, "--- "); ok {
+		yamlParserCore.lineArray[yamlParserCore.index].text = strings.TrimSpace(rest)
+	}
+
+	return nil
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func splitYAMLKey(text string) (string, string, bool, error) {
+	if text == "" || strings.IndexByte("-?[{#&*!|>%@`", text[0]) >= 0 && !(text[0] == '-' && len(text) > 1 && text[1] != ' ') {
+		return "", "", false, nil
+	}
+
+	if text[0] == '"' || text[0] == '\'' {
+		key, length, err := parseYAMLQuoted(text)
+		if err != nil {
+			return "", "", false, err
+		}
+		rest := strings.TrimLeft(text[length:], " ")
+		if !strings.HasPrefix(rest, ":") || (len(rest) > 1 && rest[1] != ' ') {
+			return "", "", false, nil
+		}
+		return key, strings.TrimSpace(rest[1:]), true, nil
+	}
+
+	for index := 0; index < len(text); index++ {
+		if text[index] == ':' && (index+1 == len(text) || text[index+1] == ' ') {
+			return strings.TrimSpace(text[:index]), strings.TrimSpace(text[index+1:]), true, nil
+		}
+	}
+
+	return "", "", false, nil
+}
+
+func (yamlParserCore *yamlParser) parseBlock(indent int) (*yamlNode, error) {
+	yamlLineCore, _ := yamlParserCore.peek()
+	if isYAMLSequenceItem(yamlLineCore.text) {
+		return yamlParserCore.parseSequence(indent)
+	}
+
+	_, _, isKey, err := splitYAMLKey(yamlLineCore.text)
+	if err != nil {
+		return nil, fmt.Errorf("yaml: line %d: %w", yamlLineCore.number, err)
+	}
+	if isKey {
+		return yamlParserCore.parseMapping(indent)
+	}
+
+	yamlParserCore.index++
+	return yamlParserCore.parseInline(yamlLineCore, yamlLineCore.text)
+}
+
+func (yamlParserCore *yamlParser) parseMapping(indent int) (*yamlNode, error) {
+	yamlMap := &yamlNode{kind: yamlNodeMapping}
+	keyMap := map[string]bool{}
+
+	for {
+		yamlLineCore, ok := yamlParserCore.peek()
+		if !ok || yamlLineCore.indent < indent {
+			break
+		}
+		if yamlLineCore.indent > indent {
+			return nil, fmt.Errorf("yaml: line %d: unexpected indentation", yamlLineCore.number)
+		}
+		if yamlLineCore.indent == 0 && (yamlLineCore.text == "..." || yamlLineCore.text == "---" || strings.HasPrefix(yamlLineCore.text, "--- ")) {
+			break
+		}
+
+		key, rest, isKey, err := splitYAMLKey(yamlLineCore.text)
+		if err != nil {
+			return nil, fmt.Errorf("yaml: line %d: %w", yamlLineCore.number, err)
+		}
+		if !isKey {
+			return nil, fmt.Errorf("yaml: line %d: expected a mapping key", yamlLineCore.number)
+		}
+		if keyMap[key] {
+			return nil, fmt.Errorf("yaml: line %d: duplicate key %q", yamlLineCore.number, key)
+		}
+		keyMap[key] = true
+
+		yamlParserCore.index++
+		yamlValue, err := yamlParserCore.parseValue(yamlLineCore, indent, rest, true)
+		if err != nil {
+			return nil, err
+		}
+		yamlMap.keyArray = append(yamlMap.keyArray, key)
+		yamlMap.itemArray = append(yamlMap.itemArray, yamlValue)
+	}
+
+	return yamlMap, nil
+}
+
+func (yamlParserCore *yamlParser) parseSequence(indent int) (*yamlNode, error) {
+	yamlSequence := &yamlNode{kind: yamlNodeSequence}
+
+	for {
+		yamlLineCore, ok := yamlParserCore.peek()
+		if !ok || yamlLineCore.indent < indent {
+			break
+		}
+		if yamlLineCore.indent > indent {
+			return nil, fmt.Errorf("yaml: line %d: unexpected indentation", yamlLineCore.number)
+		}
+		if !isYAMLSequenceItem(yamlLineCore.text) {
+			break
+		}
+
+		rest := strings.TrimLeft(yamlLineCore.text[1:], " ")
+		_, _, isKey, err := splitYAMLKey(rest)
+		if err != nil {
+			return nil, fmt.Errorf("yaml: line %d: %w", yamlLineCore.number, err)
+		}
+
+		var yamlItem *yamlNode
+		if isKey || isYAMLSequenceItem(rest) {
+			itemIndent := yamlLineCore.indent + len(yamlLineCore.text) - len(rest)
+			yamlParserCore.lineArray[yamlParserCore.index].indent = itemIndent
+			yamlParserCore.lineArray[yamlParserCore.index].text = rest
+			yamlItem, err = yamlParserCore.parseBlock(itemIndent)
+		} else {
+			yamlParserCore.index++
+			yamlItem, err = yamlParserCore.parseValue(yamlLineCore, indent, rest, false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		yamlSequence.itemArray = append(yamlSequence.itemArray, yamlItem)
+	}
+
+	return yamlSequence, nil
+}
+
+func (yamlParserCore *yamlParser) parseValue(yamlLineCore yamlLine, indent int, rest string, isMappingValue bool) (*yamlNode, error) {
+	switch {
+	case rest == "":
+		yamlNextLine, ok := yamlParserCore.peek()
+		if ok && yamlNextLine.indent > indent {
+			return yamlParserCore.parseBlock(yamlNextLine.indent)
+		}
+		if ok && isMappingValue && yamlNextLine.indent == indent && isYAMLSequenceItem(yamlNextLine.text) {
+			return yamlParserCore.parseSequence(indent)
+		}
+		return &yamlNode{value: "null"}, nil
+	case rest[0] == '|' || rest[0] == '>':
+		return yamlParserCore.parseBlockScalar(yamlLineCore, indent, rest)
+	case rest[0] == '&' || rest[0] == '*' || rest[0] == '!':
+		return nil, fmt.Errorf("yaml: line %d: anchors, aliases and tags are not supported", yamlLineCore.number)
+	default:
+		return yamlParserCore.parseInline(yamlLineCore, rest)
+	}
+}
+
+func (yamlParserCore *yamlParser) parseInline(yamlLineCore yamlLine, text string) (*yamlNode, error) {
+	if text[0] == '[' || text[0] == '{' {
+		for yamlFlowDepth(text) > 0 {
+			yamlNextLine, ok := yamlParserCore.peek()
+			if !ok {
+				return nil, fmt.Errorf("yaml: line %d: unterminated flow collection", yamlLineCore.number)
+			}
+			yamlParserCore.index++
+			text += " " + yamlNextLine.text
+		}
+	}
+
+	yamlFlow := &yamlFlowParser{text: text}
+	yamlValue, err := yamlFlow.parseValue(false)
+	if err == nil {
+		yamlFlow.skipSpace()
+		if yamlFlow.offset < len(yamlFlow.text) {
+			err = fmt.Errorf("unexpected %q after value", yamlFlow.text[yamlFlow.offset:])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("yaml: line %d: %w", yamlLineCore.number, err)
+	}
+
+	return yamlValue, nil
+}
+
+func (yamlParserCore *yamlParser) parseBlockScalar(yamlLineCore yamlLine, indent int, header string) (*yamlNode, error) {
+	isFolded := header[0] == '>'
+	chomp := byte(0)
+	for _, symbol := range []byte(strings.TrimSpace(header[1:])) {
+		switch symbol {
+		case '-', '+':
+			chomp = symbol
+		default:
+			return nil, fmt.Errorf("yaml: line %d: unsupported block scalar header %q", yamlLineCore.number, header)
+		}
+	}
+
+	var (
+		lineArray   []string
+		blockIndent = -1
+	)
+	for yamlParserCore.index < len(yamlParserCore.lineArray) {
+		raw := yamlParserCore.lineArray[yamlParserCore.index].raw
+		content := strings.TrimLeft(raw, " ")
+		if strings.TrimSpace(raw) == "" {
+			lineArray = append(lineArray, "")
+			yamlParserCore.index++
+			continue
+		}
+
+		lineIndent := len(raw) - len(content)
+		if blockIndent < 0 {
+			blockIndent = lineIndent
+		}
+		if lineIndent <= indent || lineIndent < blockIndent {
+			break
+		}
+		lineArray = append(lineArray, raw[blockIndent:])
+		yamlParserCore.index++
+	}
+
+	trailing := 0
+	for trailing < len(lineArray) && lineArray[len(lineArray)-1-trailing] == "" {
+		trailing++
+	}
+	yamlParserCore.index -= trailing
+	if chomp != '+' {
+		lineArray = lineArray[:len(lineArray)-trailing]
+	}
+
+	var text string
+	if isFolded {
+		var builder strings.Builder
+		for index, line := range lineArray {
+			if index > 0 {
+				if line == "" || lineArray[index-1] == "" || strings.HasPrefix(line, " ") {
+					builder.WriteByte('\n')
+				} else {
+					builder.WriteByte(' ')
+				}
+			}
+			builder.WriteString(line)
+		}
+		text = builder.String()
+	} else {
+		text = strings.Join(lineArray, "\n")
+	}
+	if chomp != '-' && len(lineArray) > 0 {
+		text += "\n"
+	}
+
+	return newYAMLString(text), nil
+}
+
+func yamlFlowDepth(text string) int {
+	var (
+		depth int
+		quote byte
+	)
+	for index := 0; index < len(text); index++ {
+		symbol := text[index]
+		switch {
+		case quote == '"' && symbol == '\\':
+			index++
+		case quote != 0:
+			if symbol == quote {
+				quote = 0
+			}
+		case symbol == '"' || symbol == '\'':
+			quote = symbol
+		case symbol == '[' || symbol == '{':
+			depth++
+		case symbol == ']' || symbol == '}':
+			depth--
+		}
+	}
+
+	return depth
+}
+
+//--------------------------------------------------------------------------------//
+// YAML Flow Values
+//--------------------------------------------------------------------------------//
+
+type yamlFlowParser struct {
+	text   string
+	offset int
+}
+
+func (yamlFlow *yamlFlowParser) skipSpace() {
+	for yamlFlow.offset < len(yamlFlow.text) && (yamlFlow.text[yamlFlow.offset] == ' ' || yamlFlow.text[yamlFlow.offset] == '\t') {
+		yamlFlow.offset++
+	}
+}
+
+func (yamlFlow *yamlFlowParser) peek() byte {
+	yamlFlow.skipSpace()
+	if yamlFlow.offset < len(yamlFlow.text) {
+		return yamlFlow.text[yamlFlow.offset]
+	}
+
+	return 0
+}
+
+func (yamlFlow *yamlFlowParser) parseValue(isFlow bool) (*yamlNode, error) {
+	switch yamlFlow.peek() {
+	case '[':
+		yamlFlow.offset++
+		yamlSequence := &yamlNode{kind: yamlNodeSequence}
+		for {
+			if yamlFlow.peek() == ']' {
+				yamlFlow.offset++
+				return yamlSequence, nil
+			}
+			yamlItem, err := yamlFlow.parseValue(true)
+			if err != nil {
+				return nil, err
+			}
+			yamlSequence.itemArray = append(yamlSequence.itemArray, yamlItem)
+			if err := yamlFlow.parseSeparator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		yamlFlow.offset++
+		yamlMap := &yamlNode{kind: yamlNodeMapping}
+		keyMap := map[string]bool{}
+		for {
+			if yamlFlow.peek() == '}' {
+				yamlFlow.offset++
+				return yamlMap, nil
+			}
+			yamlKey, err := yamlFlow.parseValue(true)
+			if err != nil {
+				return nil, err
+			}
+			key := yamlKey.stringValue()
+			if yamlKey.kind != yamlNodeScalar || keyMap[key] {
+				return nil, fmt.Errorf("invalid or duplicate flow mapping key %q", key)
+			}
+			keyMap[key] = true
+
+			yamlValue := &yamlNode{value: "null"}
+			if yamlFlow.peek() == ':' {
+				yamlFlow.offset++
+				if symbol := yamlFlow.peek(); symbol != ',' && symbol != '}' {
+					if yamlValue, err = yamlFlow.parseValue(true); err != nil {
+						return nil, err
+					}
+				}
+			}
+			yamlMap.keyArray = append(yamlMap.keyArray, key)
+			yamlMap.itemArray = append(yamlMap.itemArray, yamlValue)
+			if err := yamlFlow.parseSeparator('}'); err != nil {
+				return nil, err
+			}
+		}
+	case '"', '\'':
+		value, length, err := parseYAMLQuoted(yamlFlow.text[yamlFlow.offset:])
+		if err != nil {
+			return nil, err
+		}
+		yamlFlow.offset += length
+		return newYAMLString(value), nil
+	case 0:
+		if isFlow {
+			return nil, fmt.Errorf("unterminated flow collection")
+		}
+		return &yamlNode{value: "null"}, nil
+	}
+
+	start := yamlFlow.offset
+	for yamlFlow.offset < len(yamlFlow.text) {
+		symbol := yamlFlow.text[yamlFlow.offset]
+		if isFlow && (symbol == ',' || symbol == ']' || symbol == '}') {
+			break
+		}
+		if isFlow && symbol == ':' && (yamlFlow.offset+1 == len(yamlFlow.text) || strings.IndexByte(" ,]}", yamlFlow.text[yamlFlow.offset+1]) >= 0) {
+			break
+		}
+		yamlFlow.offset++
+	}
+
+	return resolveYAMLPlain(strings.TrimSpace(yamlFlow.text[start:yamlFlow.offset])), nil
+}
+
+func (yamlFlow *yamlFlowParser) parseSeparator(end byte) error {
+	switch yamlFlow.peek() {
+	case ',':
+		yamlFlow.offset++
+		return nil
+	case end:
+		return nil
+	default:
+		return fmt.Errorf("expected ',' or %q in flow collection", end)
+	}
+}
+
+func parseYAMLQuoted(text string) (string, int, error) {
+	quote := text[0]
+	var builder strings.Builder
+
+	for index := 1; index < len(text); index++ {
+		symbol := text[index]
+		switch {
+		case symbol == quote && quote == '\'':
+			if index+1 < len(text) && text[index+1] == '\'' {
+				builder.WriteByte('\'')
+				index++
+				continue
+			}
+			return builder.String(), index + 1, nil
+		case symbol == quote:
+			return builder.String(), index + 1, nil
+		case symbol == '\\' && quote == '"':
+			index++
+			if index >= len(text) {
+				return "", 0, fmt.Errorf("unterminated escape sequence")
+			}
+			length, err := writeYAMLEscape(&builder, text[index:])
+			if err != nil {
+				return "", 0, err
+			}
+			index += length - 1
+		default:
+			builder.WriteByte(symbol)
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated quoted string")
+}
+
+func writeYAMLEscape(builder *strings.Builder, text string) (int, error) {
+	simpleMap := map[byte]string{
+		'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+		'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\", 'N': "\u0085",
+		'_': " ", 'L': " ", 'P': " ",
+	}
+	if value, ok := simpleMap[text[0]]; ok {
+		builder.WriteString(value)
+		return 1, nil
+	}
+
+	length := map[byte]int{'x': 2, 'u': 4, 'U': 8}[text[0]]
+	if length == 0 || len(text) < length+1 {
+		return 0, fmt.Errorf("invalid escape sequence \\%c", text[0])
+	}
+	code, err := strconv.ParseUint(text[1:length+1], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid escape sequence \\%s", text[:length+1])
+	}
+	builder.WriteRune(rune(code))
+
+	return length + 1, nil
+}
+
+func resolveYAMLPlain(value string) *yamlNode {
+	switch value {
+	case "", "~", "null", "Null", "NULL":
+		return &yamlNode{value: "null"}
+	case "true", "True", "TRUE":
+		return &yamlNode{value: "true"}
+	case "false", "False", "FALSE":
+		return &yamlNode{value: "false"}
+	}
+
+	switch {
+	case yamlIntRegexp.MatchString(value):
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return &yamlNode{value: strconv.FormatInt(number, 10)}
+		}
+	case strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0o"):
+		if number, err := strconv.ParseInt(value, 0, 64); err == nil && !strings.Contains(value, "_") {
+			return &yamlNode{value: strconv.FormatInt(number, 10)}
+		}
+	case yamlFloatRegexp.MatchString(value):
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return &yamlNode{value: strconv.FormatFloat(number, 'g', -1, 64)}
+		}
+	}
+
+	return newYAMLString(value)
+}
+
+func newYAMLString(value string) *yamlNode {
+	jsonByte, _ := json.Marshal(value)
+	return &yamlNode{value: string(jsonByte)}
+}
+
+func (yamlNodeCore *yamlNode) stringValue() string {
+	var value string
+	if err := json.Unmarshal([]byte(yamlNodeCore.value), &value); err != nil {
+		return yamlNodeCore.value
+	}
+
+	return value
+}
+
+func (yamlNodeCore *yamlNode) isString() bool {
+	return yamlNodeCore.kind == yamlNodeScalar && strings.HasPrefix(yamlNodeCore.value, `"`)
+}
+
+func (yamlNodeCore *yamlNode) writeJSON(buffer *bytes.Buffer) {
+	switch yamlNodeCore.kind {
+	case yamlNodeSequence:
+		buffer.WriteByte('[')
+		for index, yamlItem := range yamlNodeCore.itemArray {
+			if index > 0 {
+				buffer.WriteByte(',')
+			}
+			yamlItem.writeJSON(buffer)
+		}
+		buffer.WriteByte(']')
+	case yamlNodeMapping:
+		buffer.WriteByte('{')
+		for index, key := range yamlNodeCore.keyArray {
+			if index > 0 {
+				buffer.WriteByte(',')
+			}
+			buffer.WriteString(newYAMLString(key).value)
+			buffer.WriteByte(':')
+			yamlNodeCore.itemArray[index].writeJSON(buffer)
+		}
+		buffer.WriteByte('}')
+	default:
+		buffer.WriteString(yamlNodeCore.value)
+	}
+}
+
+//--------------------------------------------------------------------------------//
+// YAML Encode
+//--------------------------------------------------------------------------------//
+
+func yamlNodeFromJSON(decoder *json.Decoder) (*yamlNode, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		yamlNodeCore := &yamlNode{kind: yamlNodeSequence}
+		if value == '{' {
+			yamlNodeCore.kind = yamlNodeMapping
+		}
+		for decoder.More() {
+			if yamlNodeCore.kind == yamlNodeMapping {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				yamlNodeCore.keyArray = append(yamlNodeCore.keyArray, fmt.Sprint(keyToken))
+			}
+			yamlItem, err := yamlNodeFromJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			yamlNodeCore.itemArray = append(yamlNodeCore.itemArray, yamlItem)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return yamlNodeCore, nil
+	case string:
+		return newYAMLString(value), nil
+	case json.Number:
+		return &yamlNode{value: value.String()}, nil
+	case bool:
+		return &yamlNode{value: strconv.FormatBool(value)}, nil
+	default:
+		return &yamlNode{value: "null"}, nil
+	}
+}
+
+func (yamlNodeCore *yamlNode) isFlat() bool {
+	if yamlNodeCore.kind == yamlNodeScalar || len(yamlNodeCore.itemArray) == 0 {
+		return true
+	}
+	if yamlNodeCore.kind == yamlNodeMapping {
+		return false
+	}
+	for _, yamlItem := range yamlNodeCore.itemArray {
+		if yamlItem.kind != yamlNodeScalar {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (yamlNodeCore *yamlNode) flowString() string {
+	switch yamlNodeCore.kind {
+	case yamlNodeSequence:
+		itemArray := make([]string, 0, len(yamlNodeCore.itemArray))
+		for _, yamlItem := range yamlNodeCore.itemArray {
+			itemArray = append(itemArray, yamlItem.flowString())
+		}
+		return "[" + strings.Join(itemArray, ", ") + "]"
+	case yamlNodeMapping:
+		return "{}"
+	}
+	if !yamlNodeCore.isString() {
+		return yamlNodeCore.value
+	}
+
+	return quoteYAMLString(yamlNodeCore.stringValue(), true)
+}
+
+func (yamlNodeCore *yamlNode) writeBlock(buffer *bytes.Buffer, indent int) {
+	padding := strings.Repeat(" ", indent)
+
+	switch yamlNodeCore.kind {
+	case yamlNodeMapping:
+		for index, key := range yamlNodeCore.keyArray {
+			yamlValue := yamlNodeCore.itemArray[index]
+			buffer.WriteString(padding + quoteYAMLString(key, false) + ":")
+			switch {
+			case yamlValue.isFlat():
+				buffer.WriteString(" " + yamlValue.flowString() + "\n")
+			case yamlValue.kind == yamlNodeSequence:
+				buffer.WriteByte('\n')
+				yamlValue.writeBlock(buffer, indent+yamlIndent)
+			default:
+				buffer.WriteByte('\n')
+				yamlValue.writeBlock(buffer, indent+yamlIndent)
+			}
+		}
+	case yamlNodeSequence:
+		for _, yamlItem := range yamlNodeCore.itemArray {
+			if yamlItem.isFlat() {
+				buffer.WriteString(padding + "- " + yamlItem.flowString() + "\n")
+				continue
+			}
+
+			var itemBuffer bytes.Buffer
+			yamlItem.writeBlock(&itemBuffer, indent+yamlIndent)
+			buffer.WriteString(padding + "- ")
+			buffer.Write(itemBuffer.Bytes()[indent+yamlIndent:])
+		}
+	}
+}
+
+func quoteYAMLString(value string, isFlow bool) string {
+	if isYAMLPlainSafe(value, isFlow) {
+		return value
+	}
+
+	var builder strings.Builder
+	builder.WriteByte('"')
+	for _, symbol := range value {
+		switch symbol {
+		case '"', '\\':
+			builder.WriteByte('\\')
+			builder.WriteRune(symbol)
+		case '\n':
+			builder.WriteString(`\n`)
+		case '\r':
+			builder.WriteString(`\r`)
+		case '\t':
+			builder.WriteString(`\t`)
+		default:
+			if symbol < 0x20 || symbol == 0x7f || symbol == utf8.RuneError {
+				fmt.Fprintf(&builder, `\x%02x`, symbol)
+			} else {
+				builder.WriteRune(symbol)
+			}
+		}
+	}
+	builder.WriteByte('"')
+
+	return builder.String()
+}
+
+func isYAMLPlainSafe(value string, isFlow bool) bool {
+	if value == "" || value != strings.TrimSpace(value) || !resolveYAMLPlain(value).isString() {
+		return false
+	}
+	switch strings.ToLower(value) {
+	case "y", "n", "yes", "no", "on", "off":
+		return false
+	}
+	if strings.IndexByte("-?:,[]{}#&*!|>'\"%@`.", value[0]) >= 0 && !(value[0] == '.' && !strings.HasPrefix(value, "...")) {
+		return false
+	}
+	if strings.Contains(value, ": ") || strings.Contains(value, " #") || strings.HasSuffix(value, ":") {
+		return false
+	}
+	if isFlow && strings.ContainsAny(value, ",[]{}") {
+		return false
+	}
+	for _, symbol := range value {
+		if symbol < 0x20 || symbol == 0x7f || symbol == 0xfeff || symbol == utf8.RuneError {
+			return false
+		}
+	}
+
+	return true
+}
+
+//--------------------------------------------------------------------------------//